@@ -0,0 +1,312 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/anthropic/internal/anthropicclient"
+	"github.com/comqositi/kpllms/schema"
+)
+
+type LLM struct {
+	client *anthropicclient.Client
+}
+
+// Anthropic 没有 json mode，开启 JsonMode 时追加到 system 中
+const jsonModePrompt = "只返回合法的 JSON，不要包含任何其他内容。"
+
+// samplingRanges anthropic 支持的采样参数范围
+var samplingRanges = kpllms.SamplingRanges{
+	Temperature: kpllms.Range{Min: 0, Max: 1},
+	TopP:        kpllms.Range{Min: 0, Max: 1},
+}
+
+var (
+	_                kpllms.Model = (*LLM)(nil)
+	ErrEmptyResponse              = errors.New("no response")
+	ErrMissingToken               = errors.New("missing the Anthropic API key, set it in the ANTHROPIC_API_KEY environment variable") //nolint:lll
+)
+
+// New 创建大模型 model 的实现
+func New(opts ...Option) (*LLM, error) {
+	options := &options{
+		token:   os.Getenv(tokenEnvVarName),
+		model:   os.Getenv(modelEnvVarName),
+		baseURL: os.Getenv(baseURLEnvVarName),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.token == "" {
+		return nil, ErrMissingToken
+	}
+	c, err := anthropicclient.New(
+		anthropicclient.WithToken(options.token),
+		anthropicclient.WithModel(options.model),
+		anthropicclient.WithBaseURL(options.baseURL),
+		anthropicclient.WithAPIVersion(options.apiVersion),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &LLM{client: c}, nil
+}
+
+// Chat 实现大模型接口
+func (o *LLM) Chat(ctx context.Context, messages []*schema.ChatMessage, options ...kpllms.CallOption) (*schema.ContentResponse, error) {
+	opts := kpllms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if err := samplingRanges.Validate(&opts); err != nil {
+		return nil, err
+	}
+	if err := opts.CheckSupported("anthropic", kpllms.OptionStop, kpllms.OptionUser); err != nil {
+		return nil, err
+	}
+
+	system, msgs, err := messagesToClientMessages(messages)
+	if err != nil {
+		return nil, err
+	}
+	if opts.JsonMode {
+		system = strings.TrimSpace(system + "\n\n" + jsonModePrompt)
+	}
+
+	req := &anthropicclient.MessageRequest{
+		Model:         opts.Model,
+		System:        system,
+		Messages:      msgs,
+		MaxTokens:     kpllms.Deref(opts.MaxTokens),
+		Temperature:   opts.Temperature,
+		TopP:          opts.TopP,
+		StopSequences: opts.Stop,
+		StreamingFunc: opts.StreamingFunc,
+	}
+	if opts.User != "" {
+		req.Metadata = &anthropicclient.Metadata{UserID: opts.User}
+	}
+
+	// 组装工具
+	for _, tool := range opts.Tools {
+		t, err := toolFromTool(tool)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert llms tool to anthropic tool: %w", err)
+		}
+		req.Tools = append(req.Tools, t)
+	}
+	if len(req.Tools) > 0 {
+		switch opts.ToolChoice.Type {
+		case schema.ToolChoiceTypeFunction:
+			req.ToolChoice = &anthropicclient.ToolChoice{Type: "tool", Name: opts.ToolChoice.Function.Name}
+		case schema.ToolChoiceTypeNone:
+			req.ToolChoice = &anthropicclient.ToolChoice{Type: "none"}
+		}
+	}
+
+	result, err := o.client.CreateMessage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	choice := &schema.ContentChoice{
+		StopReason: result.StopReason,
+		GenerationInfo: map[string]any{
+			"id":    result.ID,
+			"model": result.Model,
+		},
+		Usage: &schema.Usage{
+			PromptTokens:     result.Usage.InputTokens,
+			CompletionTokens: result.Usage.OutputTokens,
+			TotalTokens:      result.Usage.InputTokens + result.Usage.OutputTokens,
+		},
+	}
+	for _, block := range result.Content {
+		switch block.Type {
+		case anthropicclient.ContentTypeText:
+			choice.Content += block.Text
+		case anthropicclient.ContentTypeToolUse:
+			args := string(block.Input)
+			if args == "" {
+				args = "{}"
+			}
+			choice.ToolCalls = append(choice.ToolCalls, &schema.ToolCall{
+				Id:   block.ID,
+				Type: schema.ToolCallTypeFunction,
+				Function: schema.FunctionCall{
+					Name:      block.Name,
+					Arguments: args,
+				},
+			})
+		}
+	}
+	if choice.Content == "" && len(choice.ToolCalls) == 0 && result.StopReason != anthropicclient.StopReasonMaxTokens {
+		return nil, ErrEmptyResponse
+	}
+	return &schema.ContentResponse{Choices: []*schema.ContentChoice{choice}}, nil
+}
+
+// messagesToClientMessages system 合并为顶层字段，tool 消息转为 user 消息中的 tool_result，
+// 连续的 tool 消息合并到同一条 user 消息
+func messagesToClientMessages(messages []*schema.ChatMessage) (string, []*anthropicclient.Message, error) {
+	var systems []string
+	msgs := make([]*anthropicclient.Message, 0, len(messages))
+	for _, mc := range messages {
+		switch mc.Role {
+		case schema.RoleSystem:
+			text, err := textFromContent(mc.Content)
+			if err != nil {
+				return "", nil, err
+			}
+			systems = append(systems, text)
+		case schema.RoleUser:
+			blocks, err := blocksFromContent(mc.Content)
+			if err != nil {
+				return "", nil, err
+			}
+			msgs = append(msgs, &anthropicclient.Message{Role: anthropicclient.RoleUser, Content: blocks})
+		case schema.RoleAssistant:
+			text, err := textFromContent(mc.Content)
+			if err != nil {
+				return "", nil, err
+			}
+			msg := &anthropicclient.Message{Role: anthropicclient.RoleAssistant}
+			if text != "" {
+				msg.Content = append(msg.Content, &anthropicclient.ContentBlock{Type: anthropicclient.ContentTypeText, Text: text})
+			}
+			for _, t := range mc.ToolCalls {
+				input := json.RawMessage(t.Function.Arguments)
+				if strings.TrimSpace(t.Function.Arguments) == "" {
+					input = json.RawMessage("{}")
+				} else if !json.Valid(input) {
+					return "", nil, fmt.Errorf("tool call %s arguments is not valid json", t.Function.Name)
+				}
+				msg.Content = append(msg.Content, &anthropicclient.ContentBlock{
+					Type:  anthropicclient.ContentTypeToolUse,
+					ID:    t.Id,
+					Name:  t.Function.Name,
+					Input: input,
+				})
+			}
+			msgs = append(msgs, msg)
+		case schema.RoleTool:
+			text, err := textFromContent(mc.Content)
+			if err != nil {
+				return "", nil, err
+			}
+			block := &anthropicclient.ContentBlock{
+				Type:      anthropicclient.ContentTypeToolResult,
+				ToolUseID: mc.ToolCallId,
+				Content:   text,
+			}
+			if n := len(msgs); n > 0 && msgs[n-1].Role == anthropicclient.RoleUser && isToolResults(msgs[n-1]) {
+				msgs[n-1].Content = append(msgs[n-1].Content, block)
+			} else {
+				msgs = append(msgs, &anthropicclient.Message{Role: anthropicclient.RoleUser, Content: []*anthropicclient.ContentBlock{block}})
+			}
+		default:
+			return "", nil, fmt.Errorf("role %v not supported", mc.Role)
+		}
+	}
+	return strings.Join(systems, "\n\n"), msgs, nil
+}
+
+func isToolResults(msg *anthropicclient.Message) bool {
+	for _, b := range msg.Content {
+		if b.Type != anthropicclient.ContentTypeToolResult {
+			return false
+		}
+	}
+	return len(msg.Content) > 0
+}
+
+// textFromContent 只取文本内容
+func textFromContent(content any) (string, error) {
+	if content == nil {
+		return "", nil
+	}
+	if s, ok := content.(string); ok {
+		return s, nil
+	}
+	blocks, err := blocksFromContent(content)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, b := range blocks {
+		if b.Type != anthropicclient.ContentTypeText {
+			return "", errors.New("only text content is supported here")
+		}
+		sb.WriteString(b.Text)
+	}
+	return sb.String(), nil
+}
+
+// blocksFromContent 把 string 或 TextContent/ImageContent 数组转为内容块
+func blocksFromContent(content any) ([]*anthropicclient.ContentBlock, error) {
+	var parts []any
+	switch c := content.(type) {
+	case string:
+		return []*anthropicclient.ContentBlock{{Type: anthropicclient.ContentTypeText, Text: c}}, nil
+	case []any:
+		parts = c
+	case []schema.TextContent:
+		for _, p := range c {
+			parts = append(parts, p)
+		}
+	default:
+		return nil, fmt.Errorf("content type %T not supported", content)
+	}
+	blocks := make([]*anthropicclient.ContentBlock, 0, len(parts))
+	for _, p := range parts {
+		switch part := p.(type) {
+		case schema.TextContent:
+			blocks = append(blocks, &anthropicclient.ContentBlock{Type: anthropicclient.ContentTypeText, Text: part.Text})
+		case *schema.TextContent:
+			blocks = append(blocks, &anthropicclient.ContentBlock{Type: anthropicclient.ContentTypeText, Text: part.Text})
+		case schema.ImageContent:
+			blocks = append(blocks, imageBlock(part.ImageUrl.Url))
+		case *schema.ImageContent:
+			blocks = append(blocks, imageBlock(part.ImageUrl.Url))
+		default:
+			return nil, fmt.Errorf("content part type %T not supported", p)
+		}
+	}
+	return blocks, nil
+}
+
+// imageBlock data:image/png;base64,xxx 格式转为 base64 来源，其他按 url 处理
+func imageBlock(url string) *anthropicclient.ContentBlock {
+	source := &anthropicclient.ImageSource{Type: "url", URL: url}
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		if meta, data, ok := strings.Cut(rest, ","); ok && strings.HasSuffix(meta, ";base64") {
+			source = &anthropicclient.ImageSource{
+				Type:      "base64",
+				MediaType: strings.TrimSuffix(meta, ";base64"),
+				Data:      data,
+			}
+		}
+	}
+	return &anthropicclient.ContentBlock{Type: anthropicclient.ContentTypeImage, Source: source}
+}
+
+func toolFromTool(t *kpllms.Tool) (anthropicclient.Tool, error) {
+	if t.Type != schema.ToolCallTypeFunction || t.Function == nil {
+		return anthropicclient.Tool{}, fmt.Errorf("tool type %v not supported", t.Type)
+	}
+	tool := anthropicclient.Tool{
+		Name:        t.Function.Name,
+		Description: t.Function.Description,
+		InputSchema: t.Function.Parameters,
+	}
+	// input_schema 是必填字段
+	if tool.InputSchema == nil {
+		tool.InputSchema = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	return tool, nil
+}
@@ -0,0 +1,50 @@
+package anthropic
+
+const (
+	tokenEnvVarName   = "ANTHROPIC_API_KEY"  //nolint:gosec
+	modelEnvVarName   = "ANTHROPIC_MODEL"    //nolint:gosec
+	baseURLEnvVarName = "ANTHROPIC_BASE_URL" //nolint:gosec
+)
+
+type options struct {
+	token      string
+	model      string
+	baseURL    string
+	apiVersion string
+}
+
+// Option is a functional option for the Anthropic client.
+type Option func(*options)
+
+// WithToken passes the Anthropic API key to the client. If not set, the key
+// is read from the ANTHROPIC_API_KEY environment variable.
+func WithToken(token string) Option {
+	return func(opts *options) {
+		opts.token = token
+	}
+}
+
+// WithModel passes the Anthropic model to the client. If not set, the model
+// is read from the ANTHROPIC_MODEL environment variable.
+func WithModel(model string) Option {
+	return func(opts *options) {
+		opts.model = model
+	}
+}
+
+// WithBaseURL passes the Anthropic base url to the client. If not set, the base url
+// is read from the ANTHROPIC_BASE_URL environment variable, and defaults to
+// https://api.anthropic.com/v1.
+func WithBaseURL(baseURL string) Option {
+	return func(opts *options) {
+		opts.baseURL = baseURL
+	}
+}
+
+// WithAPIVersion sets the anthropic-version header. If not set, the default value
+// is 2023-06-01.
+func WithAPIVersion(apiVersion string) Option {
+	return func(opts *options) {
+		opts.apiVersion = apiVersion
+	}
+}
@@ -0,0 +1,179 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+)
+
+func newTestServer(t *testing.T, handler func(body map[string]any, w http.ResponseWriter)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "key" || r.Header.Get("anthropic-version") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(b, &body); err != nil {
+			t.Error(err)
+		}
+		handler(body, w)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+var weatherTool = &kpllms.Tool{
+	Type: schema.ToolCallTypeFunction,
+	Function: &kpllms.FunctionDefinition{
+		Name:        "get_weather",
+		Description: "查询天气",
+		Parameters: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"city": map[string]any{"type": "string"}},
+		},
+	},
+}
+
+func TestChat(t *testing.T) {
+	var got map[string]any
+	srv := newTestServer(t, func(body map[string]any, w http.ResponseWriter) {
+		got = body
+		_, _ = io.WriteString(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-test",
+			"content":[{"type":"text","text":"我来查一下"},{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"上海"}}],
+			"stop_reason":"tool_use","usage":{"input_tokens":20,"output_tokens":10}}`)
+	})
+	llm, err := New(WithToken("key"), WithBaseURL(srv.URL+"/v1"), WithModel("claude-test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := []*schema.ChatMessage{
+		{Role: schema.RoleSystem, Content: "你是天气助手"},
+		{Role: schema.RoleUser, Content: "北京和上海天气如何"},
+		{Role: schema.RoleAssistant, ToolCalls: []*schema.ToolCall{
+			{Id: "toolu_0", Type: schema.ToolCallTypeFunction, Function: schema.FunctionCall{Name: "get_weather", Arguments: `{"city":"北京"}`}},
+		}},
+		{Role: schema.RoleTool, ToolCallId: "toolu_0", Content: "晴"},
+	}
+	resp, err := llm.Chat(context.Background(), messages, kpllms.WithTools([]*kpllms.Tool{weatherTool}), kpllms.WithMaxTokens(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got["system"] != "你是天气助手" || got["max_tokens"] != float64(100) || got["model"] != "claude-test" {
+		t.Fatalf("unexpected request: %v", got)
+	}
+	msgs := got["messages"].([]any)
+	if len(msgs) != 3 {
+		t.Fatalf("messages = %v", msgs)
+	}
+	toolUse := msgs[1].(map[string]any)["content"].([]any)[0].(map[string]any)
+	if toolUse["type"] != "tool_use" || toolUse["id"] != "toolu_0" || toolUse["input"].(map[string]any)["city"] != "北京" {
+		t.Fatalf("unexpected tool_use: %v", toolUse)
+	}
+	toolResult := msgs[2].(map[string]any)
+	block := toolResult["content"].([]any)[0].(map[string]any)
+	if toolResult["role"] != "user" || block["type"] != "tool_result" || block["tool_use_id"] != "toolu_0" || block["content"] != "晴" {
+		t.Fatalf("unexpected tool_result: %v", toolResult)
+	}
+	tool := got["tools"].([]any)[0].(map[string]any)
+	if tool["name"] != "get_weather" || tool["input_schema"] == nil {
+		t.Fatalf("unexpected tool: %v", tool)
+	}
+
+	c := resp.Choices[0]
+	if c.Content != "我来查一下" || c.StopReason != "tool_use" || c.Usage.TotalTokens != 30 {
+		t.Fatalf("unexpected choice: %+v", c)
+	}
+	if len(c.ToolCalls) != 1 || c.ToolCalls[0].Id != "toolu_1" || c.ToolCalls[0].Function.Arguments != `{"city":"上海"}` {
+		t.Fatalf("unexpected tool calls: %+v", c.ToolCalls)
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_2","type":"message","role":"assistant","model":"claude-test","content":[],"usage":{"input_tokens":15,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"ping"}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"你好"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"，世界"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_2","name":"get_weather","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":" \"杭州\"}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":12}}`,
+		`{"type":"message_stop"}`,
+	}
+	var stream bool
+	srv := newTestServer(t, func(body map[string]any, w http.ResponseWriter) {
+		stream, _ = body["stream"].(bool)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range events {
+			var typ struct{ Type string }
+			_ = json.Unmarshal([]byte(e), &typ)
+			_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ.Type, e)
+		}
+	})
+	llm, err := New(WithToken("key"), WithBaseURL(srv.URL+"/v1/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chunks []string
+	resp, err := llm.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}},
+		kpllms.WithTools([]*kpllms.Tool{weatherTool}),
+		kpllms.WithStreamingFunc(func(ctx context.Context, chunk []byte, innerErr error) error {
+			chunks = append(chunks, string(chunk))
+			return nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stream {
+		t.Fatal("stream not set in request")
+	}
+	c := resp.Choices[0]
+	if strings.Join(chunks, "") != "你好，世界" || c.Content != "你好，世界" {
+		t.Fatalf("chunks = %v, content = %q", chunks, c.Content)
+	}
+	if c.StopReason != "tool_use" || c.Usage.PromptTokens != 15 || c.Usage.CompletionTokens != 12 {
+		t.Fatalf("unexpected choice: %+v %+v", c, c.Usage)
+	}
+	if len(c.ToolCalls) != 1 || c.ToolCalls[0].Id != "toolu_2" || c.ToolCalls[0].Function.Arguments != `{"city": "杭州"}` {
+		t.Fatalf("unexpected tool calls: %+v", c.ToolCalls)
+	}
+}
+
+func TestChatStreamError(t *testing.T) {
+	srv := newTestServer(t, func(_ map[string]any, w http.ResponseWriter) {
+		_, _ = io.WriteString(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	})
+	llm, err := New(WithToken("key"), WithBaseURL(srv.URL+"/v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = llm.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}},
+		kpllms.WithStreamingFunc(func(context.Context, []byte, error) error { return nil }))
+	if err == nil || !strings.Contains(err.Error(), "overloaded_error") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestImageBlock(t *testing.T) {
+	b := imageBlock("data:image/png;base64,AAAA")
+	if b.Source.Type != "base64" || b.Source.MediaType != "image/png" || b.Source.Data != "AAAA" {
+		t.Fatalf("unexpected source: %+v", b.Source)
+	}
+	if b = imageBlock("https://example.com/a.jpg"); b.Source.Type != "url" {
+		t.Fatalf("unexpected source: %+v", b.Source)
+	}
+}
@@ -0,0 +1,93 @@
+package anthropicclient
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	defaultBaseURL    = "https://api.anthropic.com/v1"
+	defaultAPIVersion = "2023-06-01"
+	defaultChatModel  = "claude-3-5-sonnet-20241022"
+	// Messages API 要求必须传 max_tokens
+	defaultMaxTokens = 4096
+)
+
+// anthropic 客户端
+
+var (
+	ErrEmptyResponse = errors.New("empty response")
+	ErrMissingToken  = errors.New("api key 不能为空")
+)
+
+// Client Anthropic Messages API 客户端
+type Client struct {
+	token      string
+	Model      string
+	baseURL    string
+	apiVersion string
+}
+
+// Option is an option for the Anthropic client.
+type Option func(*Client) error
+
+// New 创建 Anthropic 客户端
+func New(opts ...Option) (*Client, error) {
+	c := &Client{}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.token == "" {
+		return nil, ErrMissingToken
+	}
+	if c.baseURL == "" {
+		c.baseURL = defaultBaseURL
+	}
+	c.baseURL = strings.TrimSuffix(c.baseURL, "/")
+	if c.apiVersion == "" {
+		c.apiVersion = defaultAPIVersion
+	}
+	if c.Model == "" {
+		c.Model = defaultChatModel
+	}
+	return c, nil
+}
+
+func WithToken(value string) Option {
+	return func(c *Client) error {
+		c.token = value
+		return nil
+	}
+}
+
+func WithModel(value string) Option {
+	return func(c *Client) error {
+		c.Model = value
+		return nil
+	}
+}
+
+func WithBaseURL(value string) Option {
+	return func(c *Client) error {
+		c.baseURL = value
+		return nil
+	}
+}
+
+func WithAPIVersion(value string) Option {
+	return func(c *Client) error {
+		c.apiVersion = value
+		return nil
+	}
+}
+
+// 设置权限
+func (c *Client) setHeaders() map[string]string {
+	return map[string]string{
+		"Content-Type":      "application/json",
+		"x-api-key":         c.token,
+		"anthropic-version": c.apiVersion,
+	}
+}
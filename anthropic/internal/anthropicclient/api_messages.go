@@ -0,0 +1,240 @@
+package anthropicclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/comqositi/kpllms/internal/httputils"
+)
+
+// anthropic messages 接口实现，文档：https://docs.anthropic.com/en/api/messages
+
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+
+	ContentTypeText       = "text"
+	ContentTypeImage      = "image"
+	ContentTypeToolUse    = "tool_use"
+	ContentTypeToolResult = "tool_result"
+
+	StopReasonEndTurn   = "end_turn"
+	StopReasonMaxTokens = "max_tokens"
+	StopReasonToolUse   = "tool_use"
+)
+
+// Metadata 请求的元数据，user_id 为终端用户的唯一标识
+type Metadata struct {
+	UserID string `json:"user_id,omitempty"`
+}
+
+// MessageRequest messages 接口请求
+type MessageRequest struct {
+	Model string `json:"model"`
+	// system 不在 messages 中，是顶层字段
+	System        string      `json:"system,omitempty"`
+	Messages      []*Message  `json:"messages"`
+	MaxTokens     int         `json:"max_tokens"`
+	Temperature   *float64    `json:"temperature,omitempty"`
+	TopP          *float64    `json:"top_p,omitempty"`
+	StopSequences []string    `json:"stop_sequences,omitempty"`
+	Metadata      *Metadata   `json:"metadata,omitempty"`
+	Stream        bool        `json:"stream,omitempty"`
+	Tools         []Tool      `json:"tools,omitempty"`
+	ToolChoice    *ToolChoice `json:"tool_choice,omitempty"`
+
+	// 流式放回的回调函数
+	StreamingFunc func(ctx context.Context, chunk []byte, innerErr error) error `json:"-"`
+}
+
+// Message 对话消息，content 固定使用内容块数组
+type Message struct {
+	Role    string          `json:"role"`
+	Content []*ContentBlock `json:"content"`
+}
+
+// ContentBlock 内容块，根据 Type 使用不同的字段
+type ContentBlock struct {
+	Type string `json:"type"`
+
+	// type: text
+	Text string `json:"text,omitempty"`
+
+	// type: image
+	Source *ImageSource `json:"source,omitempty"`
+
+	// type: tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// type: tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	IsError   bool   `json:"is_error,omitempty"`
+}
+
+// ImageSource 图片来源，type: base64 或 url
+type ImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// Tool 工具定义
+type Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema any    `json:"input_schema"`
+}
+
+// ToolChoice type: auto any tool none，type 为 tool 时需指定 name
+type ToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// Usage token 消耗
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// MessageResponse messages 接口返回
+type MessageResponse struct {
+	ID           string          `json:"id"`
+	Type         string          `json:"type"`
+	Role         string          `json:"role"`
+	Model        string          `json:"model"`
+	Content      []*ContentBlock `json:"content"`
+	StopReason   string          `json:"stop_reason"`
+	StopSequence string          `json:"stop_sequence"`
+	Usage        Usage           `json:"usage"`
+}
+
+// streamEvent 流式返回的事件，data 中的 type 与 event 行一致，只解析 data 行
+type streamEvent struct {
+	Type         string           `json:"type"`
+	Message      *MessageResponse `json:"message,omitempty"`
+	Index        int              `json:"index"`
+	ContentBlock *ContentBlock    `json:"content_block,omitempty"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *Usage `json:"usage,omitempty"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// CreateMessage 调用 messages 接口
+func (c *Client) CreateMessage(ctx context.Context, r *MessageRequest) (*MessageResponse, error) {
+	if r.Model == "" {
+		r.Model = c.Model
+	}
+	if r.MaxTokens <= 0 {
+		r.MaxTokens = defaultMaxTokens
+	}
+	r.Stream = r.StreamingFunc != nil
+
+	var response MessageResponse
+	url := c.baseURL + "/messages"
+	if !r.Stream {
+		if err := httputils.HttpPost(ctx, url, r, c.setHeaders(), &response); err != nil {
+			return nil, err
+		}
+	} else if err := c.stream(ctx, url, r, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Content) == 0 && response.StopReason == "" {
+		return nil, ErrEmptyResponse
+	}
+	return &response, nil
+}
+
+// stream 处理 SSE 事件，拼接成完整的 MessageResponse
+func (c *Client) stream(ctx context.Context, url string, r *MessageRequest, response *MessageResponse) error {
+	// 工具调用的参数分多次以 input_json_delta 返回，按内容块下标拼接
+	partialJSON := map[int]*strings.Builder{}
+	return httputils.HttpStream(ctx, url, r, c.setHeaders(), func(ctx context.Context, line string) error {
+		// 空行是事件间隔，event 行与 data 中的 type 重复，均不处理
+		if !strings.HasPrefix(line, "data:") {
+			return nil
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		var event streamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return err
+		}
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				*response = *event.Message
+				response.Content = nil
+			}
+		case "content_block_start":
+			if event.ContentBlock == nil {
+				return nil
+			}
+			for len(response.Content) <= event.Index {
+				response.Content = append(response.Content, &ContentBlock{})
+			}
+			block := *event.ContentBlock
+			// 流式返回的 tool_use 开始时 input 为 {}，参数由后续 delta 拼接
+			block.Input = nil
+			response.Content[event.Index] = &block
+		case "content_block_delta":
+			if event.Index >= len(response.Content) {
+				return fmt.Errorf("unexpected content block index: %d", event.Index)
+			}
+			block := response.Content[event.Index]
+			switch event.Delta.Type {
+			case "text_delta":
+				if event.Delta.Text == "" {
+					return nil
+				}
+				block.Text += event.Delta.Text
+				return r.StreamingFunc(ctx, []byte(event.Delta.Text), nil)
+			case "input_json_delta":
+				if partialJSON[event.Index] == nil {
+					partialJSON[event.Index] = &strings.Builder{}
+				}
+				partialJSON[event.Index].WriteString(event.Delta.PartialJSON)
+			}
+		case "content_block_stop":
+			if event.Index >= len(response.Content) || response.Content[event.Index].Type != ContentTypeToolUse {
+				return nil
+			}
+			input := "{}"
+			if b := partialJSON[event.Index]; b != nil && b.Len() > 0 {
+				input = b.String()
+			}
+			response.Content[event.Index].Input = json.RawMessage(input)
+		case "message_delta":
+			if event.Delta.StopReason != "" {
+				response.StopReason = event.Delta.StopReason
+			}
+			if event.Usage != nil {
+				response.Usage.OutputTokens = event.Usage.OutputTokens
+				// 部分网关在 message_delta 中返回 input_tokens
+				if event.Usage.InputTokens > 0 {
+					response.Usage.InputTokens = event.Usage.InputTokens
+				}
+			}
+		case "error":
+			if event.Error != nil {
+				return fmt.Errorf("%s: %s", event.Error.Type, event.Error.Message)
+			}
+			return fmt.Errorf("unexpected error event: %s", data)
+		}
+		// ping message_stop 不处理
+		return nil
+	})
+}
@@ -0,0 +1,255 @@
+package ernie
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/ernie/internal/ernieclient"
+	"github.com/comqositi/kpllms/schema"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmptyResponse            = errors.New("no response")
+	ErrMissingToken             = errors.New("缺少 API Key 和 Secret Key，请设置 ERNIE_API_KEY、ERNIE_SECRET_KEY 环境变量或使用 WithAKSK") //nolint:lll
+	ErrUnexpectedResponseLength = errors.New("unexpected length of response")
+)
+
+// Error 千帆接口返回的错误，可通过 errors.As 获取错误码
+type Error = ernieclient.Error
+
+type LLM struct {
+	client         *ernieclient.Client
+	model          string
+	embeddingModel string
+}
+
+var (
+	_ kpllms.Model    = (*LLM)(nil)
+	_ kpllms.Embedder = (*LLM)(nil)
+)
+
+// New 创建文心一言大模型 model 和 embedder 的实现
+func New(opts ...Option) (*LLM, error) {
+	options := &options{
+		apiKey:      os.Getenv(apiKeyEnvVarName),
+		secretKey:   os.Getenv(secretKeyEnvVarName),
+		accessToken: os.Getenv(accessTokenEnvVarName),
+		baseURL:     os.Getenv(baseURLEnvVarName),
+		model:       os.Getenv(modelEnvVarName),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.accessToken == "" && (options.apiKey == "" || options.secretKey == "") {
+		return nil, ErrMissingToken
+	}
+	if options.model == "" {
+		options.model = defaultModel
+	}
+	if options.embeddingModel == "" {
+		options.embeddingModel = defaultEmbeddingModel
+	}
+
+	clientOpts := []ernieclient.Option{ernieclient.WithBaseURL(options.baseURL)}
+	if options.accessToken != "" {
+		clientOpts = append(clientOpts, ernieclient.WithAccessToken(options.accessToken))
+	} else {
+		clientOpts = append(clientOpts, ernieclient.WithAKSK(options.apiKey, options.secretKey))
+	}
+	c, err := ernieclient.New(clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &LLM{client: c, model: options.model, embeddingModel: options.embeddingModel}, nil
+}
+
+// samplingRanges 文心支持的采样参数范围
+var samplingRanges = kpllms.SamplingRanges{
+	Temperature: kpllms.Range{Min: 0, Max: 1, ExclusiveMin: true},
+	TopP:        kpllms.Range{Min: 0, Max: 1},
+}
+
+// Chat 实现大模型接口
+func (o *LLM) Chat(ctx context.Context, messages []*schema.ChatMessage, options ...kpllms.CallOption) (*schema.ContentResponse, error) {
+	opts := kpllms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if err := samplingRanges.Validate(&opts); err != nil {
+		return nil, err
+	}
+	if err := opts.CheckSupported("ernie", kpllms.OptionStop, kpllms.OptionUser); err != nil {
+		return nil, err
+	}
+	model := opts.Model
+	if model == "" {
+		model = o.model
+	}
+
+	system, msgs, err := messagesToClientMessages(messages)
+	if err != nil {
+		return nil, err
+	}
+	req := &ernieclient.ChatRequest{
+		Endpoint:        endpointOf(model),
+		Messages:        msgs,
+		System:          system,
+		Temperature:     opts.Temperature,
+		TopP:            opts.TopP,
+		MaxOutputTokens: kpllms.Deref(opts.MaxTokens),
+		Stop:            opts.Stop,
+		UserID:          opts.User,
+		StreamingFunc:   opts.StreamingFunc,
+	}
+	// 使用 json 格式返回
+	if opts.JsonMode {
+		req.ResponseFormat = "json_object"
+	}
+
+	// 文心没有 none 的调用方式，不调用函数时不传函数定义
+	if opts.ToolChoice.Type != schema.ToolChoiceTypeNone {
+		for _, t := range opts.Tools {
+			if t.Type != schema.ToolCallTypeFunction || t.Function == nil {
+				return nil, fmt.Errorf("failed to convert llms tool to ernie function: tool type %v not supported", t.Type)
+			}
+			req.Functions = append(req.Functions, &ernieclient.Function{
+				Name:        t.Function.Name,
+				Description: t.Function.Description,
+				Parameters:  t.Function.Parameters,
+			})
+		}
+		if opts.ToolChoice.Type == schema.ToolChoiceTypeFunction && len(req.Functions) > 0 {
+			req.ToolChoice = &ernieclient.ToolChoice{Type: schema.ToolChoiceTypeFunction}
+			req.ToolChoice.Function.Name = opts.ToolChoice.Function.Name
+		}
+	}
+
+	result, err := o.client.CreateChat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	choice := &schema.ContentChoice{
+		Content:    result.Result,
+		StopReason: result.FinishReason,
+		GenerationInfo: map[string]any{
+			"id":                 result.ID,
+			"is_truncated":       result.IsTruncated,
+			"need_clear_history": result.NeedClearHistory,
+		},
+		Usage: &schema.Usage{
+			PromptTokens:     result.Usage.PromptTokens,
+			CompletionTokens: result.Usage.CompletionTokens,
+			TotalTokens:      result.Usage.TotalTokens,
+		},
+	}
+	if fc := result.FunctionCall; fc != nil {
+		choice.GenerationInfo["thoughts"] = fc.Thoughts
+		// 文心的函数调用没有 id，这里生成一个，返回结果时用于找回函数名
+		choice.ToolCalls = []*schema.ToolCall{{
+			Id:   "call_" + uuid.NewString(),
+			Type: schema.ToolCallTypeFunction,
+			Function: schema.FunctionCall{
+				Name:      fc.Name,
+				Arguments: fc.Arguments,
+			},
+		}}
+	}
+	return &schema.ContentResponse{Choices: []*schema.ContentChoice{choice}}, nil
+}
+
+// messagesToClientMessages system 转为顶层 system 字段，tool 消息转为 function 消息
+func messagesToClientMessages(messages []*schema.ChatMessage) (string, []*ernieclient.ChatMessage, error) {
+	var systems []string
+	msgs := make([]*ernieclient.ChatMessage, 0, len(messages))
+	// tool 消息只有 ToolCallId，函数名从之前的 assistant 消息中查找
+	toolNames := map[string]string{}
+	for _, mc := range messages {
+		content, err := textFromContent(mc.Content)
+		if err != nil {
+			return "", nil, err
+		}
+		switch mc.Role {
+		case schema.RoleSystem:
+			systems = append(systems, content)
+		case schema.RoleUser:
+			msgs = append(msgs, &ernieclient.ChatMessage{Role: ernieclient.RoleUser, Content: content})
+		case schema.RoleAssistant:
+			msg := &ernieclient.ChatMessage{Role: ernieclient.RoleAssistant, Content: content}
+			if len(mc.ToolCalls) > 1 {
+				return "", nil, errors.New("ernie does not support parallel function calls")
+			}
+			for _, t := range mc.ToolCalls {
+				toolNames[t.Id] = t.Function.Name
+				msg.FunctionCall = &ernieclient.FunctionCall{Name: t.Function.Name, Arguments: t.Function.Arguments}
+			}
+			msgs = append(msgs, msg)
+		case schema.RoleTool:
+			name := mc.Name
+			if name == "" {
+				name = toolNames[mc.ToolCallId]
+			}
+			if name == "" {
+				return "", nil, fmt.Errorf("tool message %s: function name not found", mc.ToolCallId)
+			}
+			msgs = append(msgs, &ernieclient.ChatMessage{Role: ernieclient.RoleFunction, Name: name, Content: content})
+		default:
+			return "", nil, fmt.Errorf("role %v not supported", mc.Role)
+		}
+	}
+	return strings.Join(systems, "\n"), msgs, nil
+}
+
+// textFromContent 文心只支持文本内容
+func textFromContent(content any) (string, error) {
+	switch c := content.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return c, nil
+	case []any:
+		var sb strings.Builder
+		for _, p := range c {
+			switch t := p.(type) {
+			case schema.TextContent:
+				sb.WriteString(t.Text)
+			case *schema.TextContent:
+				sb.WriteString(t.Text)
+			default:
+				return "", fmt.Errorf("content part type %T not supported", p)
+			}
+		}
+		return sb.String(), nil
+	default:
+		return "", fmt.Errorf("content type %T not supported", content)
+	}
+}
+
+// EmbedDocuments  实现 embedder 接口 文档存储向量：存入数据库的向量，被用于检索
+func (o *LLM) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings, err := o.client.CreateEmbedding(ctx, o.embeddingModel, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	if len(texts) != len(embeddings) {
+		return embeddings, ErrUnexpectedResponseLength
+	}
+	return embeddings, nil
+}
+
+// EmbedQuery  实现 embedder 接口 查询向量：对需要用于检索的文本进行想量化
+func (o *LLM) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := o.EmbedDocuments(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
@@ -0,0 +1,89 @@
+package ernie
+
+import "strings"
+
+const (
+	apiKeyEnvVarName      = "ERNIE_API_KEY"      //nolint:gosec
+	secretKeyEnvVarName   = "ERNIE_SECRET_KEY"   //nolint:gosec
+	accessTokenEnvVarName = "ERNIE_ACCESS_TOKEN" //nolint:gosec
+	modelEnvVarName       = "ERNIE_MODEL"        //nolint:gosec
+	baseURLEnvVarName     = "ERNIE_BASE_URL"     //nolint:gosec
+
+	defaultModel          = ModelERNIE35
+	defaultEmbeddingModel = "embedding-v1"
+)
+
+// 常用模型，其他模型可直接传千帆的接口地址，例如 ernie-char-8k
+const (
+	ModelERNIE4     = "ERNIE-4.0-8K"
+	ModelERNIE35    = "ERNIE-3.5-8K"
+	ModelERNIESpeed = "ERNIE-Speed-128K"
+	ModelERNIELite  = "ERNIE-Lite-8K"
+)
+
+// modelEndpoints 模型名对应的接口地址
+var modelEndpoints = map[string]string{
+	"ERNIE-Bot":     "completions",
+	"ERNIE-Bot-4":   "completions_pro",
+	ModelERNIE4:     "completions_pro",
+	ModelERNIE35:    "completions",
+	ModelERNIESpeed: "ernie-speed-128k",
+	ModelERNIELite:  "ernie-lite-8k",
+}
+
+// endpointOf 未收录的模型名转为小写作为接口地址
+func endpointOf(model string) string {
+	if e, ok := modelEndpoints[model]; ok {
+		return e
+	}
+	return strings.ToLower(model)
+}
+
+type options struct {
+	apiKey         string
+	secretKey      string
+	accessToken    string
+	baseURL        string
+	model          string
+	embeddingModel string
+}
+
+// Option is a functional option for the ERNIE client.
+type Option func(*options)
+
+// WithAKSK 设置千帆应用的 API Key 和 Secret Key，用于换取 access_token，
+// 未设置时读取 ERNIE_API_KEY 和 ERNIE_SECRET_KEY 环境变量
+func WithAKSK(apiKey, secretKey string) Option {
+	return func(o *options) {
+		o.apiKey = apiKey
+		o.secretKey = secretKey
+	}
+}
+
+// WithAccessToken 直接使用 access_token，不再自动换取和刷新，未设置时读取 ERNIE_ACCESS_TOKEN 环境变量
+func WithAccessToken(accessToken string) Option {
+	return func(o *options) {
+		o.accessToken = accessToken
+	}
+}
+
+// WithBaseURL 设置接口地址，默认 https://aip.baidubce.com
+func WithBaseURL(baseURL string) Option {
+	return func(o *options) {
+		o.baseURL = baseURL
+	}
+}
+
+// WithModel 设置模型，默认 ERNIE-3.5-8K
+func WithModel(model string) Option {
+	return func(o *options) {
+		o.model = model
+	}
+}
+
+// WithEmbeddingModel 设置向量模型，默认 embedding-v1
+func WithEmbeddingModel(model string) Option {
+	return func(o *options) {
+		o.embeddingModel = model
+	}
+}
@@ -0,0 +1,244 @@
+package ernie
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+)
+
+const chatPath = "/rpc/2.0/ai_custom/v1/wenxinworkshop/chat/"
+
+// fakeQianfan 每次换取 token 返回 t1、t2...，expiredTokens 中的 token 调用接口返回 111
+type fakeQianfan struct {
+	tokenCalls    atomic.Int32
+	expiresIn     int
+	expiredTokens sync.Map
+	chat          func(token string, body map[string]any, w http.ResponseWriter)
+}
+
+func (f *fakeQianfan) server(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth/2.0/token" {
+			q := r.URL.Query()
+			if q.Get("client_id") != "ak" || q.Get("client_secret") != "sk" {
+				_, _ = fmt.Fprint(w, `{"error":"invalid_client","error_description":"unknown client id"}`)
+				return
+			}
+			n := f.tokenCalls.Add(1)
+			_, _ = fmt.Fprintf(w, `{"access_token":"t%d","expires_in":%d}`, n, f.expiresIn)
+			return
+		}
+		token := r.URL.Query().Get("access_token")
+		if _, expired := f.expiredTokens.Load(token); expired {
+			_, _ = fmt.Fprint(w, `{"error_code":111,"error_msg":"Access token expired"}`)
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if strings.HasPrefix(r.URL.Path, chatPath) {
+			body["endpoint"] = strings.TrimPrefix(r.URL.Path, chatPath)
+			f.chat(token, body, w)
+			return
+		}
+		// embeddings
+		input := body["input"].([]any)
+		data := make([]map[string]any, 0, len(input))
+		for i := len(input) - 1; i >= 0; i-- {
+			data = append(data, map[string]any{"index": i, "embedding": []float32{float32(i)}})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestLLM(t *testing.T, srv *httptest.Server, opts ...Option) *LLM {
+	t.Helper()
+	llm, err := New(append([]Option{WithAKSK("ak", "sk"), WithBaseURL(srv.URL)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return llm
+}
+
+func TestChatTokenCached(t *testing.T) {
+	f := &fakeQianfan{expiresIn: 2592000}
+	var mu sync.Mutex
+	var got map[string]any
+	f.chat = func(_ string, body map[string]any, w http.ResponseWriter) {
+		mu.Lock()
+		got = body
+		mu.Unlock()
+		_, _ = fmt.Fprint(w, `{"id":"as-1","result":"","finish_reason":"function_call",
+			"function_call":{"name":"get_weather","arguments":"{\"city\":\"上海\"}","thoughts":"需要查天气"},
+			"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`)
+	}
+	llm := newTestLLM(t, f.server(t), WithModel(ModelERNIE4))
+	messages := []*schema.ChatMessage{
+		{Role: schema.RoleSystem, Content: "你是天气助手"},
+		{Role: schema.RoleUser, Content: "北京天气"},
+		{Role: schema.RoleAssistant, ToolCalls: []*schema.ToolCall{
+			{Id: "call_1", Type: schema.ToolCallTypeFunction, Function: schema.FunctionCall{Name: "get_weather", Arguments: `{"city":"北京"}`}},
+		}},
+		{Role: schema.RoleTool, ToolCallId: "call_1", Content: `{"weather":"晴"}`},
+	}
+	tools := []*kpllms.Tool{{Type: schema.ToolCallTypeFunction, Function: &kpllms.FunctionDefinition{Name: "get_weather", Parameters: map[string]any{"type": "object"}}}}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	resps := make([]*schema.ContentResponse, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resps[i], errs[i] = llm.Chat(context.Background(), messages, kpllms.WithTools(tools))
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := f.tokenCalls.Load(); n != 1 {
+		t.Fatalf("token requested %d times", n)
+	}
+
+	b, _ := json.Marshal(got)
+	for _, want := range []string{
+		`"endpoint":"completions_pro"`,
+		`"system":"你是天气助手"`,
+		`{"content":"","function_call":{"arguments":"{\"city\":\"北京\"}","name":"get_weather"},"role":"assistant"}`,
+		`{"content":"{\"weather\":\"晴\"}","name":"get_weather","role":"function"}`,
+		`"functions":[{"description":"","name":"get_weather","parameters":{"type":"object"}}]`,
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("request missing %s\n%s", want, b)
+		}
+	}
+	c := resps[0].Choices[0]
+	if len(c.ToolCalls) != 1 || c.ToolCalls[0].Id == "" || c.ToolCalls[0].Function.Arguments != `{"city":"上海"}` {
+		t.Fatalf("unexpected tool calls: %+v", c.ToolCalls)
+	}
+	if c.StopReason != "function_call" || c.Usage.TotalTokens != 15 || c.GenerationInfo["thoughts"] != "需要查天气" {
+		t.Fatalf("unexpected choice: %+v", c)
+	}
+}
+
+func TestChatTokenRefresh(t *testing.T) {
+	f := &fakeQianfan{expiresIn: 2592000}
+	var tokens []string
+	f.chat = func(token string, _ map[string]any, w http.ResponseWriter) {
+		tokens = append(tokens, token)
+		_, _ = fmt.Fprint(w, `{"result":"你好","is_end":true}`)
+	}
+	llm := newTestLLM(t, f.server(t))
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}}
+	if _, err := llm.Chat(context.Background(), msgs); err != nil {
+		t.Fatal(err)
+	}
+	// 服务端提前让 t1 失效，客户端应刷新 token 后重试
+	f.expiredTokens.Store("t1", true)
+	resp, err := llm.Chat(context.Background(), msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Choices[0].Content != "你好" || strings.Join(tokens, ",") != "t1,t2" {
+		t.Fatalf("tokens = %v", tokens)
+	}
+}
+
+func TestChatTokenExpiry(t *testing.T) {
+	// expires_in 为 0 时每次调用都需要重新换取
+	f := &fakeQianfan{}
+	f.chat = func(_ string, _ map[string]any, w http.ResponseWriter) {
+		_, _ = fmt.Fprint(w, `{"result":"你好","is_end":true}`)
+	}
+	llm := newTestLLM(t, f.server(t))
+	for i := 0; i < 2; i++ {
+		if _, err := llm.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := f.tokenCalls.Load(); n != 2 {
+		t.Fatalf("token requested %d times", n)
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	f := &fakeQianfan{expiresIn: 2592000}
+	f.chat = func(_ string, body map[string]any, w http.ResponseWriter) {
+		if body["stream"] != true || body["response_format"] != "json_object" {
+			_, _ = fmt.Fprint(w, `{"error_code":336003,"error_msg":"bad request"}`)
+			return
+		}
+		for i, s := range []string{`{\"a\":`, `1}`} {
+			_, _ = fmt.Fprintf(w, "data: {\"sentence_id\":%d,\"is_end\":false,\"result\":\"%s\",\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":%d,\"total_tokens\":%d}}\n\n", i, s, i+1, i+4)
+		}
+		_, _ = fmt.Fprint(w, "data: {\"sentence_id\":2,\"is_end\":true,\"result\":\"\",\"finish_reason\":\"normal\",\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":4,\"total_tokens\":7}}\n\n")
+	}
+	llm := newTestLLM(t, f.server(t))
+	var streamed strings.Builder
+	resp, err := llm.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "返回 json"}},
+		kpllms.WithJsonMode(true),
+		kpllms.WithStreamingFunc(func(ctx context.Context, chunk []byte, innerErr error) error {
+			streamed.Write(chunk)
+			return nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := resp.Choices[0]
+	if streamed.String() != `{"a":1}` || c.Content != `{"a":1}` || c.StopReason != "normal" || c.Usage.TotalTokens != 7 {
+		t.Fatalf("streamed = %q, choice = %+v, usage = %+v", streamed.String(), c, c.Usage)
+	}
+}
+
+func TestChatError(t *testing.T) {
+	f := &fakeQianfan{expiresIn: 2592000}
+	f.chat = func(_ string, _ map[string]any, w http.ResponseWriter) {
+		_, _ = fmt.Fprint(w, `{"error_code":336100,"error_msg":"try again later"}`)
+	}
+	_, err := newTestLLM(t, f.server(t)).Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}})
+	var e *Error
+	if !errors.As(err, &e) || e.Code != 336100 {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	f := &fakeQianfan{expiresIn: 2592000}
+	llm := newTestLLM(t, f.server(t))
+	texts := make([]string, 20)
+	for i := range texts {
+		texts[i] = fmt.Sprint(i)
+	}
+	embeddings, err := llm.EmbedDocuments(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 分两批 16 + 4，每批内按 index 排序
+	if len(embeddings) != 20 || embeddings[15][0] != 15 || embeddings[16][0] != 0 {
+		t.Fatalf("embeddings = %v", embeddings)
+	}
+}
+
+func TestMissingToken(t *testing.T) {
+	t.Setenv(apiKeyEnvVarName, "")
+	t.Setenv(secretKeyEnvVarName, "")
+	t.Setenv(accessTokenEnvVarName, "")
+	if _, err := New(); !errors.Is(err, ErrMissingToken) {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package ernieclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/comqositi/kpllms/internal/httputils"
+)
+
+// 千帆 chat 接口实现，文档：https://cloud.baidu.com/doc/WENXINWORKSHOP/s/jlil56u11
+
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleFunction  = "function"
+)
+
+// ChatRequest chat 接口请求
+type ChatRequest struct {
+	Messages        []*ChatMessage `json:"messages"`
+	System          string         `json:"system,omitempty"`
+	Temperature     *float64       `json:"temperature,omitempty"`
+	TopP            *float64       `json:"top_p,omitempty"`
+	PenaltyScore    float64        `json:"penalty_score,omitempty"`
+	Stream          bool           `json:"stream,omitempty"`
+	Stop            []string       `json:"stop,omitempty"`
+	MaxOutputTokens int            `json:"max_output_tokens,omitempty"`
+	ResponseFormat  string         `json:"response_format,omitempty"`
+	UserID          string         `json:"user_id,omitempty"`
+	Functions       []*Function    `json:"functions,omitempty"`
+	ToolChoice      *ToolChoice    `json:"tool_choice,omitempty"`
+
+	// 模型对应的接口地址，例如 completions、completions_pro、ernie-speed-128k
+	Endpoint string `json:"-"`
+	// 流式放回的回调函数
+	StreamingFunc func(ctx context.Context, chunk []byte, innerErr error) error `json:"-"`
+}
+
+// ChatMessage role: user assistant function，function 消息需要 name
+type ChatMessage struct {
+	Role         string        `json:"role"`
+	Content      string        `json:"content"`
+	Name         string        `json:"name,omitempty"`
+	FunctionCall *FunctionCall `json:"function_call,omitempty"`
+}
+
+type Function struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Parameters  any    `json:"parameters"`
+}
+
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Thoughts  string `json:"thoughts,omitempty"`
+}
+
+// ToolChoice 指定调用的函数 {"type":"function","function":{"name":"xx"}}
+type ToolChoice struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name string `json:"name"`
+	} `json:"function"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatResponse chat 接口返回，流式返回时每个 chunk 也是该结构
+type ChatResponse struct {
+	ID               string        `json:"id"`
+	Object           string        `json:"object"`
+	Created          int64         `json:"created"`
+	SentenceID       int           `json:"sentence_id"`
+	IsEnd            bool          `json:"is_end"`
+	IsTruncated      bool          `json:"is_truncated"`
+	Result           string        `json:"result"`
+	FinishReason     string        `json:"finish_reason"`
+	NeedClearHistory bool          `json:"need_clear_history"`
+	BanRound         int           `json:"ban_round"`
+	FunctionCall     *FunctionCall `json:"function_call,omitempty"`
+	Usage            Usage         `json:"usage"`
+
+	Error
+}
+
+// CreateChat 调用 chat 接口，流式返回时拼接所有 chunk
+func (c *Client) CreateChat(ctx context.Context, r *ChatRequest) (*ChatResponse, error) {
+	r.Stream = r.StreamingFunc != nil
+	var response *ChatResponse
+	err := c.withToken(ctx, func(token string) error {
+		url := fmt.Sprintf("%s%s/chat/%s?access_token=%s", c.baseURL, wenxinworkshopPath, r.Endpoint, token)
+		var err error
+		if r.Stream {
+			response, err = c.stream(ctx, url, r)
+			return err
+		}
+		response = &ChatResponse{}
+		if err = httputils.HttpPost(ctx, url, r, c.setHeaders(), response); err != nil {
+			return err
+		}
+		if response.Code != 0 {
+			return &response.Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response.Result == "" && response.FunctionCall == nil && response.FinishReason == "" {
+		return nil, ErrEmptyResponse
+	}
+	return response, nil
+}
+
+func (c *Client) stream(ctx context.Context, url string, r *ChatRequest) (*ChatResponse, error) {
+	response := &ChatResponse{}
+	var sb strings.Builder
+	// HttpStream 会把回调返回的错误转为 HttpError，这里单独保存接口错误，便于判断 access_token 失效
+	var apiErr *Error
+	err := httputils.HttpStream(ctx, url, r, c.setHeaders(), func(ctx context.Context, line string) error {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return nil
+		}
+		// 出错时返回的不是 SSE 格式，而是一行 JSON
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		var chunk ChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return err
+		}
+		if chunk.Code != 0 {
+			apiErr = &chunk.Error
+			return apiErr
+		}
+		response.ID = chunk.ID
+		response.Object = chunk.Object
+		response.Created = chunk.Created
+		response.SentenceID = chunk.SentenceID
+		response.IsEnd = chunk.IsEnd
+		response.IsTruncated = chunk.IsTruncated
+		response.NeedClearHistory = chunk.NeedClearHistory
+		response.BanRound = chunk.BanRound
+		if chunk.FinishReason != "" {
+			response.FinishReason = chunk.FinishReason
+		}
+		if chunk.FunctionCall != nil {
+			response.FunctionCall = chunk.FunctionCall
+		}
+		// usage 在每个 chunk 中都是累计值，最后一个 chunk（is_end）为最终值
+		response.Usage = chunk.Usage
+		if chunk.Result == "" {
+			return nil
+		}
+		sb.WriteString(chunk.Result)
+		return r.StreamingFunc(ctx, []byte(chunk.Result), nil)
+	})
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !response.IsEnd {
+		return nil, errors.New("stream closed before is_end")
+	}
+	response.Result = sb.String()
+	return response, nil
+}
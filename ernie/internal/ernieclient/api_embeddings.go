@@ -0,0 +1,54 @@
+package ernieclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/comqositi/kpllms/internal/httputils"
+)
+
+// embeddings 单次最多 16 条文本
+const maxEmbeddingBatchSize = 16
+
+type embeddingRequest struct {
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage Usage `json:"usage"`
+
+	Error
+}
+
+// CreateEmbedding 文本向量化，model 为接口地址，例如 embedding-v1、bge_large_zh、tao_8k，超过单次上限时分批请求
+func (c *Client) CreateEmbedding(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbeddingBatchSize {
+		end := min(start+maxEmbeddingBatchSize, len(texts))
+		var resp embeddingResponse
+		err := c.withToken(ctx, func(token string) error {
+			url := fmt.Sprintf("%s%s/embeddings/%s?access_token=%s", c.baseURL, wenxinworkshopPath, model, token)
+			resp = embeddingResponse{}
+			if err := httputils.HttpPost(ctx, url, &embeddingRequest{Input: texts[start:end]}, c.setHeaders(), &resp); err != nil {
+				return err
+			}
+			if resp.Code != 0 {
+				return &resp.Error
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(resp.Data, func(i, j int) bool { return resp.Data[i].Index < resp.Data[j].Index })
+		for _, d := range resp.Data {
+			embeddings = append(embeddings, d.Embedding)
+		}
+	}
+	return embeddings, nil
+}
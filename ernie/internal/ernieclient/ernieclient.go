@@ -0,0 +1,161 @@
+package ernieclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/comqositi/kpllms/internal/httputils"
+)
+
+const (
+	defaultBaseURL = "https://aip.baidubce.com"
+	// 千帆 chat 和 embeddings 接口前缀
+	wenxinworkshopPath = "/rpc/2.0/ai_custom/v1/wenxinworkshop"
+	// access_token 在有效期剩余 10% 时刷新
+	refreshRatio = 0.9
+)
+
+var (
+	ErrNotSetAuth    = errors.New("both accessToken and apiKey secretKey are not set")
+	ErrEmptyResponse = errors.New("empty response")
+)
+
+// Client 千帆（文心一言）客户端
+type Client struct {
+	apiKey    string
+	secretKey string
+	baseURL   string
+
+	// 固定的 access_token，设置后不再用 api key 换取
+	staticToken string
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// Option is an option for the ERNIE client.
+type Option func(*Client) error
+
+// New 创建千帆客户端，需要 api key + secret key 或 access_token
+func New(opts ...Option) (*Client, error) {
+	c := &Client{}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.staticToken == "" && (c.apiKey == "" || c.secretKey == "") {
+		return nil, ErrNotSetAuth
+	}
+	if c.baseURL == "" {
+		c.baseURL = defaultBaseURL
+	}
+	c.baseURL = strings.TrimSuffix(c.baseURL, "/")
+	return c, nil
+}
+
+func WithAKSK(apiKey, secretKey string) Option {
+	return func(c *Client) error {
+		c.apiKey = apiKey
+		c.secretKey = secretKey
+		return nil
+	}
+}
+
+func WithAccessToken(value string) Option {
+	return func(c *Client) error {
+		c.staticToken = value
+		return nil
+	}
+}
+
+func WithBaseURL(value string) Option {
+	return func(c *Client) error {
+		c.baseURL = value
+		return nil
+	}
+}
+
+type accessTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// getAccessToken 返回缓存的 access_token，过期前自动刷新，并发调用只会请求一次
+func (c *Client) getAccessToken(ctx context.Context) (string, error) {
+	if c.staticToken != "" {
+		return c.staticToken, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.accessToken != "" && time.Now().Before(c.expiresAt) {
+		return c.accessToken, nil
+	}
+
+	tokenURL := fmt.Sprintf("%s/oauth/2.0/token?grant_type=client_credentials&client_id=%s&client_secret=%s",
+		c.baseURL, url.QueryEscape(c.apiKey), url.QueryEscape(c.secretKey))
+	var resp accessTokenResponse
+	if err := httputils.HttpPost(ctx, tokenURL, struct{}{}, map[string]string{"Content-Type": "application/json"}, &resp); err != nil {
+		return "", err
+	}
+	if resp.Error != "" || resp.AccessToken == "" {
+		return "", fmt.Errorf("get access_token failed: %s %s", resp.Error, resp.ErrorDescription)
+	}
+	c.accessToken = resp.AccessToken
+	c.expiresAt = time.Now().Add(time.Duration(float64(resp.ExpiresIn)*refreshRatio) * time.Second)
+	return c.accessToken, nil
+}
+
+// invalidateToken access_token 失效时清除缓存，只清除与 token 相同的缓存，避免覆盖其他协程刚刷新的 token
+func (c *Client) invalidateToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.accessToken == token {
+		c.accessToken = ""
+	}
+}
+
+// Error 千帆接口返回的错误
+type Error struct {
+	Code int    `json:"error_code"`
+	Msg  string `json:"error_msg"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("ernie error code: %d, errMsg: %s", e.Code, e.Msg)
+}
+
+// isTokenError access_token 无效或过期
+func isTokenError(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && (e.Code == 110 || e.Code == 111)
+}
+
+// withToken 使用 access_token 调用接口，token 失效时刷新后重试一次
+func (c *Client) withToken(ctx context.Context, call func(token string) error) error {
+	token, err := c.getAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+	err = call(token)
+	if !isTokenError(err) || c.staticToken != "" {
+		return err
+	}
+	c.invalidateToken(token)
+	if token, err = c.getAccessToken(ctx); err != nil {
+		return err
+	}
+	return call(token)
+}
+
+func (c *Client) setHeaders() map[string]string {
+	return map[string]string{"Content-Type": "application/json"}
+}
@@ -0,0 +1,63 @@
+package gemini
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/comqositi/kpllms/gemini/internal/geminiclient"
+)
+
+var (
+	ErrEmptyResponse            = errors.New("no response")
+	ErrMissingToken             = errors.New("missing the Gemini API key, set it in the GEMINI_API_KEY environment variable") //nolint:lll
+	ErrUnexpectedResponseLength = errors.New("unexpected length of response")
+	// ErrBlocked 输入或输出被安全策略拦截，可用 errors.Is 判断，errors.As 取 *BlockedError 查看原因
+	ErrBlocked = errors.New("gemini: content blocked")
+)
+
+// SafetySetting 安全设置，例如 {Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_ONLY_HIGH"}
+type SafetySetting = geminiclient.SafetySetting
+
+// SafetyRating 安全评级
+type SafetyRating = geminiclient.SafetyRating
+
+// 会被判定为拦截的结束原因
+var blockedFinishReasons = map[string]bool{
+	"SAFETY":             true,
+	"RECITATION":         true,
+	"BLOCKLIST":          true,
+	"PROHIBITED_CONTENT": true,
+	"SPII":               true,
+	"IMAGE_SAFETY":       true,
+}
+
+// BlockedError 内容被拦截的错误，Prompt 为 true 表示输入被拦截，否则为输出被拦截
+type BlockedError struct {
+	Prompt        bool
+	Reason        string
+	SafetyRatings []SafetyRating
+}
+
+func (e *BlockedError) Error() string {
+	if e.Prompt {
+		return fmt.Sprintf("gemini: prompt blocked, reason: %s", e.Reason)
+	}
+	return fmt.Sprintf("gemini: response blocked, finish reason: %s", e.Reason)
+}
+
+func (e *BlockedError) Is(target error) bool {
+	return target == ErrBlocked
+}
+
+// checkBlocked 输入被拦截或任一候选结果因安全原因结束时返回 *BlockedError
+func checkBlocked(resp *geminiclient.GenerateContentResponse) error {
+	if fb := resp.PromptFeedback; fb != nil && fb.BlockReason != "" {
+		return &BlockedError{Prompt: true, Reason: fb.BlockReason, SafetyRatings: fb.SafetyRatings}
+	}
+	for _, c := range resp.Candidates {
+		if blockedFinishReasons[c.FinishReason] {
+			return &BlockedError{Reason: c.FinishReason, SafetyRatings: c.SafetyRatings}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,393 @@
+package gemini
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/gemini/internal/geminiclient"
+	"github.com/comqositi/kpllms/schema"
+	"github.com/google/uuid"
+)
+
+// 远程图片下载大小上限
+const maxImageSize = 20 << 20
+
+type LLM struct {
+	client         *geminiclient.Client
+	safetySettings []SafetySetting
+}
+
+var (
+	_ kpllms.Model    = (*LLM)(nil)
+	_ kpllms.Embedder = (*LLM)(nil)
+)
+
+// New 创建大模型 model 和 embedder 的实现
+func New(opts ...Option) (*LLM, error) {
+	options := &options{
+		token:   getEnvs(tokenEnvVarName, googleTokenEnvVarName),
+		model:   os.Getenv(modelEnvVarName),
+		baseURL: os.Getenv(baseURLEnvVarName),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.token == "" {
+		return nil, ErrMissingToken
+	}
+	c, err := geminiclient.New(
+		geminiclient.WithToken(options.token),
+		geminiclient.WithModel(options.model),
+		geminiclient.WithEmbeddingsModel(options.embeddingModel),
+		geminiclient.WithBaseURL(options.baseURL),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &LLM{client: c, safetySettings: options.safetySettings}, nil
+}
+
+func getEnvs(keys ...string) string {
+	for _, key := range keys {
+		if val := os.Getenv(key); val != "" {
+			return val
+		}
+	}
+	return ""
+}
+
+// samplingRanges gemini 支持的采样参数范围
+var samplingRanges = kpllms.SamplingRanges{
+	Temperature: kpllms.Range{Min: 0, Max: 2},
+	TopP:        kpllms.Range{Min: 0, Max: 1},
+	Penalty:     kpllms.Range{Min: -2, Max: 2},
+}
+
+// Chat 实现大模型接口
+func (o *LLM) Chat(ctx context.Context, messages []*schema.ChatMessage, options ...kpllms.CallOption) (*schema.ContentResponse, error) {
+	opts := kpllms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if err := samplingRanges.Validate(&opts); err != nil {
+		return nil, err
+	}
+	if err := opts.CheckSupported("gemini", kpllms.OptionStop, kpllms.OptionN, kpllms.OptionFrequencyPenalty, kpllms.OptionPresencePenalty, kpllms.OptionSeed); err != nil {
+		return nil, err
+	}
+
+	system, contents, err := messagesToContents(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	req := &geminiclient.GenerateContentRequest{
+		Model:             opts.Model,
+		Contents:          contents,
+		SystemInstruction: system,
+		SafetySettings:    o.safetySettings,
+		StreamingFunc:     opts.StreamingFunc,
+		GenerationConfig: &geminiclient.GenerationConfig{
+			MaxOutputTokens:  kpllms.Deref(opts.MaxTokens),
+			StopSequences:    opts.Stop,
+			CandidateCount:   opts.N,
+			Temperature:      opts.Temperature,
+			TopP:             opts.TopP,
+			PresencePenalty:  opts.PresencePenalty,
+			FrequencyPenalty: opts.FrequencyPenalty,
+			Seed:             opts.Seed,
+		},
+	}
+	// 使用 json 格式返回
+	if opts.JsonMode {
+		req.GenerationConfig.ResponseMimeType = "application/json"
+	}
+
+	// 组装工具，所有函数放在同一个 tool 中
+	if len(opts.Tools) > 0 {
+		tool := geminiclient.Tool{}
+		for _, t := range opts.Tools {
+			if t.Type != schema.ToolCallTypeFunction || t.Function == nil {
+				return nil, fmt.Errorf("failed to convert llms tool to gemini tool: tool type %v not supported", t.Type)
+			}
+			tool.FunctionDeclarations = append(tool.FunctionDeclarations, &geminiclient.FunctionDeclaration{
+				Name:        t.Function.Name,
+				Description: t.Function.Description,
+				Parameters:  t.Function.Parameters,
+			})
+		}
+		req.Tools = []geminiclient.Tool{tool}
+
+		switch opts.ToolChoice.Type {
+		case schema.ToolChoiceTypeFunction:
+			req.ToolConfig = &geminiclient.ToolConfig{FunctionCallingConfig: &geminiclient.FunctionCallingConfig{
+				Mode:                 geminiclient.FunctionCallingAny,
+				AllowedFunctionNames: []string{opts.ToolChoice.Function.Name},
+			}}
+		case schema.ToolChoiceTypeNone:
+			req.ToolConfig = &geminiclient.ToolConfig{FunctionCallingConfig: &geminiclient.FunctionCallingConfig{
+				Mode: geminiclient.FunctionCallingNone,
+			}}
+		}
+	}
+
+	result, err := o.client.GenerateContent(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkBlocked(result); err != nil {
+		return nil, err
+	}
+	if len(result.Candidates) == 0 {
+		return nil, ErrEmptyResponse
+	}
+
+	var usage *schema.Usage
+	if u := result.UsageMetadata; u != nil {
+		usage = &schema.Usage{
+			PromptTokens:     u.PromptTokenCount,
+			CompletionTokens: u.CandidatesTokenCount,
+			TotalTokens:      u.TotalTokenCount,
+		}
+	}
+	choices := make([]*schema.ContentChoice, 0, len(result.Candidates))
+	for _, c := range result.Candidates {
+		choice := &schema.ContentChoice{
+			StopReason: c.FinishReason,
+			Usage:      usage,
+			GenerationInfo: map[string]any{
+				"model_version":  result.ModelVersion,
+				"safety_ratings": c.SafetyRatings,
+			},
+		}
+		if c.Content != nil {
+			for _, p := range c.Content.Parts {
+				if p.FunctionCall == nil {
+					choice.Content += p.Text
+					continue
+				}
+				args, err := json.Marshal(p.FunctionCall.Args)
+				if err != nil {
+					return nil, err
+				}
+				if p.FunctionCall.Args == nil {
+					args = []byte("{}")
+				}
+				// gemini 的函数调用不一定有 id，这里生成一个，返回结果时用于找回函数名
+				id := p.FunctionCall.ID
+				if id == "" {
+					id = "call_" + uuid.NewString()
+				}
+				choice.ToolCalls = append(choice.ToolCalls, &schema.ToolCall{
+					Id:   id,
+					Type: schema.ToolCallTypeFunction,
+					Function: schema.FunctionCall{
+						Name:      p.FunctionCall.Name,
+						Arguments: string(args),
+					},
+				})
+			}
+		}
+		choices = append(choices, choice)
+	}
+	return &schema.ContentResponse{Choices: choices}, nil
+}
+
+// messagesToContents system 转为 systemInstruction，assistant 转为 model，
+// tool 消息转为 functionResponse，连续的 tool 消息合并为一轮
+func messagesToContents(ctx context.Context, messages []*schema.ChatMessage) (*geminiclient.Content, []*geminiclient.Content, error) {
+	var system *geminiclient.Content
+	contents := make([]*geminiclient.Content, 0, len(messages))
+	// tool 消息只有 ToolCallId，函数名从之前的 assistant 消息中查找
+	toolNames := map[string]string{}
+	for _, mc := range messages {
+		switch mc.Role {
+		case schema.RoleSystem:
+			parts, err := partsFromContent(ctx, mc.Content)
+			if err != nil {
+				return nil, nil, err
+			}
+			if system == nil {
+				system = &geminiclient.Content{}
+			}
+			system.Parts = append(system.Parts, parts...)
+		case schema.RoleUser:
+			parts, err := partsFromContent(ctx, mc.Content)
+			if err != nil {
+				return nil, nil, err
+			}
+			contents = append(contents, &geminiclient.Content{Role: geminiclient.RoleUser, Parts: parts})
+		case schema.RoleAssistant:
+			content := &geminiclient.Content{Role: geminiclient.RoleModel}
+			if mc.Content != nil {
+				parts, err := partsFromContent(ctx, mc.Content)
+				if err != nil {
+					return nil, nil, err
+				}
+				for _, p := range parts {
+					if p.Text != "" || p.InlineData != nil {
+						content.Parts = append(content.Parts, p)
+					}
+				}
+			}
+			for _, t := range mc.ToolCalls {
+				toolNames[t.Id] = t.Function.Name
+				var args map[string]any
+				if strings.TrimSpace(t.Function.Arguments) != "" {
+					if err := json.Unmarshal([]byte(t.Function.Arguments), &args); err != nil {
+						return nil, nil, fmt.Errorf("tool call %s arguments is not a json object: %w", t.Function.Name, err)
+					}
+				}
+				content.Parts = append(content.Parts, &geminiclient.Part{
+					FunctionCall: &geminiclient.FunctionCall{Name: t.Function.Name, Args: args},
+				})
+			}
+			contents = append(contents, content)
+		case schema.RoleTool:
+			name := mc.Name
+			if name == "" {
+				name = toolNames[mc.ToolCallId]
+			}
+			if name == "" {
+				return nil, nil, fmt.Errorf("tool message %s: function name not found", mc.ToolCallId)
+			}
+			part := &geminiclient.Part{FunctionResponse: &geminiclient.FunctionResponse{
+				Name:     name,
+				Response: functionResponse(mc.Content),
+			}}
+			if n := len(contents); n > 0 && isFunctionResponses(contents[n-1]) {
+				contents[n-1].Parts = append(contents[n-1].Parts, part)
+			} else {
+				contents = append(contents, &geminiclient.Content{Role: geminiclient.RoleUser, Parts: []*geminiclient.Part{part}})
+			}
+		default:
+			return nil, nil, fmt.Errorf("role %v not supported", mc.Role)
+		}
+	}
+	return system, contents, nil
+}
+
+func isFunctionResponses(c *geminiclient.Content) bool {
+	for _, p := range c.Parts {
+		if p.FunctionResponse == nil {
+			return false
+		}
+	}
+	return len(c.Parts) > 0
+}
+
+// functionResponse response 必须是对象，JSON 对象直接使用，其他内容放到 content 字段
+func functionResponse(content any) map[string]any {
+	s, ok := content.(string)
+	if !ok {
+		return map[string]any{"content": content}
+	}
+	var m map[string]any
+	if err := json.Unmarshal([]byte(s), &m); err == nil && m != nil {
+		return m
+	}
+	return map[string]any{"content": s}
+}
+
+// partsFromContent 把 string 或 TextContent/ImageContent 数组转为 parts，图片转为 inlineData
+func partsFromContent(ctx context.Context, content any) ([]*geminiclient.Part, error) {
+	var items []any
+	switch c := content.(type) {
+	case string:
+		return []*geminiclient.Part{{Text: c}}, nil
+	case []any:
+		items = c
+	case []schema.TextContent:
+		for _, p := range c {
+			items = append(items, p)
+		}
+	default:
+		return nil, fmt.Errorf("content type %T not supported", content)
+	}
+	parts := make([]*geminiclient.Part, 0, len(items))
+	for _, item := range items {
+		var imageUrl string
+		switch p := item.(type) {
+		case schema.TextContent:
+			parts = append(parts, &geminiclient.Part{Text: p.Text})
+			continue
+		case *schema.TextContent:
+			parts = append(parts, &geminiclient.Part{Text: p.Text})
+			continue
+		case schema.ImageContent:
+			imageUrl = p.ImageUrl.Url
+		case *schema.ImageContent:
+			imageUrl = p.ImageUrl.Url
+		default:
+			return nil, fmt.Errorf("content part type %T not supported", item)
+		}
+		blob, err := inlineData(ctx, imageUrl)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, &geminiclient.Part{InlineData: blob})
+	}
+	return parts, nil
+}
+
+// inlineData data:image/png;base64,xxx 直接使用，http 地址下载后转为 base64
+func inlineData(ctx context.Context, url string) (*geminiclient.Blob, error) {
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		meta, data, ok := strings.Cut(rest, ",")
+		if !ok || !strings.HasSuffix(meta, ";base64") {
+			return nil, fmt.Errorf("unsupported image data url")
+		}
+		return &geminiclient.Blob{MimeType: strings.TrimSuffix(meta, ";base64"), Data: data}, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, schema.NewHttpError(0, err.Error())
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, schema.NewHttpError(0, err.Error())
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, schema.NewHttpError(resp.StatusCode, string(b))
+	}
+	if len(b) > maxImageSize {
+		return nil, fmt.Errorf("image %s is larger than %d bytes", url, maxImageSize)
+	}
+	mimeType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	if !strings.HasPrefix(mimeType, "image/") {
+		mimeType = http.DetectContentType(b)
+	}
+	return &geminiclient.Blob{MimeType: mimeType, Data: base64.StdEncoding.EncodeToString(b)}, nil
+}
+
+// EmbedDocuments  实现 embedder 接口 文档存储向量：存入数据库的向量，被用于检索
+func (o *LLM) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings, err := o.client.BatchEmbedContents(ctx, texts, geminiclient.TaskTypeRetrievalDocument)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	if len(texts) != len(embeddings) {
+		return embeddings, ErrUnexpectedResponseLength
+	}
+	return embeddings, nil
+}
+
+// EmbedQuery  实现 embedder 接口 查询向量：对需要用于检索的文本进行想量化
+func (o *LLM) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return o.client.EmbedContent(ctx, text, geminiclient.TaskTypeRetrievalQuery)
+}
@@ -0,0 +1,59 @@
+package gemini
+
+const (
+	tokenEnvVarName       = "GEMINI_API_KEY"  //nolint:gosec
+	googleTokenEnvVarName = "GOOGLE_API_KEY"  //nolint:gosec
+	modelEnvVarName       = "GEMINI_MODEL"    //nolint:gosec
+	baseURLEnvVarName     = "GEMINI_BASE_URL" //nolint:gosec
+)
+
+type options struct {
+	token          string
+	model          string
+	embeddingModel string
+	baseURL        string
+	safetySettings []SafetySetting
+}
+
+// Option is a functional option for the Gemini client.
+type Option func(*options)
+
+// WithToken passes the Gemini API key to the client. If not set, the key
+// is read from the GEMINI_API_KEY or GOOGLE_API_KEY environment variable.
+func WithToken(token string) Option {
+	return func(opts *options) {
+		opts.token = token
+	}
+}
+
+// WithModel passes the Gemini model to the client. If not set, the model
+// is read from the GEMINI_MODEL environment variable, and defaults to gemini-1.5-flash.
+func WithModel(model string) Option {
+	return func(opts *options) {
+		opts.model = model
+	}
+}
+
+// WithEmbeddingModel passes the embedding model to the client. If not set, the
+// default value is text-embedding-004.
+func WithEmbeddingModel(embeddingModel string) Option {
+	return func(opts *options) {
+		opts.embeddingModel = embeddingModel
+	}
+}
+
+// WithBaseURL passes the Gemini base url to the client. If not set, the base url
+// is read from the GEMINI_BASE_URL environment variable, and defaults to
+// https://generativelanguage.googleapis.com/v1beta.
+func WithBaseURL(baseURL string) Option {
+	return func(opts *options) {
+		opts.baseURL = baseURL
+	}
+}
+
+// WithSafetySettings sets the safety settings sent with every request.
+func WithSafetySettings(settings ...SafetySetting) Option {
+	return func(opts *options) {
+		opts.safetySettings = settings
+	}
+}
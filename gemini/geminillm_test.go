@@ -0,0 +1,206 @@
+package gemini
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+)
+
+// newTestServer handlers 以 "模型:方法" 为 key，例如 models/gemini-test:generateContent
+func newTestServer(t *testing.T, handlers map[string]func(body map[string]any, w http.ResponseWriter)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/image.png" {
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("png-bytes"))
+			return
+		}
+		if r.Header.Get("x-goog-api-key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		h, ok := handlers[strings.TrimPrefix(r.URL.Path, "/v1beta/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		h(body, w)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestLLM(t *testing.T, srv *httptest.Server) *LLM {
+	t.Helper()
+	llm, err := New(WithToken("key"), WithBaseURL(srv.URL+"/v1beta"), WithModel("gemini-test"), WithEmbeddingModel("embed-test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return llm
+}
+
+func TestChat(t *testing.T) {
+	var got map[string]any
+	srv := newTestServer(t, map[string]func(map[string]any, http.ResponseWriter){
+		"models/gemini-test:generateContent": func(body map[string]any, w http.ResponseWriter) {
+			got = body
+			_, _ = io.WriteString(w, `{"candidates":[{"index":0,"finishReason":"STOP","content":{"role":"model","parts":[
+				{"text":"查询中"},{"functionCall":{"name":"get_weather","args":{"city":"上海"}}}]}}],
+				"usageMetadata":{"promptTokenCount":12,"candidatesTokenCount":5,"totalTokenCount":17}}`)
+		},
+	})
+	llm := newTestLLM(t, srv)
+	tool := &kpllms.Tool{Type: schema.ToolCallTypeFunction, Function: &kpllms.FunctionDefinition{
+		Name: "get_weather", Parameters: map[string]any{"type": "object"},
+	}}
+	messages := []*schema.ChatMessage{
+		{Role: schema.RoleSystem, Content: "你是天气助手"},
+		{Role: schema.RoleUser, Content: []any{
+			schema.TextContent{Type: schema.MultiContentText, Text: "图里是哪里的天气"},
+			schema.ImageContent{Type: schema.MultiContentImageUrl, ImageUrl: schema.ImageUrl{Url: "data:image/jpeg;base64,AAAA"}},
+		}},
+		{Role: schema.RoleAssistant, ToolCalls: []*schema.ToolCall{
+			{Id: "call_1", Type: schema.ToolCallTypeFunction, Function: schema.FunctionCall{Name: "get_weather", Arguments: `{"city":"北京"}`}},
+		}},
+		{Role: schema.RoleTool, ToolCallId: "call_1", Content: "晴"},
+	}
+	resp, err := llm.Chat(context.Background(), messages, kpllms.WithTools([]*kpllms.Tool{tool}), kpllms.WithJsonMode(true),
+		kpllms.WithToolChoice(kpllms.ToolChoice{Type: schema.ToolChoiceTypeFunction, Function: kpllms.ToolChoiceFunction{Name: "get_weather"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := json.Marshal(got)
+	for _, want := range []string{
+		`"systemInstruction":{"parts":[{"text":"你是天气助手"}]}`,
+		`"inlineData":{"data":"AAAA","mimeType":"image/jpeg"}`,
+		`{"parts":[{"functionCall":{"args":{"city":"北京"},"name":"get_weather"}}],"role":"model"}`,
+		`{"parts":[{"functionResponse":{"name":"get_weather","response":{"content":"晴"}}}],"role":"user"}`,
+		`"functionDeclarations":[{"name":"get_weather","parameters":{"type":"object"}}]`,
+		`"functionCallingConfig":{"allowedFunctionNames":["get_weather"],"mode":"ANY"}`,
+		`"responseMimeType":"application/json"`,
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("request missing %s\n%s", want, b)
+		}
+	}
+
+	c := resp.Choices[0]
+	if c.Content != "查询中" || c.StopReason != "STOP" || c.Usage.TotalTokens != 17 {
+		t.Fatalf("unexpected choice: %+v", c)
+	}
+	if len(c.ToolCalls) != 1 || c.ToolCalls[0].Id == "" || c.ToolCalls[0].Function.Arguments != `{"city":"上海"}` {
+		t.Fatalf("unexpected tool calls: %+v", c.ToolCalls)
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	chunks := []string{
+		`{"candidates":[{"index":0,"content":{"role":"model","parts":[{"text":"你好"}]}}]}`,
+		`{"candidates":[{"index":0,"content":{"role":"model","parts":[{"text":"，世界"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":4,"totalTokenCount":7}}`,
+	}
+	srv := newTestServer(t, map[string]func(map[string]any, http.ResponseWriter){
+		"models/gemini-test:streamGenerateContent": func(_ map[string]any, w http.ResponseWriter) {
+			for _, c := range chunks {
+				_, _ = fmt.Fprintf(w, "data: %s\r\n\r\n", c)
+			}
+		},
+	})
+	var streamed strings.Builder
+	resp, err := newTestLLM(t, srv).Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}},
+		kpllms.WithStreamingFunc(func(ctx context.Context, chunk []byte, innerErr error) error {
+			streamed.Write(chunk)
+			return nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := resp.Choices[0]
+	if streamed.String() != "你好，世界" || c.Content != "你好，世界" || c.StopReason != "STOP" || c.Usage.TotalTokens != 7 {
+		t.Fatalf("streamed = %q, choice = %+v", streamed.String(), c)
+	}
+}
+
+func TestChatBlocked(t *testing.T) {
+	srv := newTestServer(t, map[string]func(map[string]any, http.ResponseWriter){
+		"models/gemini-test:generateContent": func(_ map[string]any, w http.ResponseWriter) {
+			_, _ = io.WriteString(w, `{"candidates":[{"index":0,"finishReason":"SAFETY",
+				"safetyRatings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"HIGH","blocked":true}]}]}`)
+		},
+	})
+	_, err := newTestLLM(t, srv).Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "hi"}})
+	var blocked *BlockedError
+	if !errors.Is(err, ErrBlocked) || !errors.As(err, &blocked) {
+		t.Fatalf("err = %v", err)
+	}
+	if blocked.Prompt || blocked.Reason != "SAFETY" || len(blocked.SafetyRatings) != 1 {
+		t.Fatalf("unexpected error: %+v", blocked)
+	}
+}
+
+func TestInlineDataFromURL(t *testing.T) {
+	srv := newTestServer(t, nil)
+	blob, err := inlineData(context.Background(), srv.URL+"/image.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.MimeType != "image/png" || blob.Data != base64.StdEncoding.EncodeToString([]byte("png-bytes")) {
+		t.Fatalf("unexpected blob: %+v", blob)
+	}
+	if _, err = inlineData(context.Background(), srv.URL+"/missing.png"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	srv := newTestServer(t, map[string]func(map[string]any, http.ResponseWriter){
+		"models/embed-test:batchEmbedContents": func(body map[string]any, w http.ResponseWriter) {
+			reqs := body["requests"].([]any)
+			out := make([]map[string]any, 0, len(reqs))
+			for i, r := range reqs {
+				if r.(map[string]any)["taskType"] != "RETRIEVAL_DOCUMENT" || r.(map[string]any)["model"] != "models/embed-test" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				out = append(out, map[string]any{"values": []float32{float32(i), 1}})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"embeddings": out})
+		},
+		"models/embed-test:embedContent": func(body map[string]any, w http.ResponseWriter) {
+			if body["taskType"] != "RETRIEVAL_QUERY" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = io.WriteString(w, `{"embedding":{"values":[0.5,0.5]}}`)
+		},
+	})
+	llm := newTestLLM(t, srv)
+	docs, err := llm.EmbedDocuments(context.Background(), []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 3 || docs[2][0] != 2 {
+		t.Fatalf("docs = %v", docs)
+	}
+	q, err := llm.EmbedQuery(context.Background(), "q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q) != 2 {
+		t.Fatalf("query = %v", q)
+	}
+}
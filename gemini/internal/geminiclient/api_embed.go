@@ -0,0 +1,78 @@
+package geminiclient
+
+import (
+	"context"
+
+	"github.com/comqositi/kpllms/internal/httputils"
+)
+
+const (
+	TaskTypeRetrievalDocument = "RETRIEVAL_DOCUMENT"
+	TaskTypeRetrievalQuery    = "RETRIEVAL_QUERY"
+
+	// batchEmbedContents 单次最多 100 条
+	maxBatchEmbedSize = 100
+)
+
+type embedContentRequest struct {
+	Model    string   `json:"model"`
+	Content  *Content `json:"content"`
+	TaskType string   `json:"taskType,omitempty"`
+}
+
+type embedding struct {
+	Values []float32 `json:"values"`
+}
+
+type batchEmbedContentsRequest struct {
+	Requests []*embedContentRequest `json:"requests"`
+}
+
+type batchEmbedContentsResponse struct {
+	Embeddings []embedding `json:"embeddings"`
+}
+
+type embedContentResponse struct {
+	Embedding embedding `json:"embedding"`
+}
+
+// EmbedContent 单条文本向量化
+func (c *Client) EmbedContent(ctx context.Context, text, taskType string) ([]float32, error) {
+	req := &embedContentRequest{
+		Model:    modelName(c.EmbeddingsModel),
+		Content:  &Content{Parts: []*Part{{Text: text}}},
+		TaskType: taskType,
+	}
+	var resp embedContentResponse
+	if err := httputils.HttpPost(ctx, c.buildURL(c.EmbeddingsModel, "embedContent"), req, c.setHeaders(), &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Embedding.Values) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	return resp.Embedding.Values, nil
+}
+
+// BatchEmbedContents 批量向量化，超过单次上限时分批请求
+func (c *Client) BatchEmbedContents(ctx context.Context, texts []string, taskType string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxBatchEmbedSize {
+		end := min(start+maxBatchEmbedSize, len(texts))
+		req := &batchEmbedContentsRequest{Requests: make([]*embedContentRequest, 0, end-start)}
+		for _, text := range texts[start:end] {
+			req.Requests = append(req.Requests, &embedContentRequest{
+				Model:    modelName(c.EmbeddingsModel),
+				Content:  &Content{Parts: []*Part{{Text: text}}},
+				TaskType: taskType,
+			})
+		}
+		var resp batchEmbedContentsResponse
+		if err := httputils.HttpPost(ctx, c.buildURL(c.EmbeddingsModel, "batchEmbedContents"), req, c.setHeaders(), &resp); err != nil {
+			return nil, err
+		}
+		for _, e := range resp.Embeddings {
+			embeddings = append(embeddings, e.Values)
+		}
+	}
+	return embeddings, nil
+}
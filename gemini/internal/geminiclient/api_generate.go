@@ -0,0 +1,226 @@
+package geminiclient
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/comqositi/kpllms/internal/httputils"
+)
+
+// gemini generateContent 接口实现，文档：https://ai.google.dev/api/generate-content
+
+const (
+	RoleUser  = "user"
+	RoleModel = "model"
+
+	FunctionCallingAuto = "AUTO"
+	FunctionCallingAny  = "ANY"
+	FunctionCallingNone = "NONE"
+)
+
+// GenerateContentRequest generateContent 请求
+type GenerateContentRequest struct {
+	Contents          []*Content        `json:"contents"`
+	SystemInstruction *Content          `json:"systemInstruction,omitempty"`
+	Tools             []Tool            `json:"tools,omitempty"`
+	ToolConfig        *ToolConfig       `json:"toolConfig,omitempty"`
+	GenerationConfig  *GenerationConfig `json:"generationConfig,omitempty"`
+	SafetySettings    []SafetySetting   `json:"safetySettings,omitempty"`
+
+	// 请求的模型，拼接在 url 中
+	Model string `json:"-"`
+	// 流式放回的回调函数
+	StreamingFunc func(ctx context.Context, chunk []byte, innerErr error) error `json:"-"`
+}
+
+// Content 一轮对话内容，role: user 或 model
+type Content struct {
+	Role  string  `json:"role,omitempty"`
+	Parts []*Part `json:"parts"`
+}
+
+// Part 内容片段，只会设置其中一个字段
+type Part struct {
+	Text             string            `json:"text,omitempty"`
+	InlineData       *Blob             `json:"inlineData,omitempty"`
+	FunctionCall     *FunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *FunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// Blob 内联的二进制数据，Data 为 base64 编码
+type Blob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type FunctionCall struct {
+	ID   string         `json:"id,omitempty"`
+	Name string         `json:"name"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+type FunctionResponse struct {
+	ID       string         `json:"id,omitempty"`
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+type Tool struct {
+	FunctionDeclarations []*FunctionDeclaration `json:"functionDeclarations,omitempty"`
+}
+
+type FunctionDeclaration struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+type ToolConfig struct {
+	FunctionCallingConfig *FunctionCallingConfig `json:"functionCallingConfig,omitempty"`
+}
+
+// FunctionCallingConfig mode: AUTO ANY NONE，ANY 时可通过 AllowedFunctionNames 指定函数
+type FunctionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+type GenerationConfig struct {
+	StopSequences    []string `json:"stopSequences,omitempty"`
+	ResponseMimeType string   `json:"responseMimeType,omitempty"`
+	CandidateCount   int      `json:"candidateCount,omitempty"`
+	MaxOutputTokens  int      `json:"maxOutputTokens,omitempty"`
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"topP,omitempty"`
+	TopK             int      `json:"topK,omitempty"`
+	PresencePenalty  *float64 `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequencyPenalty,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+}
+
+type SafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+type SafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+type Candidate struct {
+	Index         int            `json:"index"`
+	Content       *Content       `json:"content,omitempty"`
+	FinishReason  string         `json:"finishReason,omitempty"`
+	SafetyRatings []SafetyRating `json:"safetyRatings,omitempty"`
+}
+
+type PromptFeedback struct {
+	BlockReason   string         `json:"blockReason,omitempty"`
+	SafetyRatings []SafetyRating `json:"safetyRatings,omitempty"`
+}
+
+type UsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+// GenerateContentResponse generateContent 返回，流式返回时每个 chunk 也是该结构
+type GenerateContentResponse struct {
+	Candidates     []*Candidate    `json:"candidates,omitempty"`
+	PromptFeedback *PromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *UsageMetadata  `json:"usageMetadata,omitempty"`
+	ModelVersion   string          `json:"modelVersion,omitempty"`
+}
+
+// GenerateContent 调用 generateContent，设置了 StreamingFunc 时调用 streamGenerateContent 并拼接结果
+func (c *Client) GenerateContent(ctx context.Context, r *GenerateContentRequest) (*GenerateContentResponse, error) {
+	if r.Model == "" {
+		r.Model = c.Model
+	}
+	var response GenerateContentResponse
+	if r.StreamingFunc == nil {
+		if err := httputils.HttpPost(ctx, c.buildURL(r.Model, "generateContent"), r, c.setHeaders(), &response); err != nil {
+			return nil, err
+		}
+		return &response, nil
+	}
+
+	err := httputils.HttpStream(ctx, c.buildURL(r.Model, "streamGenerateContent")+"?alt=sse", r, c.setHeaders(), func(ctx context.Context, line string) error {
+		if !strings.HasPrefix(line, "data:") {
+			return nil
+		}
+		var chunk GenerateContentResponse
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &chunk); err != nil {
+			return err
+		}
+		if chunk.PromptFeedback != nil {
+			response.PromptFeedback = chunk.PromptFeedback
+		}
+		if chunk.UsageMetadata != nil {
+			response.UsageMetadata = chunk.UsageMetadata
+		}
+		if chunk.ModelVersion != "" {
+			response.ModelVersion = chunk.ModelVersion
+		}
+		for _, cand := range chunk.Candidates {
+			if err := mergeCandidate(ctx, &response, cand, r.StreamingFunc); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// mergeCandidate 把流式 chunk 中的候选结果合并到 response，只有第一个候选结果的文本会回调 StreamingFunc
+func mergeCandidate(ctx context.Context, response *GenerateContentResponse, cand *Candidate,
+	streamingFunc func(ctx context.Context, chunk []byte, innerErr error) error,
+) error {
+	var target *Candidate
+	for _, c := range response.Candidates {
+		if c.Index == cand.Index {
+			target = c
+		}
+	}
+	if target == nil {
+		target = &Candidate{Index: cand.Index, Content: &Content{Role: RoleModel}}
+		response.Candidates = append(response.Candidates, target)
+	}
+	if cand.FinishReason != "" {
+		target.FinishReason = cand.FinishReason
+	}
+	if len(cand.SafetyRatings) > 0 {
+		target.SafetyRatings = cand.SafetyRatings
+	}
+	if cand.Content == nil {
+		return nil
+	}
+	for _, p := range cand.Content.Parts {
+		if p.FunctionCall != nil {
+			target.Content.Parts = append(target.Content.Parts, p)
+			continue
+		}
+		if p.Text == "" {
+			continue
+		}
+		// 文本片段拼接到最后一个文本 part
+		if n := len(target.Content.Parts); n > 0 && target.Content.Parts[n-1].FunctionCall == nil && target.Content.Parts[n-1].Text != "" {
+			target.Content.Parts[n-1].Text += p.Text
+		} else {
+			target.Content.Parts = append(target.Content.Parts, &Part{Text: p.Text})
+		}
+		if cand.Index == 0 {
+			if err := streamingFunc(ctx, []byte(p.Text), nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
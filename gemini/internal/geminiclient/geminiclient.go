@@ -0,0 +1,104 @@
+package geminiclient
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultBaseURL        = "https://generativelanguage.googleapis.com/v1beta"
+	defaultChatModel      = "gemini-1.5-flash"
+	defaultEmbeddingModel = "text-embedding-004"
+)
+
+// gemini 客户端
+
+var (
+	ErrEmptyResponse = errors.New("empty response")
+	ErrMissingToken  = errors.New("api key 不能为空")
+)
+
+// Client Gemini API 客户端
+type Client struct {
+	token           string
+	Model           string
+	EmbeddingsModel string
+	baseURL         string
+}
+
+// Option is an option for the Gemini client.
+type Option func(*Client) error
+
+// New 创建 Gemini 客户端
+func New(opts ...Option) (*Client, error) {
+	c := &Client{}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.token == "" {
+		return nil, ErrMissingToken
+	}
+	if c.baseURL == "" {
+		c.baseURL = defaultBaseURL
+	}
+	c.baseURL = strings.TrimSuffix(c.baseURL, "/")
+	if c.Model == "" {
+		c.Model = defaultChatModel
+	}
+	if c.EmbeddingsModel == "" {
+		c.EmbeddingsModel = defaultEmbeddingModel
+	}
+	return c, nil
+}
+
+func WithToken(value string) Option {
+	return func(c *Client) error {
+		c.token = value
+		return nil
+	}
+}
+
+func WithModel(value string) Option {
+	return func(c *Client) error {
+		c.Model = value
+		return nil
+	}
+}
+
+func WithEmbeddingsModel(value string) Option {
+	return func(c *Client) error {
+		c.EmbeddingsModel = value
+		return nil
+	}
+}
+
+func WithBaseURL(value string) Option {
+	return func(c *Client) error {
+		c.baseURL = value
+		return nil
+	}
+}
+
+// buildURL 拼接接口地址，例如 {baseURL}/models/gemini-1.5-flash:generateContent
+func (c *Client) buildURL(model, method string) string {
+	return fmt.Sprintf("%s/%s:%s", c.baseURL, modelName(model), method)
+}
+
+// modelName 接口中的模型名需要带 models/ 前缀
+func modelName(model string) string {
+	if strings.HasPrefix(model, "models/") || strings.HasPrefix(model, "tunedModels/") {
+		return model
+	}
+	return "models/" + model
+}
+
+// 设置权限
+func (c *Client) setHeaders() map[string]string {
+	return map[string]string{
+		"Content-Type":   "application/json",
+		"x-goog-api-key": c.token,
+	}
+}
@@ -0,0 +1,271 @@
+package hunyuan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/hunyuan/internal/hunyuanclient"
+	"github.com/comqositi/kpllms/schema"
+)
+
+var (
+	ErrEmptyResponse            = errors.New("no response")
+	ErrMissingToken             = errors.New("缺少腾讯云 SecretId 或 SecretKey，请设置 TENCENTCLOUD_SECRET_ID、TENCENTCLOUD_SECRET_KEY 环境变量或使用 WithSecret") //nolint:lll
+	ErrUnexpectedResponseLength = errors.New("unexpected length of response")
+)
+
+// 混元没有 json mode，开启 JsonMode 时追加 system 消息
+const jsonModePrompt = "只返回合法的 JSON，不要包含任何其他内容。"
+
+// Error 腾讯云接口返回的 Response.Error，可通过 errors.As 获取错误码和 RequestId
+type Error = hunyuanclient.Error
+
+type LLM struct {
+	client            *hunyuanclient.Client
+	enableEnhancement *bool
+}
+
+var (
+	_ kpllms.Model    = (*LLM)(nil)
+	_ kpllms.Embedder = (*LLM)(nil)
+)
+
+// New 创建腾讯混元大模型 model 和 embedder 的实现
+func New(opts ...Option) (*LLM, error) {
+	options := &options{
+		secretID:  os.Getenv(secretIDEnvVarName),
+		secretKey: os.Getenv(secretKeyEnvVarName),
+		region:    os.Getenv(regionEnvVarName),
+		model:     os.Getenv(modelEnvVarName),
+		baseURL:   os.Getenv(baseURLEnvVarName),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.secretID == "" || options.secretKey == "" {
+		return nil, ErrMissingToken
+	}
+	c, err := hunyuanclient.New(
+		hunyuanclient.WithSecret(options.secretID, options.secretKey),
+		hunyuanclient.WithRegion(options.region),
+		hunyuanclient.WithModel(options.model),
+		hunyuanclient.WithBaseURL(options.baseURL),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &LLM{client: c, enableEnhancement: options.enableEnhancement}, nil
+}
+
+// samplingRanges 混元支持的采样参数范围
+var samplingRanges = kpllms.SamplingRanges{
+	Temperature: kpllms.Range{Min: 0, Max: 2},
+	TopP:        kpllms.Range{Min: 0, Max: 1},
+}
+
+// Chat 实现大模型接口
+func (o *LLM) Chat(ctx context.Context, messages []*schema.ChatMessage, options ...kpllms.CallOption) (*schema.ContentResponse, error) {
+	opts := kpllms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if err := samplingRanges.Validate(&opts); err != nil {
+		return nil, err
+	}
+	if err := opts.CheckSupported("hunyuan", kpllms.OptionStop, kpllms.OptionSeed); err != nil {
+		return nil, err
+	}
+
+	msgs, err := messagesToClientMessages(messages)
+	if err != nil {
+		return nil, err
+	}
+	if opts.JsonMode {
+		msgs = append([]*hunyuanclient.ChatMessage{{Role: hunyuanclient.RoleSystem, Content: jsonModePrompt}}, msgs...)
+	}
+	req := &hunyuanclient.ChatRequest{
+		Model:             opts.Model,
+		Messages:          msgs,
+		Temperature:       opts.Temperature,
+		TopP:              opts.TopP,
+		Seed:              opts.Seed,
+		Stop:              opts.Stop,
+		EnableEnhancement: o.enableEnhancement,
+		StreamingFunc:     opts.StreamingFunc,
+	}
+
+	for _, t := range opts.Tools {
+		tool, err := toolFromTool(t)
+		if err != nil {
+			return nil, err
+		}
+		req.Tools = append(req.Tools, tool)
+	}
+	if len(req.Tools) > 0 {
+		switch opts.ToolChoice.Type {
+		case schema.ToolChoiceTypeNone:
+			req.ToolChoice = "none"
+		case schema.ToolChoiceTypeFunction:
+			req.ToolChoice = "custom"
+			for _, t := range req.Tools {
+				if t.Function.Name == opts.ToolChoice.Function.Name {
+					req.CustomTool = t
+				}
+			}
+		}
+	}
+
+	result, err := o.client.CreateChat(ctx, req)
+	if err != nil {
+		if errors.Is(err, hunyuanclient.ErrEmptyResponse) {
+			return nil, ErrEmptyResponse
+		}
+		return nil, err
+	}
+
+	usage := &schema.Usage{
+		PromptTokens:     result.Usage.PromptTokens,
+		CompletionTokens: result.Usage.CompletionTokens,
+		TotalTokens:      result.Usage.TotalTokens,
+	}
+	choices := make([]*schema.ContentChoice, 0, len(result.Choices))
+	for _, c := range result.Choices {
+		choice := &schema.ContentChoice{
+			Content:    c.Message.Content,
+			StopReason: c.FinishReason,
+			Usage:      usage,
+			GenerationInfo: map[string]any{
+				"id":         result.ID,
+				"request_id": result.RequestID,
+				"note":       result.Note,
+			},
+		}
+		for _, tc := range c.Message.ToolCalls {
+			choice.ToolCalls = append(choice.ToolCalls, &schema.ToolCall{
+				Id:   tc.ID,
+				Type: schema.ToolCallTypeFunction,
+				Function: schema.FunctionCall{
+					Name:      tc.Function.Name,
+					Arguments: tc.Function.Arguments,
+				},
+			})
+		}
+		choices = append(choices, choice)
+	}
+	return &schema.ContentResponse{Choices: choices}, nil
+}
+
+// toolFromTool 混元的函数参数需要序列化为 json 字符串
+func toolFromTool(t *kpllms.Tool) (*hunyuanclient.Tool, error) {
+	if t.Type != schema.ToolCallTypeFunction || t.Function == nil {
+		return nil, fmt.Errorf("failed to convert llms tool to hunyuan tool: tool type %v not supported", t.Type)
+	}
+	params, err := json.Marshal(t.Function.Parameters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal parameters of function %s: %w", t.Function.Name, err)
+	}
+	return &hunyuanclient.Tool{
+		Type: hunyuanclient.ToolTypeFunction,
+		Function: hunyuanclient.FunctionDefinition{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			Parameters:  string(params),
+		},
+	}, nil
+}
+
+func messagesToClientMessages(messages []*schema.ChatMessage) ([]*hunyuanclient.ChatMessage, error) {
+	msgs := make([]*hunyuanclient.ChatMessage, 0, len(messages))
+	for _, mc := range messages {
+		msg := &hunyuanclient.ChatMessage{}
+		switch mc.Role {
+		case schema.RoleSystem:
+			msg.Role = hunyuanclient.RoleSystem
+		case schema.RoleUser:
+			msg.Role = hunyuanclient.RoleUser
+		case schema.RoleAssistant:
+			msg.Role = hunyuanclient.RoleAssistant
+			for _, t := range mc.ToolCalls {
+				msg.ToolCalls = append(msg.ToolCalls, &hunyuanclient.ToolCall{
+					ID:   t.Id,
+					Type: hunyuanclient.ToolTypeFunction,
+					Function: hunyuanclient.FunctionCall{
+						Name:      t.Function.Name,
+						Arguments: t.Function.Arguments,
+					},
+				})
+			}
+		case schema.RoleTool:
+			msg.Role = hunyuanclient.RoleTool
+			msg.ToolCallID = mc.ToolCallId
+		default:
+			return nil, fmt.Errorf("role %v not supported", mc.Role)
+		}
+		if err := setContent(msg, mc.Content); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// setContent 文本放在 Content 中，包含图片时使用 Contents
+func setContent(msg *hunyuanclient.ChatMessage, content any) error {
+	switch c := content.(type) {
+	case nil:
+		return nil
+	case string:
+		msg.Content = c
+		return nil
+	case []any:
+		for _, p := range c {
+			switch part := p.(type) {
+			case schema.TextContent:
+				msg.Contents = append(msg.Contents, &hunyuanclient.Content{Type: "text", Text: part.Text})
+			case *schema.TextContent:
+				msg.Contents = append(msg.Contents, &hunyuanclient.Content{Type: "text", Text: part.Text})
+			case schema.ImageContent:
+				msg.Contents = append(msg.Contents, imageContent(part.ImageUrl.Url))
+			case *schema.ImageContent:
+				msg.Contents = append(msg.Contents, imageContent(part.ImageUrl.Url))
+			default:
+				return fmt.Errorf("content part type %T not supported", p)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("content type %T not supported", content)
+	}
+}
+
+func imageContent(url string) *hunyuanclient.Content {
+	return &hunyuanclient.Content{Type: "image_url", ImageURL: &hunyuanclient.ImageURL{URL: url}}
+}
+
+// EmbedDocuments  实现 embedder 接口 文档存储向量：存入数据库的向量，被用于检索
+func (o *LLM) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings, err := o.client.CreateEmbedding(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	if len(texts) != len(embeddings) {
+		return embeddings, ErrUnexpectedResponseLength
+	}
+	return embeddings, nil
+}
+
+// EmbedQuery  实现 embedder 接口 查询向量：对需要用于检索的文本进行想量化
+func (o *LLM) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := o.EmbedDocuments(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
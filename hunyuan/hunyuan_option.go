@@ -0,0 +1,57 @@
+package hunyuan
+
+const (
+	secretIDEnvVarName  = "TENCENTCLOUD_SECRET_ID"  //nolint:gosec
+	secretKeyEnvVarName = "TENCENTCLOUD_SECRET_KEY" //nolint:gosec
+	regionEnvVarName    = "TENCENTCLOUD_REGION"     //nolint:gosec
+	modelEnvVarName     = "HUNYUAN_MODEL"           //nolint:gosec
+	baseURLEnvVarName   = "HUNYUAN_BASE_URL"        //nolint:gosec
+)
+
+type options struct {
+	secretID          string
+	secretKey         string
+	region            string
+	model             string
+	baseURL           string
+	enableEnhancement *bool
+}
+
+// Option is a functional option for the Hunyuan client.
+type Option func(*options)
+
+// WithSecret 设置腾讯云 API 密钥，未设置时读取 TENCENTCLOUD_SECRET_ID 和 TENCENTCLOUD_SECRET_KEY 环境变量
+func WithSecret(secretID, secretKey string) Option {
+	return func(o *options) {
+		o.secretID = secretID
+		o.secretKey = secretKey
+	}
+}
+
+// WithRegion 设置地域，未设置时读取 TENCENTCLOUD_REGION 环境变量，混元可以不传
+func WithRegion(region string) Option {
+	return func(o *options) {
+		o.region = region
+	}
+}
+
+// WithModel 设置模型，未设置时读取 HUNYUAN_MODEL 环境变量，默认 hunyuan-pro
+func WithModel(model string) Option {
+	return func(o *options) {
+		o.model = model
+	}
+}
+
+// WithBaseURL 设置接口地址，未设置时读取 HUNYUAN_BASE_URL 环境变量，默认 https://hunyuan.tencentcloudapi.com
+func WithBaseURL(baseURL string) Option {
+	return func(o *options) {
+		o.baseURL = baseURL
+	}
+}
+
+// WithEnableEnhancement 是否开启搜索增强等功能增强，接口默认开启
+func WithEnableEnhancement(enable bool) Option {
+	return func(o *options) {
+		o.enableEnhancement = &enable
+	}
+}
@@ -0,0 +1,134 @@
+package hunyuan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+)
+
+// newTestServer 按 X-TC-Action 分发请求，校验签名头后由 handler 返回
+func newTestServer(t *testing.T, handler func(action string, body map[string]any, w http.ResponseWriter)) *LLM {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "TC3-HMAC-SHA256 Credential=id/") || r.Header.Get("X-TC-Version") != "2023-09-01" ||
+			r.Header.Get("X-TC-Region") != "ap-guangzhou" {
+			t.Errorf("unexpected headers: %v", r.Header)
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		handler(r.Header.Get("X-TC-Action"), body, w)
+	}))
+	t.Cleanup(srv.Close)
+	llm, err := New(WithSecret("id", "key"), WithRegion("ap-guangzhou"), WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return llm
+}
+
+func TestChat(t *testing.T) {
+	var got map[string]any
+	llm := newTestServer(t, func(action string, body map[string]any, w http.ResponseWriter) {
+		got = body
+		_, _ = io.WriteString(w, `{"Response":{"Id":"h1","Choices":[{"FinishReason":"tool_calls","Message":{"Role":"assistant","Content":"",
+			"ToolCalls":[{"Id":"call_1","Type":"function","Function":{"Name":"get_weather","Arguments":"{\"city\":\"深圳\"}"}}]}}],
+			"Usage":{"PromptTokens":20,"CompletionTokens":8,"TotalTokens":28},"RequestId":"r1"}}`)
+	})
+	tools := []*kpllms.Tool{{Type: schema.ToolCallTypeFunction, Function: &kpllms.FunctionDefinition{
+		Name: "get_weather", Parameters: map[string]any{"type": "object"},
+	}}}
+	resp, err := llm.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "深圳天气"}},
+		kpllms.WithTools(tools), kpllms.WithToolChoice(kpllms.ToolChoice{Type: schema.ToolChoiceTypeFunction,
+			Function: kpllms.ToolChoiceFunction{Name: "get_weather"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := got["Tools"].([]any)[0].(map[string]any)["Function"].(map[string]any)
+	if got["Model"] != "hunyuan-pro" || fn["Parameters"] != `{"type":"object"}` || got["ToolChoice"] != "custom" || got["CustomTool"] == nil {
+		t.Fatalf("request = %v", got)
+	}
+	c := resp.Choices[0]
+	if c.StopReason != "tool_calls" || c.Usage.TotalTokens != 28 || c.GenerationInfo["request_id"] != "r1" ||
+		len(c.ToolCalls) != 1 || c.ToolCalls[0].Function.Arguments != `{"city":"深圳"}` {
+		t.Fatalf("choice = %+v", c)
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	chunks := []string{
+		`{"Id":"h2","Choices":[{"Delta":{"Role":"assistant","Content":"你好"}}],"Usage":{"PromptTokens":3,"CompletionTokens":1,"TotalTokens":4}}`,
+		`{"Id":"h2","Choices":[{"FinishReason":"stop","Delta":{"Role":"assistant","Content":"呀"}}],"Usage":{"PromptTokens":3,"CompletionTokens":2,"TotalTokens":5}}`,
+	}
+	llm := newTestServer(t, func(action string, body map[string]any, w http.ResponseWriter) {
+		if body["Stream"] != true {
+			t.Errorf("Stream = %v", body["Stream"])
+		}
+		for _, c := range chunks {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+	})
+	var streamed strings.Builder
+	resp, err := llm.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}},
+		kpllms.WithStreamingFunc(func(ctx context.Context, chunk []byte, innerErr error) error {
+			streamed.Write(chunk)
+			return nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := resp.Choices[0]
+	if streamed.String() != "你好呀" || c.Content != "你好呀" || c.StopReason != "stop" || c.Usage.TotalTokens != 5 {
+		t.Fatalf("choice = %+v", c)
+	}
+}
+
+func TestChatError(t *testing.T) {
+	errBody := `{"Response":{"Error":{"Code":"AuthFailure.SignatureFailure","Message":"签名错误"},"RequestId":"r2"}}`
+	llm := newTestServer(t, func(action string, body map[string]any, w http.ResponseWriter) {
+		_, _ = io.WriteString(w, errBody)
+	})
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "x"}}
+	_, err := llm.Chat(context.Background(), msgs)
+	var e *Error
+	if !errors.As(err, &e) || e.Code != "AuthFailure.SignatureFailure" || e.RequestID != "r2" {
+		t.Fatalf("err = %v", err)
+	}
+	// 流式请求出错时返回的也是普通 json
+	_, err = llm.Chat(context.Background(), msgs, kpllms.WithStreamingFunc(func(ctx context.Context, chunk []byte, innerErr error) error {
+		return nil
+	}))
+	if !errors.As(err, &e) || e.Code != "AuthFailure.SignatureFailure" {
+		t.Fatalf("stream err = %v", err)
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	llm := newTestServer(t, func(action string, body map[string]any, w http.ResponseWriter) {
+		if action != "GetEmbedding" {
+			t.Errorf("action = %s", action)
+		}
+		input := body["InputList"].([]any)
+		data := make([]map[string]any, 0, len(input))
+		for i := len(input) - 1; i >= 0; i-- {
+			data = append(data, map[string]any{"Index": i, "Embedding": []float32{float32(i)}})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"Response": map[string]any{"Data": data}})
+	})
+	docs, err := llm.EmbedDocuments(context.Background(), []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 3 || docs[2][0] != 2 {
+		t.Fatalf("docs = %v", docs)
+	}
+}
@@ -0,0 +1,198 @@
+package hunyuanclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/comqositi/kpllms/internal/httputils"
+)
+
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+
+	ToolTypeFunction = "function"
+)
+
+// ChatRequest ChatCompletions 接口请求，字段名为腾讯云风格的大驼峰
+type ChatRequest struct {
+	Model             string         `json:"Model"`
+	Messages          []*ChatMessage `json:"Messages"`
+	Stream            bool           `json:"Stream"`
+	Temperature       *float64       `json:"Temperature,omitempty"`
+	TopP              *float64       `json:"TopP,omitempty"`
+	Seed              *int           `json:"Seed,omitempty"`
+	Stop              []string       `json:"Stop,omitempty"`
+	Tools             []*Tool        `json:"Tools,omitempty"`
+	ToolChoice        string         `json:"ToolChoice,omitempty"`
+	CustomTool        *Tool          `json:"CustomTool,omitempty"`
+	EnableEnhancement *bool          `json:"EnableEnhancement,omitempty"`
+
+	// 流式放回的回调函数
+	StreamingFunc func(ctx context.Context, chunk []byte, innerErr error) error `json:"-"`
+}
+
+type ChatMessage struct {
+	Role       string      `json:"Role"`
+	Content    string      `json:"Content,omitempty"`
+	Contents   []*Content  `json:"Contents,omitempty"`
+	ToolCallID string      `json:"ToolCallId,omitempty"`
+	ToolCalls  []*ToolCall `json:"ToolCalls,omitempty"`
+}
+
+// Content 多模态内容，Type 为 text 或 image_url
+type Content struct {
+	Type     string    `json:"Type"`
+	Text     string    `json:"Text,omitempty"`
+	ImageURL *ImageURL `json:"ImageUrl,omitempty"`
+}
+
+type ImageURL struct {
+	URL string `json:"Url"`
+}
+
+type Tool struct {
+	Type     string             `json:"Type"`
+	Function FunctionDefinition `json:"Function"`
+}
+
+// FunctionDefinition 混元的 Parameters 为 json 字符串
+type FunctionDefinition struct {
+	Name        string `json:"Name"`
+	Description string `json:"Description,omitempty"`
+	Parameters  string `json:"Parameters"`
+}
+
+type ToolCall struct {
+	ID       string       `json:"Id"`
+	Type     string       `json:"Type"`
+	Index    int          `json:"Index,omitempty"`
+	Function FunctionCall `json:"Function"`
+}
+
+type FunctionCall struct {
+	Name      string `json:"Name"`
+	Arguments string `json:"Arguments"`
+}
+
+type Choice struct {
+	FinishReason string      `json:"FinishReason"`
+	Message      ChatMessage `json:"Message"`
+	Delta        ChatMessage `json:"Delta"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"PromptTokens"`
+	CompletionTokens int `json:"CompletionTokens"`
+	TotalTokens      int `json:"TotalTokens"`
+}
+
+// ChatResponse ChatCompletions 接口返回，流式返回时每个 chunk 也是该结构，内容在 Delta 中
+type ChatResponse struct {
+	ID        string    `json:"Id"`
+	Created   int64     `json:"Created"`
+	Note      string    `json:"Note"`
+	Choices   []*Choice `json:"Choices"`
+	Usage     Usage     `json:"Usage"`
+	RequestID string    `json:"RequestId"`
+}
+
+// CreateChat 调用 ChatCompletions 接口，流式返回时拼接所有 chunk
+func (c *Client) CreateChat(ctx context.Context, r *ChatRequest) (*ChatResponse, error) {
+	if r.Model == "" {
+		r.Model = c.Model
+	}
+	r.Stream = r.StreamingFunc != nil
+
+	var response ChatResponse
+	if !r.Stream {
+		if err := c.call(ctx, "ChatCompletions", r, &response); err != nil {
+			return nil, err
+		}
+	} else if err := c.stream(ctx, r, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Choices) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	return &response, nil
+}
+
+func (c *Client) stream(ctx context.Context, r *ChatRequest, response *ChatResponse) error {
+	body, headers, err := c.sign("ChatCompletions", r)
+	if err != nil {
+		return err
+	}
+	choice := &Choice{Message: ChatMessage{Role: RoleAssistant}}
+	response.Choices = []*Choice{choice}
+
+	// 回调中的错误会被 HttpStream 包装，这里记录接口返回的错误以保留类型
+	var apiErr error
+	err = httputils.HttpStream(ctx, c.baseURL, body, headers, func(ctx context.Context, line string) error {
+		// 请求出错时不是 sse 格式，直接返回 {"Response":{"Error":...}}
+		if strings.HasPrefix(line, "{") {
+			var envelope struct {
+				Response json.RawMessage `json:"Response"`
+			}
+			if err := json.Unmarshal([]byte(line), &envelope); err != nil {
+				return fmt.Errorf("unexpected line: %v", line)
+			}
+			apiErr = parseResponse(envelope.Response, response)
+			return apiErr
+		}
+		if !strings.HasPrefix(line, "data:") {
+			return nil
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			return nil
+		}
+		var chunk ChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("unexpected line: %v", line)
+		}
+		response.ID, response.Created, response.Note = chunk.ID, chunk.Created, chunk.Note
+		response.Usage = chunk.Usage
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		delta := chunk.Choices[0]
+		if delta.FinishReason != "" {
+			choice.FinishReason = delta.FinishReason
+		}
+		for _, tc := range delta.Delta.ToolCalls {
+			mergeToolCall(&choice.Message, tc)
+		}
+		if delta.Delta.Content == "" {
+			return nil
+		}
+		choice.Message.Content += delta.Delta.Content
+		return r.StreamingFunc(ctx, []byte(delta.Delta.Content), nil)
+	})
+	if apiErr != nil {
+		return apiErr
+	}
+	return err
+}
+
+// mergeToolCall 按 Index 拼接流式返回的工具调用
+func mergeToolCall(msg *ChatMessage, delta *ToolCall) {
+	for _, tc := range msg.ToolCalls {
+		if tc.Index != delta.Index {
+			continue
+		}
+		if delta.ID != "" {
+			tc.ID = delta.ID
+		}
+		if delta.Function.Name != "" {
+			tc.Function.Name = delta.Function.Name
+		}
+		tc.Function.Arguments += delta.Function.Arguments
+		return
+	}
+	msg.ToolCalls = append(msg.ToolCalls, delta)
+}
@@ -0,0 +1,38 @@
+package hunyuanclient
+
+import (
+	"context"
+	"sort"
+)
+
+// GetEmbedding 单次最多 200 条
+const maxEmbeddingBatchSize = 200
+
+type embeddingRequest struct {
+	InputList []string `json:"InputList"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"Index"`
+		Embedding []float32 `json:"Embedding"`
+	} `json:"Data"`
+	Usage Usage `json:"Usage"`
+}
+
+// CreateEmbedding 调用 GetEmbedding 接口，超过单次上限时分批请求
+func (c *Client) CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbeddingBatchSize {
+		end := min(start+maxEmbeddingBatchSize, len(texts))
+		var resp embeddingResponse
+		if err := c.call(ctx, "GetEmbedding", &embeddingRequest{InputList: texts[start:end]}, &resp); err != nil {
+			return nil, err
+		}
+		sort.Slice(resp.Data, func(i, j int) bool { return resp.Data[i].Index < resp.Data[j].Index })
+		for _, d := range resp.Data {
+			embeddings = append(embeddings, d.Embedding)
+		}
+	}
+	return embeddings, nil
+}
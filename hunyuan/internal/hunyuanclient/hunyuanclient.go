@@ -0,0 +1,149 @@
+package hunyuanclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/comqositi/kpllms/internal/httputils"
+	"github.com/comqositi/kpllms/internal/tc3"
+)
+
+// 腾讯混元客户端，文档：https://cloud.tencent.com/document/api/1729/101837
+
+const (
+	defaultBaseURL = "https://hunyuan.tencentcloudapi.com"
+	defaultModel   = "hunyuan-pro"
+
+	service = "hunyuan"
+	version = "2023-09-01"
+)
+
+var (
+	ErrEmptyResponse = errors.New("empty response")
+	ErrMissingAuth   = errors.New("缺少 SecretId 或 SecretKey")
+)
+
+// Error 腾讯云接口返回的错误，即 Response.Error
+type Error struct {
+	Code      string `json:"Code"`
+	Message   string `json:"Message"`
+	RequestID string `json:"-"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("hunyuan error code: %s, errMsg: %s, requestId: %s", e.Code, e.Message, e.RequestID)
+}
+
+// Client 腾讯混元客户端
+type Client struct {
+	signer  *tc3.Signer
+	Model   string
+	baseURL string
+	host    string
+}
+
+// Option is an option for the Hunyuan client.
+type Option func(*Client) error
+
+// New 创建混元客户端
+func New(opts ...Option) (*Client, error) {
+	c := &Client{signer: &tc3.Signer{Service: service, Version: version}}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.signer.SecretID == "" || c.signer.SecretKey == "" {
+		return nil, ErrMissingAuth
+	}
+	if c.baseURL == "" {
+		c.baseURL = defaultBaseURL
+	}
+	c.baseURL = strings.TrimSuffix(c.baseURL, "/")
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	c.host = u.Host
+	if c.Model == "" {
+		c.Model = defaultModel
+	}
+	return c, nil
+}
+
+// WithSecret 设置腾讯云 API 密钥
+func WithSecret(secretID, secretKey string) Option {
+	return func(c *Client) error {
+		c.signer.SecretID, c.signer.SecretKey = secretID, secretKey
+		return nil
+	}
+}
+
+func WithRegion(value string) Option {
+	return func(c *Client) error {
+		c.signer.Region = value
+		return nil
+	}
+}
+
+func WithModel(value string) Option {
+	return func(c *Client) error {
+		c.Model = value
+		return nil
+	}
+}
+
+func WithBaseURL(value string) Option {
+	return func(c *Client) error {
+		c.baseURL = value
+		return nil
+	}
+}
+
+// sign 序列化请求体并签名，返回的请求体需要原样发送
+func (c *Client) sign(action string, payload any) (json.RawMessage, map[string]string, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, c.signer.Headers(c.host, action, body, time.Now()), nil
+}
+
+// responseHeader 腾讯云接口返回都包在 Response 中，出错时 http 状态码仍为 200，错误在 Response.Error
+type responseHeader struct {
+	Error     *Error `json:"Error"`
+	RequestID string `json:"RequestId"`
+}
+
+// parseResponse 解析 Response 的内容，有错误时返回 *Error
+func parseResponse(raw json.RawMessage, resp any) error {
+	var header responseHeader
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return err
+	}
+	if e := header.Error; e != nil {
+		e.RequestID = header.RequestID
+		return e
+	}
+	return json.Unmarshal(raw, resp)
+}
+
+// call 调用非流式接口
+func (c *Client) call(ctx context.Context, action string, payload any, resp any) error {
+	body, headers, err := c.sign(action, payload)
+	if err != nil {
+		return err
+	}
+	var envelope struct {
+		Response json.RawMessage `json:"Response"`
+	}
+	if err = httputils.HttpPost(ctx, c.baseURL, body, headers, &envelope); err != nil {
+		return err
+	}
+	return parseResponse(envelope.Response, resp)
+}
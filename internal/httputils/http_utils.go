@@ -13,6 +13,20 @@ import (
 	netUrl "net/url"
 )
 
+type responseHeaderKey struct{}
+
+// WithResponseHeader 请求完成后把响应头写入 header，用于读取 x-request-id、限流等信息
+func WithResponseHeader(ctx context.Context, header *http.Header) context.Context {
+	return context.WithValue(ctx, responseHeaderKey{}, header)
+}
+
+// saveResponseHeader 请求失败时也保存响应头，方便排查问题
+func saveResponseHeader(ctx context.Context, r *http.Response) {
+	if h, ok := ctx.Value(responseHeaderKey{}).(*http.Header); ok && h != nil {
+		*h = r.Header.Clone()
+	}
+}
+
 // HttpPost 发送 http post 请求
 // resp 为返回值
 func HttpPost(ctx context.Context, baseUrl string, payload any, headers map[string]string, resp any) error {
@@ -29,13 +43,13 @@ func HttpPost(ctx context.Context, baseUrl string, payload any, headers map[stri
 		return schema.NewHttpError(0, err.Error())
 	}
 	for k, v := range headers {
-		fmt.Println(k, ":", v)
 		req.Header.Set(k, v)
 	}
 	r, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return schema.NewHttpError(0, err.Error())
 	}
+	saveResponseHeader(ctx, r)
 	defer r.Body.Close()
 	b, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -76,6 +90,7 @@ func HttpGet(ctx context.Context, baseUrl string, query map[string]string, heade
 	if err != nil {
 		return schema.NewHttpError(0, err.Error())
 	}
+	saveResponseHeader(ctx, r)
 	defer r.Body.Close()
 	b, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -115,6 +130,7 @@ func HttpStream(ctx context.Context, baseUrl string, payload any, headers map[st
 	if err != nil {
 		return schema.NewHttpError(0, err.Error())
 	}
+	saveResponseHeader(ctx, r)
 	defer func(Body io.ReadCloser) {
 		err1 := Body.Close()
 		if err1 != nil {
@@ -152,3 +168,28 @@ func parseStreaming(ctx context.Context, r *http.Response, sfunc streamFunc) err
 	return nil
 
 }
+
+// MergeBody 将 extra 合并到 payload 序列化后的 json 对象中，extra 中的字段覆盖原有字段，值为 nil 时删除该字段
+func MergeBody(payload any, extra map[string]any) (json.RawMessage, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(extra) == 0 {
+		return b, nil
+	}
+	body := make(map[string]json.RawMessage)
+	if err = json.Unmarshal(b, &body); err != nil {
+		return nil, err
+	}
+	for k, v := range extra {
+		if v == nil {
+			delete(body, k)
+			continue
+		}
+		if body[k], err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(body)
+}
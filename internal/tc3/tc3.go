@@ -0,0 +1,82 @@
+// Package tc3 实现腾讯云 API 3.0 的 TC3-HMAC-SHA256 签名，文档：https://cloud.tencent.com/document/api/213/30654
+package tc3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	algorithm     = "TC3-HMAC-SHA256"
+	contentType   = "application/json"
+	signedHeaders = "content-type;host;x-tc-action"
+)
+
+// Signer 腾讯云接口签名，同一个 Signer 可用于多个接口
+type Signer struct {
+	SecretID  string
+	SecretKey string
+	// 产品名，例如 hunyuan
+	Service string
+	// 接口版本，例如 2023-09-01
+	Version string
+	// 地域，部分接口可以为空
+	Region string
+}
+
+// Headers 对 POST json 请求签名，返回需要设置的请求头，payload 必须和实际发送的请求体一致
+func (s *Signer) Headers(host, action string, payload []byte, now time.Time) map[string]string {
+	timestamp := now.Unix()
+	date := now.UTC().Format("2006-01-02")
+
+	canonicalRequest := strings.Join([]string{
+		"POST",
+		"/",
+		"",
+		fmt.Sprintf("content-type:%s\nhost:%s\nx-tc-action:%s\n", contentType, host, strings.ToLower(action)),
+		signedHeaders,
+		sha256hex(payload),
+	}, "\n")
+	credentialScope := date + "/" + s.Service + "/tc3_request"
+	stringToSign := strings.Join([]string{
+		algorithm,
+		strconv.FormatInt(timestamp, 10),
+		credentialScope,
+		sha256hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	secretDate := hmacSHA256([]byte("TC3"+s.SecretKey), date)
+	secretService := hmacSHA256(secretDate, s.Service)
+	secretSigning := hmacSHA256(secretService, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(secretSigning, stringToSign))
+
+	headers := map[string]string{
+		"Authorization": fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+			algorithm, s.SecretID, credentialScope, signedHeaders, signature),
+		"Content-Type":   contentType,
+		"Host":           host,
+		"X-TC-Action":    action,
+		"X-TC-Timestamp": strconv.FormatInt(timestamp, 10),
+		"X-TC-Version":   s.Version,
+	}
+	if s.Region != "" {
+		headers["X-TC-Region"] = s.Region
+	}
+	return headers
+}
+
+func sha256hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, msg string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
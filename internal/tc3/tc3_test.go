@@ -0,0 +1,24 @@
+package tc3
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeaders(t *testing.T) {
+	s := &Signer{SecretID: "AKIDEXAMPLE", SecretKey: "SECRETEXAMPLE", Service: "hunyuan", Version: "2023-09-01"}
+	headers := s.Headers("hunyuan.tencentcloudapi.com", "ChatCompletions", []byte(`{"Model":"hunyuan-lite"}`), time.Unix(1700000000, 0))
+
+	want := "TC3-HMAC-SHA256 Credential=AKIDEXAMPLE/2023-11-14/hunyuan/tc3_request, " +
+		"SignedHeaders=content-type;host;x-tc-action, " +
+		"Signature=5c45fe911e8660769b57c2c9da350ba8d34896f4694c260930bf83d372899931"
+	if headers["Authorization"] != want {
+		t.Fatalf("Authorization = %s", headers["Authorization"])
+	}
+	if headers["X-TC-Timestamp"] != "1700000000" || headers["X-TC-Action"] != "ChatCompletions" || headers["X-TC-Version"] != "2023-09-01" {
+		t.Fatalf("headers = %v", headers)
+	}
+	if _, ok := headers["X-TC-Region"]; ok {
+		t.Fatal("X-TC-Region should be omitted when region is empty")
+	}
+}
@@ -0,0 +1,73 @@
+package tokenutils
+
+import (
+	"log"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// 未识别的模型使用的编码
+const fallbackEncoding = "gpt2"
+
+// tiktoken 每次获取编码都会重新加载词表，此处按模型名缓存
+var encodings sync.Map //nolint:gochecknoglobals
+
+// EncodingForModel 获取模型对应的 tiktoken 编码，模型未识别时退回 gpt2 编码
+func EncodingForModel(model string) (*tiktoken.Tiktoken, error) {
+	if e, ok := encodings.Load(model); ok {
+		return e.(*tiktoken.Tiktoken), nil
+	}
+	e, err := tiktoken.EncodingForModel(model)
+	if err != nil {
+		e, err = GetEncoding(fallbackEncoding)
+		if err != nil {
+			return nil, err
+		}
+	}
+	encodings.Store(model, e)
+	return e, nil
+}
+
+// GetEncoding 根据编码名称获取 tiktoken 编码，例如：cl100k_base
+func GetEncoding(name string) (*tiktoken.Tiktoken, error) {
+	key := "encoding:" + name
+	if e, ok := encodings.Load(key); ok {
+		return e.(*tiktoken.Tiktoken), nil
+	}
+	e, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		return nil, err
+	}
+	encodings.Store(key, e)
+	return e, nil
+}
+
+// CountTokens 计算文本的 token 数量，获取编码失败时返回 0
+func CountTokens(model, text string) int {
+	e, err := EncodingForModel(model)
+	if err != nil {
+		log.Printf("[WARN] Failed to calculate number of tokens for model, falling back to approximate count")
+		return 0
+	}
+	return len(e.Encode(text, nil, nil))
+}
+
+// 非中日韩字符平均每个 token 的字节数
+const tokenApproximation = 4
+
+// ApproximateTokens 无法加载 tiktoken 词表时估算 token 数：中日韩文字按每字一个 token，其余按 4 字节一个 token
+func ApproximateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+			unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) {
+			cjk++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+	}
+	return cjk + (other+tokenApproximation-1)/tokenApproximation
+}
@@ -0,0 +1,129 @@
+package minimax
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/minimax/internal/minimaxclientv1"
+)
+
+// 限制返回格式功能（glyph），文档：https://platform.minimaxi.com/document/ChatCompletion%20Pro
+
+// jsonModePrompt JsonMode 没有指定 schema 时追加到 bot_setting 的提示
+const jsonModePrompt = "\n请使用 json 格式返回。"
+
+// defaultJsonSchemaName v2 接口的 json_schema 必须有名称，没有设置时使用的默认名称
+const defaultJsonSchemaName = "response"
+
+// glyphFromOptions raw 模板优先，其次 JsonMode + JsonSchema 转为 json_value
+func glyphFromOptions(opts kpllms.CallOptions) (*minimaxclientv1.Glyph, error) {
+	if rawGlyph := getCallOptions(opts).rawGlyph; rawGlyph != "" {
+		return &minimaxclientv1.Glyph{Type: "raw", RawGlyph: rawGlyph}, nil
+	}
+	if !opts.JsonMode || opts.JsonSchema == nil || opts.JsonSchema.Schema == nil {
+		return nil, nil
+	}
+	properties, order, err := schemaProperties(opts.JsonSchema.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json schema: %w", err)
+	}
+	glyph := &minimaxclientv1.Glyph{Type: "json_value", JsonProperties: properties}
+	for _, name := range order {
+		glyph.PropertyList = append(glyph.PropertyList, map[string]any{"name": name})
+	}
+	return glyph, nil
+}
+
+// schemaProperties 返回 schema 的 properties 和属性顺序。
+// json 字符串按照原始顺序，其他类型先 required 再按名称排序
+func schemaProperties(s any) (map[string]any, []string, error) {
+	var raw []byte
+	keepOrder := true
+	switch v := s.(type) {
+	case json.RawMessage:
+		raw = v
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		raw, keepOrder = b, false
+	}
+
+	var def struct {
+		Properties map[string]any `json:"properties"`
+		Required   []string       `json:"required"`
+	}
+	if err := json.Unmarshal(raw, &def); err != nil {
+		return nil, nil, err
+	}
+	if len(def.Properties) == 0 {
+		return nil, nil, fmt.Errorf("schema has no properties")
+	}
+	if keepOrder {
+		order, err := propertyOrder(raw)
+		return def.Properties, order, err
+	}
+
+	order := make([]string, 0, len(def.Properties))
+	seen := make(map[string]bool, len(def.Properties))
+	for _, name := range def.Required {
+		if _, ok := def.Properties[name]; ok && !seen[name] {
+			order = append(order, name)
+			seen[name] = true
+		}
+	}
+	rest := make([]string, 0, len(def.Properties))
+	for name := range def.Properties {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return def.Properties, append(order, rest...), nil
+}
+
+// propertyOrder 按照 json 中出现的顺序返回顶层 properties 的 key
+func propertyOrder(raw []byte) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if key != "properties" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		var order []string
+		for dec.More() {
+			name, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, err
+			}
+			order = append(order, name.(string))
+		}
+		return order, nil
+	}
+	return nil, nil
+}
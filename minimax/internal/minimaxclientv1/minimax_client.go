@@ -57,49 +57,19 @@ func (c *Client) CreateCompletion(ctx context.Context, r *CompletionRequest) (*C
 	//fmt.Println(string(b))
 
 	var streamPayload Completion
+	body, err := httputils.MergeBody(r, r.ExtraBody)
+	if err != nil {
+		return nil, err
+	}
 
 	if r.Stream {
-		err := httputils.HttpStream(ctx, url, r, c.setHeader(), func(ctx context.Context, line string) error {
-			//fmt.Println(line)
-			if line == "\n" || line == "" {
-				return nil
-			}
-			var data string
-			if !strings.HasPrefix(line, "data: ") {
-				return nil
-			} else {
-				// 错误  {"error_code":6,"error_msg":"No permission to access data"}
-				data = strings.TrimPrefix(line, "data: ")
-			}
-			err := json.Unmarshal([]byte(data), &streamPayload)
-			if err != nil {
-				return err
-			}
-			if streamPayload.BaseResp.StatusCode != 0 {
-				return errors.New(fmt.Sprintf("statusCode: %d, errMsg: %s", streamPayload.BaseResp.StatusCode, streamPayload.BaseResp.StatusMsg))
-			}
-			// 用户输入内容命中敏感词
-			if streamPayload.InputSensitive {
-				return errors.New("模型返回：输入内容违规")
-			}
-			// 用户输出命中敏感词
-			if streamPayload.OutputSensitive {
-				return errors.New("模型返回：输出内容违规")
-			}
-			if streamPayload.Choices[0].FinishReason == "stop" {
-				return nil
-			}
-			if streamPayload.Choices[0].Messages[0].Text != "" {
-				return r.StreamingFunc(ctx, []byte(streamPayload.Choices[0].Messages[0].Text), nil)
-			}
-			return nil
-		})
+		final, err := c.stream(ctx, url, body, r)
 		if err != nil {
 			return nil, err
 		}
-
+		streamPayload = *final
 	} else {
-		err := httputils.HttpPost(ctx, url, r, c.setHeader(), &streamPayload)
+		err := httputils.HttpPost(ctx, url, body, c.setHeader(), &streamPayload)
 		if err != nil {
 			return nil, err
 		}
@@ -111,6 +81,62 @@ func (c *Client) CreateCompletion(ctx context.Context, r *CompletionRequest) (*C
 	return &streamPayload, nil
 }
 
+// stream 增量数据包只包含文本，最后一个数据包（finish_reason 不为空）包含完整回复、函数调用和 usage
+func (c *Client) stream(ctx context.Context, url string, body json.RawMessage, r *CompletionRequest) (*Completion, error) {
+	var final *Completion
+	var text strings.Builder
+	err := httputils.HttpStream(ctx, url, body, c.setHeader(), func(ctx context.Context, line string) error {
+		if !strings.HasPrefix(line, "data: ") {
+			return nil
+		}
+		// 错误  {"error_code":6,"error_msg":"No permission to access data"}
+		data := strings.TrimPrefix(line, "data: ")
+		var chunk Completion
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return err
+		}
+		if chunk.BaseResp.StatusCode != 0 {
+			return errors.New(fmt.Sprintf("statusCode: %d, errMsg: %s", chunk.BaseResp.StatusCode, chunk.BaseResp.StatusMsg))
+		}
+		// 用户输入内容命中敏感词
+		if chunk.InputSensitive {
+			return errors.New("模型返回：输入内容违规")
+		}
+		// 用户输出命中敏感词
+		if chunk.OutputSensitive {
+			return errors.New("模型返回：输出内容违规")
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		if chunk.Choices[0].FinishReason != "" {
+			final = &chunk
+			return nil
+		}
+		for _, m := range chunk.Choices[0].Messages {
+			if m.Text == "" {
+				continue
+			}
+			text.WriteString(m.Text)
+			if err := r.StreamingFunc(ctx, []byte(m.Text), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 连接提前结束没有收到最后一个数据包时，使用已拼接的文本
+	if final == nil {
+		final = &Completion{Choices: []Choice{{}}}
+	}
+	if len(final.Choices[0].Messages) == 0 {
+		final.Choices[0].Messages = []Message{{SenderType: "BOT", SenderName: r.ReplyConstraints.SenderName, Text: text.String()}}
+	}
+	return final, nil
+}
+
 // 设置权限
 func (c *Client) setHeader() map[string]string {
 	return map[string]string{
@@ -17,7 +17,7 @@ var (
 	ErrEmbeddingCode   = errors.New("embedding API returned unexpected status code")
 )
 
-// Option is an option for the ERNIE client.
+// Option is an option for the minimax client.
 type Option func(*Client) error
 
 // Doer performs a HTTP request.
@@ -71,16 +71,18 @@ type CompletionRequest struct {
 	Model  string `json:"model"`
 	Stream bool   `json:"stream,omitempty"`
 	// 最大输出token
-	TokensToGenerate int64   `json:"tokens_to_generate,omitempty"`
-	Temperature      float32 `json:"temperature,omitempty"`
-	TopP             float32 `json:"top_p,omitempty"`
+	TokensToGenerate int64    `json:"tokens_to_generate,omitempty"`
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
 	// 对输出中易涉及隐私问题的文本信息进行打码，目前包括但不限于邮箱、域名、链接、证件号、家庭住址等，默认true，即开启打码
-	MaskSensitiveInfo bool             `json:"mask_sensitive_info,omitempty"`
+	MaskSensitiveInfo *bool            `json:"mask_sensitive_info,omitempty"`
 	Messages          []*Message       `json:"messages"`          //长度影响接口性能
 	BotSetting        []BotSetting     `json:"bot_setting"`       //对每一个机器人的设定
 	ReplyConstraints  ReplyConstraints `json:"reply_constraints"` //模型回复要求
 
 	StreamingFunc func(ctx context.Context, chunk []byte, err error) error `json:"-"`
+	// 合并到请求体中的字段
+	ExtraBody map[string]any `json:"-"`
 
 	SampleMessages []*SampleMessage `json:"sample_messages,omitempty"`
 
@@ -0,0 +1,168 @@
+package minimaxclientv2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/comqositi/kpllms/internal/httputils"
+)
+
+// minimax chatcompletion_v2 接口客户端，文档：https://platform.minimaxi.com/document/ChatCompletion%20v2
+
+var ErrEmptyResponse = errors.New("empty response")
+
+type Client struct {
+	groupId    string
+	apiKey     string
+	baseUrl    string
+	model      string
+	httpClient Doer
+}
+
+func NewClient(opts ...Option) (*Client, error) {
+	c := &Client{}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.apiKey == "" {
+		return nil, errors.New("api key 不能为空")
+	}
+
+	if c.baseUrl == "" {
+		c.baseUrl = defaultBaseUrl
+	}
+	c.baseUrl = strings.TrimSuffix(c.baseUrl, "/")
+	if c.model == "" {
+		c.model = defaultModel
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	return c, nil
+}
+
+// CreateChatCompletion 调用 chatcompletion_v2 接口，流式返回时拼接所有数据包
+func (c *Client) CreateChatCompletion(ctx context.Context, r *ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	if r.Model == "" {
+		r.Model = c.model
+	}
+	r.Stream = r.StreamingFunc != nil
+	url := c.baseUrl + "/text/chatcompletion_v2"
+	if c.groupId != "" {
+		url += "?GroupId=" + c.groupId
+	}
+
+	body, err := httputils.MergeBody(r, r.ExtraBody)
+	if err != nil {
+		return nil, err
+	}
+	var response ChatCompletionResponse
+	if !r.Stream {
+		if err := httputils.HttpPost(ctx, url, body, c.setHeader(), &response); err != nil {
+			return nil, err
+		}
+	} else if err := c.stream(ctx, url, body, r, &response); err != nil {
+		return nil, err
+	}
+	if response.BaseResp.StatusCode != 0 {
+		return nil, fmt.Errorf("statusCode: %d, errMsg: %s", response.BaseResp.StatusCode, response.BaseResp.StatusMsg)
+	}
+	// 命中敏感词时没有 choices，由调用方判断
+	if len(response.Choices) == 0 && !response.InputSensitive && !response.OutputSensitive {
+		return nil, ErrEmptyResponse
+	}
+	return &response, nil
+}
+
+func (c *Client) stream(ctx context.Context, url string, body json.RawMessage, r *ChatCompletionRequest, response *ChatCompletionResponse) error {
+	message := &Message{Role: "assistant"}
+	choice := &Choice{Message: message}
+	var content strings.Builder
+	return httputils.HttpStream(ctx, url, body, c.setHeader(), func(ctx context.Context, line string) error {
+		if !strings.HasPrefix(line, "data:") {
+			return nil
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			return nil
+		}
+		var chunk ChatCompletionResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("unexpected line: %v", line)
+		}
+		if chunk.BaseResp.StatusCode != 0 {
+			return fmt.Errorf("statusCode: %d, errMsg: %s", chunk.BaseResp.StatusCode, chunk.BaseResp.StatusMsg)
+		}
+		response.Id, response.Created, response.Model, response.Object = chunk.Id, chunk.Created, chunk.Model, chunk.Object
+		response.InputSensitive, response.InputSensitiveType = chunk.InputSensitive, chunk.InputSensitiveType
+		response.OutputSensitive, response.OutputSensitiveType = chunk.OutputSensitive, chunk.OutputSensitiveType
+		if chunk.Usage != nil {
+			response.Usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		response.Choices = []*Choice{choice}
+		delta := chunk.Choices[0]
+		if delta.FinishReason != "" {
+			choice.FinishReason = delta.FinishReason
+		}
+		// 最后一个数据包在 message 中返回完整的回复，不再重复拼接
+		if delta.Message != nil {
+			if text, _ := delta.Message.Content.(string); text != "" && content.Len() == 0 {
+				content.WriteString(text)
+			}
+			if len(message.ToolCalls) == 0 {
+				message.ToolCalls = delta.Message.ToolCalls
+			}
+			message.Content = content.String()
+			return nil
+		}
+		if delta.Delta == nil {
+			return nil
+		}
+		for _, tc := range delta.Delta.ToolCalls {
+			mergeToolCall(message, tc)
+		}
+		text, _ := delta.Delta.Content.(string)
+		if text == "" {
+			return nil
+		}
+		content.WriteString(text)
+		message.Content = content.String()
+		return r.StreamingFunc(ctx, []byte(text), nil)
+	})
+}
+
+// mergeToolCall 按 index 拼接流式返回的工具调用
+func mergeToolCall(msg *Message, delta *ToolCall) {
+	for _, tc := range msg.ToolCalls {
+		if tc.Index != delta.Index {
+			continue
+		}
+		if delta.Id != "" {
+			tc.Id = delta.Id
+		}
+		if delta.Function.Name != "" {
+			tc.Function.Name = delta.Function.Name
+		}
+		tc.Function.Arguments += delta.Function.Arguments
+		return
+	}
+	msg.ToolCalls = append(msg.ToolCalls, delta)
+}
+
+// 设置权限
+func (c *Client) setHeader() map[string]string {
+	return map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": "Bearer " + c.apiKey,
+	}
+}
@@ -0,0 +1,164 @@
+package minimaxclientv2
+
+import (
+	"context"
+	"net/http"
+)
+
+const (
+	defaultBaseUrl = "https://api.minimax.chat/v1"
+	defaultModel   = "abab6.5s-chat"
+)
+
+// Option is an option for the minimax v2 client.
+type Option func(*Client) error
+
+// Doer performs a HTTP request.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+func WithGroupId(value string) Option {
+	return func(c *Client) error {
+		c.groupId = value
+		return nil
+	}
+}
+
+func WithApiKey(value string) Option {
+	return func(c *Client) error {
+		c.apiKey = value
+		return nil
+	}
+}
+
+func WithBaseUrl(value string) Option {
+	return func(c *Client) error {
+		c.baseUrl = value
+		return nil
+	}
+}
+
+func WithHttpClient(value Doer) Option {
+	return func(c *Client) error {
+		c.httpClient = value
+		return nil
+	}
+}
+
+func WithModel(value string) Option {
+	return func(c *Client) error {
+		c.model = value
+		return nil
+	}
+}
+
+// ChatCompletionRequest chatcompletion_v2 接口请求，格式和 openai 一致
+type ChatCompletionRequest struct {
+	Model       string     `json:"model"`
+	Messages    []*Message `json:"messages"`
+	Stream      bool       `json:"stream,omitempty"`
+	MaxTokens   int        `json:"max_tokens,omitempty"`
+	Temperature *float64   `json:"temperature,omitempty"`
+	TopP        *float64   `json:"top_p,omitempty"`
+	// 对输出中易涉及隐私问题的文本信息进行打码，默认 true
+	MaskSensitiveInfo *bool   `json:"mask_sensitive_info,omitempty"`
+	Tools             []*Tool `json:"tools,omitempty"`
+	// auto 或 none
+	ToolChoice string `json:"tool_choice,omitempty"`
+	// 只支持 json_schema
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	StreamingFunc func(ctx context.Context, chunk []byte, err error) error `json:"-"`
+	// 合并到请求体中的字段
+	ExtraBody map[string]any `json:"-"`
+}
+
+// ResponseFormat 按照 json schema 返回
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JsonSchema *JsonSchema `json:"json_schema"`
+}
+
+type JsonSchema struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      any    `json:"schema"`
+}
+
+// Message content 为 string 或 []*Content
+type Message struct {
+	Role       string      `json:"role"`
+	Name       string      `json:"name,omitempty"`
+	Content    any         `json:"content"`
+	ToolCalls  []*ToolCall `json:"tool_calls,omitempty"`
+	ToolCallId string      `json:"tool_call_id,omitempty"`
+}
+
+// Content 多模态内容，type 为 text 或 image_url
+type Content struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageUrl *ImageUrl `json:"image_url,omitempty"`
+}
+
+type ImageUrl struct {
+	Url string `json:"url"`
+}
+
+type Tool struct {
+	Type     string              `json:"type"`
+	Function *FunctionDefinition `json:"function"`
+}
+
+// FunctionDefinition v2 接口的 parameters 为 json schema 字符串
+type FunctionDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  string `json:"parameters"`
+}
+
+type ToolCall struct {
+	Index    int          `json:"index,omitempty"`
+	Id       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function FunctionCall `json:"function"`
+}
+
+type FunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+type Choice struct {
+	Index        int      `json:"index"`
+	FinishReason string   `json:"finish_reason"`
+	Message      *Message `json:"message,omitempty"`
+	Delta        *Message `json:"delta,omitempty"`
+}
+
+type Usage struct {
+	PromptTokens     int64 `json:"prompt_tokens,omitempty"`
+	CompletionTokens int64 `json:"completion_tokens,omitempty"`
+	TotalTokens      int64 `json:"total_tokens,omitempty"`
+}
+
+type BaseResp struct {
+	StatusCode int64  `json:"status_code,omitempty"`
+	StatusMsg  string `json:"status_msg,omitempty"`
+}
+
+// ChatCompletionResponse chatcompletion_v2 接口返回，流式返回时每个数据包也是该结构
+type ChatCompletionResponse struct {
+	Id                  string    `json:"id"`
+	Created             int64     `json:"created"`
+	Model               string    `json:"model"`
+	Object              string    `json:"object"`
+	Choices             []*Choice `json:"choices"`
+	Usage               *Usage    `json:"usage,omitempty"`
+	InputSensitive      bool      `json:"input_sensitive,omitempty"`
+	InputSensitiveType  int64     `json:"input_sensitive_type,omitempty"`
+	OutputSensitive     bool      `json:"output_sensitive,omitempty"`
+	OutputSensitiveType int64     `json:"output_sensitive_type,omitempty"`
+	BaseResp            BaseResp  `json:"base_resp"`
+}
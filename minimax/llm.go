@@ -3,6 +3,7 @@ package minimax
 import (
 	"errors"
 	minimaxclientv12 "github.com/comqositi/kpllms/minimax/internal/minimaxclientv1"
+	"github.com/comqositi/kpllms/minimax/internal/minimaxclientv2"
 	"net/http"
 	"os"
 )
@@ -13,8 +14,9 @@ var (
 	ErrUnexpectedResponseLength = errors.New("unexpected length of response")
 )
 
-func newClient(opts ...Option) (*minimaxclientv12.Client, error) {
+func newClient(opts ...Option) (*options, *minimaxclientv12.Client, error) {
 	options := &options{
+		apiVersion:     APIVersionPro,
 		groupId:        os.Getenv(groupIdEnvVarName),
 		apiKey:         os.Getenv(apiKeyEnvVarName),
 		baseUrl:        os.Getenv(baseURLEnvVarName),
@@ -29,18 +31,31 @@ func newClient(opts ...Option) (*minimaxclientv12.Client, error) {
 
 	if options.model == "" {
 		options.model = defaultModel
+		if options.apiVersion == APIVersionV2 {
+			options.model = defaultModelV2
+		}
 	}
 
 	if options.embeddingModel == "" {
 		options.embeddingModel = defaultEmbeddingModel
 	}
 
-	return minimaxclientv12.NewClient(minimaxclientv12.WithGroupId(options.groupId),
+	c, err := minimaxclientv12.NewClient(minimaxclientv12.WithGroupId(options.groupId),
 		minimaxclientv12.WithApiKey(options.apiKey),
 		minimaxclientv12.WithBaseUrl(options.baseUrl),
 		minimaxclientv12.WithHttpClient(options.httpClient),
 		minimaxclientv12.WithModel(options.model),
 		minimaxclientv12.WithEmbeddingsModel(options.embeddingModel),
 	)
+	return options, c, err
+}
 
+// newClientV2 创建 chatcompletion_v2 接口的客户端
+func newClientV2(options *options) (*minimaxclientv2.Client, error) {
+	return minimaxclientv2.NewClient(minimaxclientv2.WithGroupId(options.groupId),
+		minimaxclientv2.WithApiKey(options.apiKey),
+		minimaxclientv2.WithBaseUrl(options.baseUrl),
+		minimaxclientv2.WithHttpClient(options.httpClient),
+		minimaxclientv2.WithModel(options.model),
+	)
 }
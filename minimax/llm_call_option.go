@@ -0,0 +1,95 @@
+package minimax
+
+import (
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+)
+
+// minimax 特有的调用参数，通过 kpllms.CallOptions.ProviderOptions 传递
+
+type callOptionsKey struct{}
+
+type callOptions struct {
+	// glyph 语法的返回格式模板
+	rawGlyph string
+	// 插件，例如 plugin_web_search
+	plugins []string
+	// 示例对话
+	sampleMessages []*schema.ChatMessage
+	// 为空时使用默认值 false
+	maskSensitiveInfo *bool
+	// 多个机器人设定，回复使用第一个机器人
+	botSettings []BotSetting
+}
+
+// BotSetting 机器人设定
+type BotSetting struct {
+	BotName string
+	Content string
+}
+
+const pluginWebSearch = "plugin_web_search"
+
+func withCallOption(f func(*callOptions)) kpllms.CallOption {
+	return func(o *kpllms.CallOptions) {
+		if o.ProviderOptions == nil {
+			o.ProviderOptions = make(map[any]any)
+		}
+		co, _ := o.ProviderOptions[callOptionsKey{}].(*callOptions)
+		if co == nil {
+			co = &callOptions{}
+			o.ProviderOptions[callOptionsKey{}] = co
+		}
+		f(co)
+	}
+}
+
+// getCallOptions 没有设置时返回空的参数
+func getCallOptions(opts kpllms.CallOptions) *callOptions {
+	if co, ok := opts.ProviderOptions[callOptionsKey{}].(*callOptions); ok {
+		return co
+	}
+	return &callOptions{}
+}
+
+// WithWebSearch 开启联网搜索插件，会按照搜索次数额外计费，仅 chatcompletion_pro 接口支持，v2 接口返回 kpllms.ErrUnsupportedCallOption
+func WithWebSearch() kpllms.CallOption {
+	return withCallOption(func(o *callOptions) {
+		for _, p := range o.plugins {
+			if p == pluginWebSearch {
+				return
+			}
+		}
+		o.plugins = append(o.plugins, pluginWebSearch)
+	})
+}
+
+// WithSampleMessages 示例对话，仅 chatcompletion_pro 接口支持，v2 接口返回 kpllms.ErrUnsupportedCallOption
+func WithSampleMessages(messages ...*schema.ChatMessage) kpllms.CallOption {
+	return withCallOption(func(o *callOptions) {
+		o.sampleMessages = messages
+	})
+}
+
+// WithMaskSensitiveInfo 对输出中的邮箱、域名、链接、证件号、家庭住址等隐私信息打码，默认不打码
+func WithMaskSensitiveInfo(mask bool) kpllms.CallOption {
+	return withCallOption(func(o *callOptions) {
+		o.maskSensitiveInfo = &mask
+	})
+}
+
+// WithBotSettings 多个机器人设定，模型以第一个机器人的身份回复，system 消息不再放入机器人设定。
+// 仅 chatcompletion_pro 接口支持，v2 接口返回 kpllms.ErrUnsupportedCallOption
+func WithBotSettings(settings ...BotSetting) kpllms.CallOption {
+	return withCallOption(func(o *callOptions) {
+		o.botSettings = settings
+	})
+}
+
+// WithRawGlyph 使用 glyph 语法限制返回格式，例如：这句话的翻译是：{{gen 'content'}}，
+// 结果放在 GenerationInfo 的 glyph_result 中。仅 chatcompletion_pro 接口支持，v2 接口返回 kpllms.ErrUnsupportedCallOption
+func WithRawGlyph(rawGlyph string) kpllms.CallOption {
+	return withCallOption(func(o *callOptions) {
+		o.rawGlyph = rawGlyph
+	})
+}
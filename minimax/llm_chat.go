@@ -4,15 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+
 	"github.com/comqositi/kpllms"
 	minimaxclientv12 "github.com/comqositi/kpllms/minimax/internal/minimaxclientv1"
+	"github.com/comqositi/kpllms/minimax/internal/minimaxclientv2"
 	"github.com/comqositi/kpllms/schema"
 )
 
 type Chat struct {
-	client    *minimaxclientv12.Client
+	client *minimaxclientv12.Client
+	// 使用 chatcompletion_v2 接口时不为空
+	clientV2  *minimaxclientv2.Client
 	usage     []minimaxclientv12.Usage
 	chatError error // 每次模型调用的错误信息
+	// 每次调用的默认参数
+	defaultOptions []kpllms.CallOption
 }
 
 const (
@@ -24,39 +31,92 @@ var (
 	_ kpllms.Model = (*Chat)(nil)
 )
 
+// samplingRanges minimax 支持的采样参数范围
+var samplingRanges = kpllms.SamplingRanges{
+	Temperature: kpllms.Range{Min: 0, Max: 1, ExclusiveMin: true},
+	TopP:        kpllms.Range{Min: 0, Max: 1, ExclusiveMin: true},
+}
+
 // NewChat returns a new OpenAI chat LLM.
 func NewChat(opts ...Option) (*Chat, error) {
-	c, err := newClient(opts...)
-	return &Chat{
-		client: c,
-	}, err
+	options, c, err := newClient(opts...)
+	if err != nil {
+		return &Chat{client: c}, err
+	}
+	chat := &Chat{client: c, defaultOptions: options.defaultCallOptions}
+	if options.apiVersion == APIVersionV2 {
+		chat.clientV2, err = newClientV2(options)
+	}
+	return chat, err
 }
 
 func (o *Chat) Chat(ctx context.Context, messageSets []*schema.ChatMessage, options ...kpllms.CallOption) (*schema.ContentResponse, error) {
-	opts := kpllms.CallOptions{}
-	for _, opt := range options {
-		opt(&opts)
+	opts := o.ResolveCallOptions(options...)
+	if err := samplingRanges.Validate(&opts); err != nil {
+		return nil, err
+	}
+	if err := opts.CheckSupported("minimax"); err != nil {
+		return nil, err
+	}
+	if o.clientV2 != nil {
+		return o.chatV2(ctx, messageSets, opts)
 	}
 
+	co := getCallOptions(opts)
 	clientMsg, setting, reply := messagesToClientMessages(messageSets)
+	// 对输出中易涉及隐私问题的文本信息进行打码，目前包括但不限于邮箱、域名、链接、证件号、家庭住址等，接口默认true，这里默认不打码
+	maskSensitiveInfo := false
+	if co.maskSensitiveInfo != nil {
+		maskSensitiveInfo = *co.maskSensitiveInfo
+	}
 	req := &minimaxclientv12.CompletionRequest{
 		Model:            opts.Model,
 		Messages:         clientMsg,
-		TokensToGenerate: int64(opts.MaxTokens),
-		Temperature:      float32(opts.Temperature),
-		TopP:             float32(opts.TopP),
+		TokensToGenerate: int64(kpllms.Deref(opts.MaxTokens)),
+		Temperature:      opts.Temperature,
+		TopP:             opts.TopP,
 		BotSetting:       []minimaxclientv12.BotSetting{setting},
 		ReplyConstraints: reply,
 		//RequestId : opts.RequestId,
 		StreamingFunc:     opts.StreamingFunc,
 		Stream:            opts.StreamingFunc != nil,
-		MaskSensitiveInfo: false, // 对输出中易涉及隐私问题的文本信息进行打码，目前包括但不限于邮箱、域名、链接、证件号、家庭住址等，默认true，即开启打码
-		//FunctionCallSetting   自动模式等
+		MaskSensitiveInfo: &maskSensitiveInfo,
+		Plugins:           co.plugins,
+		ExtraBody:         opts.ExtraBody,
+	}
+	// 多个机器人时以第一个机器人的身份回复，历史消息中的 BOT 消息也使用该名称
+	if len(co.botSettings) > 0 {
+		req.BotSetting = req.BotSetting[:0]
+		for _, b := range co.botSettings {
+			req.BotSetting = append(req.BotSetting, minimaxclientv12.BotSetting{BotName: b.BotName, Content: b.Content})
+		}
+		req.ReplyConstraints.SenderName = co.botSettings[0].BotName
+		for _, m := range req.Messages {
+			if m.SenderType == "BOT" {
+				m.SenderName = co.botSettings[0].BotName
+			}
+		}
+	}
+	if len(co.sampleMessages) > 0 {
+		samples, _, _ := messagesToClientMessages(co.sampleMessages)
+		for _, m := range samples {
+			if m.SenderType == "BOT" {
+				m.SenderName = req.ReplyConstraints.SenderName
+			}
+			req.SampleMessages = append(req.SampleMessages, &minimaxclientv12.SampleMessage{
+				SenderType: m.SenderType, SenderName: m.SenderName, Text: m.Text,
+			})
+		}
 	}
 
-	// TODO 开启 jsonMode 格式
-	if opts.JsonMode {
-
+	// 指定 schema 时使用 glyph json_value 限制返回格式，否则通过 bot_setting 提示返回 json
+	glyph, err := glyphFromOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	req.ReplyConstraints.Glyph = glyph
+	if opts.JsonMode && glyph == nil {
+		req.BotSetting[0].Content += jsonModePrompt
 	}
 
 	// 工具加入
@@ -71,11 +131,10 @@ func (o *Chat) Chat(ctx context.Context, messageSets []*schema.ChatMessage, opti
 	// opts.ToolChoice 默认 auto自动调用
 	if opts.ToolChoice.Type == schema.ToolChoiceTypeFunction {
 		// 指定函数
-		req.FunctionCallSetting.Type = "specific"
-		req.FunctionCallSetting.Name = opts.ToolChoice.Function.Name
+		req.FunctionCallSetting = &minimaxclientv12.FunctionCallSetting{Type: "specific", Name: opts.ToolChoice.Function.Name}
 	} else if opts.ToolChoice.Type == schema.ToolChoiceTypeNone {
 		// 不调用
-		req.FunctionCallSetting.Type = "none"
+		req.FunctionCallSetting = &minimaxclientv12.FunctionCallSetting{Type: "none"}
 	}
 
 	result, err := o.client.CreateCompletion(ctx, req)
@@ -92,33 +151,64 @@ func (o *Chat) Chat(ctx context.Context, messageSets []*schema.ChatMessage, opti
 		return nil, ErrEmptyResponse
 	}
 
+	// 流式和非流式返回相同结构，流式时由客户端拼接为完整回复
 	resp := &schema.ContentResponse{Choices: []*schema.ContentChoice{
-		{Usage: &schema.Usage{}},
+		{
+			Usage: &schema.Usage{},
+			GenerationInfo: map[string]any{
+				"id":                       result.Id,
+				"tokens_with_added_plugin": int(result.Usage.TokensWithAddedPlugin),
+			},
+		},
 	}}
 
-	//utils.PrintlnJson(result)
-
 	resp.Choices[0].Usage.PromptTokens = int(result.Usage.PromptTokens)
 	resp.Choices[0].Usage.CompletionTokens = int(result.Usage.CompletionTokens)
 	resp.Choices[0].Usage.TotalTokens = int(result.Usage.TotalTokens)
-	resp.Choices[0].Content = result.Choices[0].Messages[0].Text
 	resp.Choices[0].StopReason = result.Choices[0].FinishReason
-
-	if result.Choices[0].Messages[0].FunctionCall != nil {
-		resp.Choices[0].ToolCalls = []*schema.ToolCall{
-			{
-				Type: schema.ToolCallTypeFunction,
-				Function: schema.FunctionCall{
-					Name:      result.Choices[0].Messages[0].FunctionCall.Name,
-					Arguments: result.Choices[0].Messages[0].FunctionCall.Arguments,
-				},
-			},
+	resp.Choices[0].Content, resp.Choices[0].ToolCalls = messagesToContent(result.Id, result.Choices[0].Messages)
+	if glyphResult := result.Choices[0].GlyphResult.Content; glyphResult != "" {
+		resp.Choices[0].GenerationInfo["glyph_result"] = glyphResult
+		if resp.Choices[0].Content == "" {
+			resp.Choices[0].Content = glyphResult
 		}
 	}
 
 	return resp, nil
 
 }
+
+// messagesToContent 拼接回复中的文本，并行函数调用时每个函数调用是一条单独的 BOT 消息
+func messagesToContent(id string, messages []minimaxclientv12.Message) (string, []*schema.ToolCall) {
+	var text strings.Builder
+	var toolCalls []*schema.ToolCall
+	for _, m := range messages {
+		text.WriteString(m.Text)
+		if m.FunctionCall == nil {
+			continue
+		}
+		toolCalls = append(toolCalls, &schema.ToolCall{
+			Id:   toolCallId(id, len(toolCalls)),
+			Type: schema.ToolCallTypeFunction,
+			Function: schema.FunctionCall{
+				Name:      m.FunctionCall.Name,
+				Arguments: m.FunctionCall.Arguments,
+			},
+		})
+	}
+	return text.String(), toolCalls
+}
+
+// toolCallId minimax 不返回函数调用 id，根据请求 id 和序号生成，相同的回复得到相同的 id
+func toolCallId(id string, index int) string {
+	return fmt.Sprintf("call_%s_%d", id, index)
+}
+
+// ResolveCallOptions 返回合并默认参数后实际使用的调用参数，用于调试
+func (o *Chat) ResolveCallOptions(options ...kpllms.CallOption) kpllms.CallOptions {
+	return kpllms.ResolveCallOptions(o.defaultOptions, options...)
+}
+
 func toolFromTool(t *kpllms.Tool) (*minimaxclientv12.FunctionDefinition, error) {
 
 	tool := &minimaxclientv12.FunctionDefinition{
@@ -139,55 +229,73 @@ func messagesToClientMessages(messages []*schema.ChatMessage) ([]*minimaxclientv
 		SenderType: defaultSendType,
 		SenderName: defaultBotName,
 	}
-	msglen := len(messages)
-	// 第一个system信息放入bot_setting
-	if len(messages) > 0 {
-		if messages[0].Role == schema.RoleSystem {
-			systemContent := messages[0].Content.(string)
-			setting.Content = systemContent
-			messages = messages[1:]
-			msglen -= 1
-		}
-	}
-
-	msgs := make([]*minimaxclientv12.Message, msglen)
-	for i, m := range messages {
-		typ := m.Role
-		msg := &minimaxclientv12.Message{}
-		// 如果是字符串，先赋值
-		if content, ok := m.Content.(string); ok {
-			msg.Text = content
-		}
-
-		switch typ {
-		// ai 回答，可能是文本答案，可能是 function
+	// 函数调用 id 对应的函数名，FUNCTION 消息的 sender_name 需要为函数名
+	toolNames := make(map[string]string)
+	hasSystem := false
+	msgs := make([]*minimaxclientv12.Message, 0, len(messages))
+	for _, m := range messages {
+		switch m.Role {
+		// system 信息放入 bot_setting
+		case schema.RoleSystem:
+			if !hasSystem {
+				setting.Content = messageText(m.Content)
+			} else {
+				setting.Content += "\n" + messageText(m.Content)
+			}
+			hasSystem = true
+		// ai 回答，可能是文本答案，可能是 function，多个函数调用拆分为多条 BOT 消息
 		case schema.RoleAssistant:
-			msg.SenderType = "BOT"
-			msg.SenderName = defaultBotName
-			if m.ToolCalls != nil {
-				msg.FunctionCall = toolToFunction(m.ToolCalls)
+			msg := &minimaxclientv12.Message{SenderType: "BOT", SenderName: defaultBotName, Text: messageText(m.Content)}
+			if len(m.ToolCalls) == 0 {
+				msgs = append(msgs, msg)
+				continue
+			}
+			for i, call := range m.ToolCalls {
+				if i > 0 {
+					msg = &minimaxclientv12.Message{SenderType: "BOT", SenderName: defaultBotName}
+				}
+				msg.FunctionCall = &minimaxclientv12.FunctionCall{
+					Name:      call.Function.Name,
+					Arguments: call.Function.Arguments,
+				}
+				toolNames[call.Id] = call.Function.Name
+				msgs = append(msgs, msg)
 			}
 		case schema.RoleUser:
-			msg.SenderType = "USER"
-			msg.SenderName = defaultSendName
+			msgs = append(msgs, &minimaxclientv12.Message{SenderType: "USER", SenderName: defaultSendName, Text: messageText(m.Content)})
 		case schema.RoleTool:
-			msg.SenderType = "FUNCTION"
-			msg.SenderName = defaultSendName
-
-			//case schema.ChatMessageTypeFunction:
-			//	msg.Role = "function"
+			name := toolNames[m.ToolCallId]
+			if name == "" {
+				name = m.Name
+			}
+			if name == "" {
+				name = defaultSendName
+			}
+			msgs = append(msgs, &minimaxclientv12.Message{SenderType: "FUNCTION", SenderName: name, Text: messageText(m.Content)})
 		}
-		msgs[i] = msg
 	}
 
 	return msgs, setting, replyConstraints
 }
 
-func toolToFunction(call []*schema.ToolCall) *minimaxclientv12.FunctionCall {
-	return &minimaxclientv12.FunctionCall{
-		Name:      call[0].Function.Name,
-		Arguments: call[0].Function.Arguments,
+// messageText 取出消息中的文本，openai 格式的函数调用消息 content 可能为空
+func messageText(content any) string {
+	switch c := content.(type) {
+	case string:
+		return c
+	case []any:
+		var text strings.Builder
+		for _, p := range c {
+			switch part := p.(type) {
+			case schema.TextContent:
+				text.WriteString(part.Text)
+			case *schema.TextContent:
+				text.WriteString(part.Text)
+			}
+		}
+		return text.String()
 	}
+	return ""
 }
 
 // EmbedDocuments  实现 embedder 接口 文档存储向量：存入数据库的向量，被用于检索
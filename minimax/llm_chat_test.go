@@ -0,0 +1,417 @@
+package minimax
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+)
+
+// newTestServer 按接口路径分发请求，handler 返回响应
+func newTestServer(t *testing.T, handler func(path string, body map[string]any, w http.ResponseWriter), opts ...Option) *Chat {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" || r.URL.Query().Get("GroupId") != "group" {
+			t.Errorf("unexpected auth: %v %v", r.Header, r.URL)
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		handler(r.URL.Path, body, w)
+	}))
+	t.Cleanup(srv.Close)
+	chat, err := NewChat(append([]Option{WithGroupId("group"), WithApiKey("key"), WithBaseUrl(srv.URL)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chat
+}
+
+func TestChatV2(t *testing.T) {
+	var got map[string]any
+	chat := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		if path != "/text/chatcompletion_v2" {
+			t.Errorf("path = %s", path)
+		}
+		got = body
+		_, _ = io.WriteString(w, `{"id":"m1","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":"",
+			"tool_calls":[{"id":"call_a","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"上海\"}"}},
+			{"id":"call_b","type":"function","function":{"name":"get_time","arguments":"{}"}}]}}],
+			"usage":{"total_tokens":50},"base_resp":{"status_code":0,"status_msg":""}}`)
+	}, WithAPIVersion(APIVersionV2))
+
+	tools := []*kpllms.Tool{{Type: schema.ToolCallTypeFunction, Function: &kpllms.FunctionDefinition{
+		Name: "get_weather", Parameters: map[string]any{"type": "object"},
+	}}}
+	resp, err := chat.Chat(context.Background(), []*schema.ChatMessage{
+		{Role: schema.RoleSystem, Content: "你是助手"},
+		{Role: schema.RoleUser, Content: []any{
+			schema.TextContent{Text: "这是哪里的天气"},
+			schema.ImageContent{ImageUrl: schema.ImageUrl{Url: "https://example.com/a.png"}},
+		}},
+	}, kpllms.WithTools(tools))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := got["messages"].([]any)
+	parts := msgs[1].(map[string]any)["content"].([]any)
+	fn := got["tools"].([]any)[0].(map[string]any)["function"].(map[string]any)
+	if got["model"] != defaultModelV2 || len(msgs) != 2 || len(parts) != 2 || fn["parameters"] != `{"type":"object"}` {
+		t.Fatalf("request = %v", got)
+	}
+	c := resp.Choices[0]
+	if len(c.ToolCalls) != 2 || c.ToolCalls[1].Id != "call_b" || c.Usage.TotalTokens != 50 {
+		t.Fatalf("choice = %+v", c)
+	}
+}
+
+func TestChatV2Stream(t *testing.T) {
+	chunks := []string{
+		`{"id":"m2","choices":[{"index":0,"delta":{"role":"assistant","content":"你好"}}]}`,
+		`{"id":"m2","choices":[{"index":0,"delta":{"role":"assistant","content":"，有什么"}}]}`,
+		`{"id":"m2","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"你好，有什么"}}],
+			"usage":{"total_tokens":12},"base_resp":{"status_code":0}}`,
+	}
+	chat := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		for _, c := range chunks {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", strings.ReplaceAll(c, "\n", ""))
+		}
+	}, WithAPIVersion(APIVersionV2))
+	var streamed strings.Builder
+	resp, err := chat.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}},
+		kpllms.WithStreamingFunc(func(ctx context.Context, chunk []byte, innerErr error) error {
+			streamed.Write(chunk)
+			return nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := resp.Choices[0]
+	if streamed.String() != "你好，有什么" || c.Content != "你好，有什么" || c.StopReason != "stop" || c.Usage.TotalTokens != 12 {
+		t.Fatalf("streamed = %q, choice = %+v", streamed.String(), c)
+	}
+}
+
+func TestChatV2UnsupportedOptions(t *testing.T) {
+	chat := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		t.Errorf("unexpected request: %v", body)
+	}, WithAPIVersion(APIVersionV2))
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}}
+	for _, opt := range []kpllms.CallOption{
+		WithWebSearch(),
+		WithSampleMessages(&schema.ChatMessage{Role: schema.RoleUser, Content: "示例"}),
+		WithBotSettings(BotSetting{BotName: "助手", Content: "你是助手"}),
+		WithRawGlyph("翻译：{{gen 'content'}}"),
+		kpllms.WithJsonMode(true),
+		kpllms.WithToolChoice(kpllms.ToolChoice{Type: schema.ToolChoiceTypeFunction, Function: kpllms.ToolChoiceFunction{Name: "f"}}),
+	} {
+		if _, err := chat.Chat(context.Background(), msgs, opt); !errors.Is(err, kpllms.ErrUnsupportedCallOption) {
+			t.Fatalf("err = %v", err)
+		}
+	}
+}
+
+func TestChatV2JsonSchema(t *testing.T) {
+	var got map[string]any
+	chat := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		got = body
+		_, _ = io.WriteString(w, `{"id":"m3","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"{\"city\":\"上海\"}"}}],
+			"base_resp":{"status_code":0}}`)
+	}, WithAPIVersion(APIVersionV2))
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "上海天气"}}
+	resp, err := chat.Chat(context.Background(), msgs, kpllms.WithJsonSchema(&kpllms.JsonSchema{
+		Schema: `{"type":"object","properties":{"city":{"type":"string"}}}`,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	format, _ := json.Marshal(got["response_format"])
+	want := `{"json_schema":{"name":"response","schema":{"properties":{"city":{"type":"string"}},"type":"object"}},"type":"json_schema"}`
+	if string(format) != want || resp.Choices[0].Content != `{"city":"上海"}` {
+		t.Fatalf("response_format = %s, content = %q", format, resp.Choices[0].Content)
+	}
+
+	_, err = chat.Chat(context.Background(), msgs, kpllms.WithJsonSchema(&kpllms.JsonSchema{Schema: `{"type":`}))
+	if err == nil || !strings.Contains(err.Error(), "invalid json schema") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestChatProByDefault(t *testing.T) {
+	chat := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		if path != "/text/chatcompletion_pro" || body["model"] != defaultModel || body["bot_setting"] == nil {
+			t.Errorf("unexpected request: %s %v", path, body)
+		}
+		_, _ = io.WriteString(w, `{"choices":[{"finish_reason":"stop","messages":[{"sender_type":"BOT","sender_name":"靠谱智能助理","text":"你好"}]}],
+			"usage":{"total_tokens":5},"base_resp":{"status_code":0}}`)
+	})
+	resp, err := chat.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Choices[0].Content != "你好" {
+		t.Fatalf("choice = %+v", resp.Choices[0])
+	}
+}
+
+func TestChatProParallelToolCalls(t *testing.T) {
+	var got map[string]any
+	chat := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		got = body
+		_, _ = io.WriteString(w, `{"id":"r1","choices":[{"finish_reason":"function_call","messages":[
+			{"sender_type":"BOT","sender_name":"靠谱智能助理","text":"","function_call":{"name":"get_weather","arguments":"{\"city\":\"上海\"}"}},
+			{"sender_type":"BOT","sender_name":"靠谱智能助理","text":"","function_call":{"name":"get_time","arguments":"{}"}}]}],
+			"usage":{"total_tokens":30},"base_resp":{"status_code":0}}`)
+	})
+	msgs := []*schema.ChatMessage{
+		{Role: schema.RoleSystem, Content: "你是助手"},
+		{Role: schema.RoleUser, Content: "上海天气和时间"},
+	}
+	resp, err := chat.Chat(context.Background(), msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := resp.Choices[0].ToolCalls
+	if len(calls) != 2 || calls[0].Id != "call_r1_0" || calls[1].Id != "call_r1_1" || calls[1].Function.Name != "get_time" {
+		t.Fatalf("tool calls = %+v", calls)
+	}
+
+	// openai 格式的对话记录：函数调用消息 content 为空，工具结果通过 tool_call_id 关联
+	msgs = append(msgs,
+		&schema.ChatMessage{Role: schema.RoleAssistant, ToolCalls: calls},
+		&schema.ChatMessage{Role: schema.RoleTool, ToolCallId: calls[1].Id, Content: "12:00"},
+		&schema.ChatMessage{Role: schema.RoleTool, ToolCallId: calls[0].Id, Content: "晴"},
+	)
+	if _, err = chat.Chat(context.Background(), msgs); err != nil {
+		t.Fatal(err)
+	}
+	sent := got["messages"].([]any)
+	if len(sent) != 5 || got["bot_setting"].([]any)[0].(map[string]any)["content"] != "你是助手" {
+		t.Fatalf("request = %v", got)
+	}
+	for i, want := range []string{"USER:用户", "BOT:get_weather", "BOT:get_time", "FUNCTION:get_time", "FUNCTION:get_weather"} {
+		m := sent[i].(map[string]any)
+		name := m["sender_name"].(string)
+		if fc, ok := m["function_call"].(map[string]any); ok {
+			name = fc["name"].(string)
+		}
+		if m["sender_type"].(string)+":"+name != want {
+			t.Fatalf("message %d = %v, want %s", i, m, want)
+		}
+	}
+}
+
+func TestChatProStream(t *testing.T) {
+	chunks := []string{
+		`{"id":"r2","choices":[{"messages":[{"sender_type":"BOT","sender_name":"靠谱智能助理","text":"我查"}]}]}`,
+		`{"id":"r2","choices":[{"messages":[{"sender_type":"BOT","sender_name":"靠谱智能助理","text":"一下"}]}]}`,
+		`{"id":"r2","choices":[{"finish_reason":"function_call","messages":[{"sender_type":"BOT","sender_name":"靠谱智能助理","text":"我查一下",` +
+			`"function_call":{"name":"get_weather","arguments":"{}"}}]}],"usage":{"total_tokens":20,"tokens_with_added_plugin":7},"base_resp":{"status_code":0}}`,
+	}
+	chat := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		if body["stream"] != true {
+			_, _ = io.WriteString(w, chunks[2])
+			return
+		}
+		for _, c := range chunks {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+	})
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "上海天气"}}
+	want, err := chat.Chat(context.Background(), msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed strings.Builder
+	resp, err := chat.Chat(context.Background(), msgs, kpllms.WithStreamingFunc(func(ctx context.Context, chunk []byte, innerErr error) error {
+		streamed.Write(chunk)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := resp.Choices[0]
+	if streamed.String() != "我查一下" || c.Content != "我查一下" || c.StopReason != "function_call" || c.Usage.TotalTokens != 20 ||
+		len(c.ToolCalls) != 1 || c.ToolCalls[0].Id != "call_r2_0" || c.GenerationInfo["tokens_with_added_plugin"] != 7 {
+		t.Fatalf("streamed = %q, choice = %+v", streamed.String(), c)
+	}
+	if !reflect.DeepEqual(want.Choices[0], c) {
+		t.Fatalf("stream result %+v differs from %+v", c, want.Choices[0])
+	}
+}
+
+func TestChatProGlyph(t *testing.T) {
+	var got map[string]any
+	chat := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		got = body
+		_, _ = io.WriteString(w, `{"choices":[{"finish_reason":"stop","messages":[{"sender_type":"BOT","sender_name":"靠谱智能助理","text":""}],
+			"glyph_result":{"content":"{\"name\":\"张三\",\"age\":18}"}}],"usage":{"total_tokens":5},"base_resp":{"status_code":0}}`)
+	})
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "张三今年18岁"}}
+	glyph := func() map[string]any {
+		return got["reply_constraints"].(map[string]any)["glyph"].(map[string]any)
+	}
+	propertyList := func() string {
+		var names []string
+		for _, p := range glyph()["property_list"].([]any) {
+			names = append(names, p.(map[string]any)["name"].(string))
+		}
+		return strings.Join(names, ",")
+	}
+
+	// json 字符串保留属性顺序
+	resp, err := chat.Chat(context.Background(), msgs, kpllms.WithJsonSchema(&kpllms.JsonSchema{
+		Schema: `{"type":"object","properties":{"name":{"type":"string"},"age":{"type":"integer"}}}`,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if glyph()["type"] != "json_value" || propertyList() != "name,age" || glyph()["json_properties"].(map[string]any)["age"] == nil {
+		t.Fatalf("glyph = %v", glyph())
+	}
+	if c := resp.Choices[0]; c.Content != `{"name":"张三","age":18}` || c.GenerationInfo["glyph_result"] != c.Content {
+		t.Fatalf("choice = %+v", c)
+	}
+
+	// schema.Definition 先 required 再按名称排序
+	_, err = chat.Chat(context.Background(), msgs, kpllms.WithJsonSchema(&kpllms.JsonSchema{Schema: schema.Definition{
+		Type: schema.Object,
+		Properties: map[string]schema.Definition{
+			"age": {Type: schema.Integer}, "city": {Type: schema.String}, "name": {Type: schema.String},
+		},
+		Required: []string{"name"},
+	}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if propertyList() != "name,age,city" {
+		t.Fatalf("glyph = %v", glyph())
+	}
+
+	if _, err = chat.Chat(context.Background(), msgs, WithRawGlyph("这句话的翻译是：{{gen 'content'}}")); err != nil {
+		t.Fatal(err)
+	}
+	if glyph()["type"] != "raw" || glyph()["raw_glyph"] != "这句话的翻译是：{{gen 'content'}}" {
+		t.Fatalf("glyph = %v", glyph())
+	}
+
+	// 没有 schema 时通过 bot_setting 提示
+	if _, err = chat.Chat(context.Background(), msgs, kpllms.WithJsonMode(true)); err != nil {
+		t.Fatal(err)
+	}
+	setting := got["bot_setting"].([]any)[0].(map[string]any)["content"].(string)
+	if _, ok := got["reply_constraints"].(map[string]any)["glyph"]; ok || !strings.HasSuffix(setting, jsonModePrompt) {
+		t.Fatalf("request = %v", got)
+	}
+}
+
+func TestChatProProviderOptions(t *testing.T) {
+	var got map[string]any
+	chat := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		got = body
+		_, _ = io.WriteString(w, `{"choices":[{"finish_reason":"stop","messages":[{"sender_type":"BOT","sender_name":"小李","text":"好的"}]}],
+			"usage":{"total_tokens":5},"base_resp":{"status_code":0}}`)
+	})
+	_, err := chat.Chat(context.Background(), []*schema.ChatMessage{
+		{Role: schema.RoleUser, Content: "你好"},
+		{Role: schema.RoleAssistant, Content: "你好"},
+		{Role: schema.RoleUser, Content: "今天新闻"},
+	},
+		WithWebSearch(), WithWebSearch(),
+		WithMaskSensitiveInfo(true),
+		WithBotSettings(BotSetting{BotName: "小李", Content: "记者"}, BotSetting{BotName: "小王", Content: "编辑"}),
+		WithSampleMessages(&schema.ChatMessage{Role: schema.RoleUser, Content: "问"}, &schema.ChatMessage{Role: schema.RoleAssistant, Content: "答"}),
+		kpllms.WithToolChoice(kpllms.ToolChoice{Type: schema.ToolChoiceTypeFunction, Function: kpllms.ToolChoiceFunction{Name: "search"}}),
+		kpllms.WithExtraBody(map[string]any{"request_id": "abc", "temperature": 0.5}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugins := got["plugins"].([]any)
+	bots := got["bot_setting"].([]any)
+	samples := got["sample_messages"].([]any)
+	if len(plugins) != 1 || plugins[0] != "plugin_web_search" || got["mask_sensitive_info"] != true || len(bots) != 2 {
+		t.Fatalf("request = %v", got)
+	}
+	if got["reply_constraints"].(map[string]any)["sender_name"] != "小李" || got["messages"].([]any)[1].(map[string]any)["sender_name"] != "小李" {
+		t.Fatalf("request = %v", got)
+	}
+	if len(samples) != 2 || samples[1].(map[string]any)["sender_name"] != "小李" {
+		t.Fatalf("sample_messages = %v", samples)
+	}
+	if fc := got["function_call"].(map[string]any); fc["type"] != "specific" || fc["name"] != "search" {
+		t.Fatalf("function_call = %v", fc)
+	}
+	if got["request_id"] != "abc" || got["temperature"] != 0.5 {
+		t.Fatalf("extra body not merged: %v", got)
+	}
+
+	// 默认不打码，需要显式传 false
+	if _, err = chat.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got["mask_sensitive_info"] != false || got["plugins"] != nil {
+		t.Fatalf("request = %v", got)
+	}
+}
+
+func TestChatSamplingOptions(t *testing.T) {
+	var got map[string]any
+	chat := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		got = body
+		_, _ = io.WriteString(w, `{"choices":[{"finish_reason":"stop","messages":[{"sender_type":"BOT","sender_name":"靠谱智能助理","text":"你好"}]}],
+			"usage":{"total_tokens":5},"base_resp":{"status_code":0}}`)
+	})
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}}
+	if _, err := chat.Chat(context.Background(), msgs, kpllms.WithTopP(1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got["temperature"]; ok || got["top_p"] != float64(1) {
+		t.Fatalf("request = %v", got)
+	}
+	// minimax 的 temperature 取值 (0, 1]
+	if _, err := chat.Chat(context.Background(), msgs, kpllms.WithTemperature(0)); !errors.Is(err, kpllms.ErrInvalidCallOption) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestChatDefaultCallOptions(t *testing.T) {
+	var got map[string]any
+	chat := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		got = body
+		_, _ = io.WriteString(w, `{"choices":[{"finish_reason":"stop","messages":[{"sender_type":"BOT","sender_name":"靠谱智能助理","text":"你好"}]}],
+			"usage":{"total_tokens":5},"base_resp":{"status_code":0}}`)
+	}, WithDefaultCallOptions(kpllms.WithTemperature(0.1), WithWebSearch()))
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}}
+	if _, err := chat.Chat(context.Background(), msgs, kpllms.WithTemperature(0.7), WithMaskSensitiveInfo(true)); err != nil {
+		t.Fatal(err)
+	}
+	// minimax 的参数按字段合并
+	if got["temperature"] != 0.7 || got["mask_sensitive_info"] != true || len(got["plugins"].([]any)) != 1 {
+		t.Fatalf("request = %v", got)
+	}
+	if opts := chat.ResolveCallOptions(); *opts.Temperature != 0.1 || getCallOptions(opts).plugins[0] != pluginWebSearch {
+		t.Fatalf("resolved = %+v", opts)
+	}
+}
+
+func TestChatUnsupportedOptions(t *testing.T) {
+	chat := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		t.Errorf("unexpected request: %v", body)
+	})
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}}
+	for _, opt := range []kpllms.CallOption{kpllms.WithStop("。"), kpllms.WithN(2), kpllms.WithSeed(1), kpllms.WithLogProbs(0),
+		kpllms.WithChoiceStreamingFunc(func(context.Context, int, []byte) error { return nil })} {
+		if _, err := chat.Chat(context.Background(), msgs, opt); !errors.Is(err, kpllms.ErrUnsupportedCallOption) {
+			t.Fatalf("err = %v", err)
+		}
+	}
+}
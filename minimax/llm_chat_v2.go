@@ -0,0 +1,239 @@
+package minimax
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/minimax/internal/minimaxclientv2"
+	"github.com/comqositi/kpllms/schema"
+)
+
+// chatV2 使用 chatcompletion_v2 接口，消息格式和 openai 一致
+func (o *Chat) chatV2(ctx context.Context, messageSets []*schema.ChatMessage, opts kpllms.CallOptions) (*schema.ContentResponse, error) {
+	if err := checkOptionsV2(opts); err != nil {
+		return nil, err
+	}
+	msgs, err := messagesToClientMessagesV2(messageSets)
+	if err != nil {
+		return nil, err
+	}
+	maskSensitiveInfo := false
+	if co := getCallOptions(opts); co.maskSensitiveInfo != nil {
+		maskSensitiveInfo = *co.maskSensitiveInfo
+	}
+	req := &minimaxclientv2.ChatCompletionRequest{
+		Model:             opts.Model,
+		Messages:          msgs,
+		MaxTokens:         kpllms.Deref(opts.MaxTokens),
+		Temperature:       opts.Temperature,
+		TopP:              opts.TopP,
+		MaskSensitiveInfo: &maskSensitiveInfo,
+		StreamingFunc:     opts.StreamingFunc,
+		ExtraBody:         opts.ExtraBody,
+	}
+
+	if opts.JsonSchema != nil {
+		format, err := responseFormatV2(opts.JsonSchema)
+		if err != nil {
+			return nil, err
+		}
+		req.ResponseFormat = format
+	}
+
+	// v2 接口只支持 auto 和 none
+	for _, tool := range opts.Tools {
+		t, err := toolFromToolV2(tool)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert llms tool to minimax tool: %w", err)
+		}
+		req.Tools = append(req.Tools, t)
+	}
+	if len(req.Tools) > 0 && opts.ToolChoice.Type == schema.ToolChoiceTypeNone {
+		req.ToolChoice = "none"
+	}
+
+	result, err := o.clientV2.CreateChatCompletion(ctx, req)
+	if err != nil {
+		if errors.Is(err, minimaxclientv2.ErrEmptyResponse) {
+			return nil, ErrEmptyResponse
+		}
+		return nil, err
+	}
+	if result.InputSensitive {
+		return nil, fmt.Errorf("输入命中敏感词：%s", SensitiveTypeToValue(result.InputSensitiveType))
+	}
+	if result.OutputSensitive {
+		return nil, fmt.Errorf("输出命中敏感词：%s", SensitiveTypeToValue(result.OutputSensitiveType))
+	}
+
+	usage := &schema.Usage{}
+	if result.Usage != nil {
+		usage.PromptTokens = int(result.Usage.PromptTokens)
+		usage.CompletionTokens = int(result.Usage.CompletionTokens)
+		usage.TotalTokens = int(result.Usage.TotalTokens)
+	}
+	choices := make([]*schema.ContentChoice, 0, len(result.Choices))
+	for _, c := range result.Choices {
+		if c.Message == nil {
+			continue
+		}
+		content, _ := c.Message.Content.(string)
+		choice := &schema.ContentChoice{
+			Content:    content,
+			StopReason: c.FinishReason,
+			Usage:      usage,
+			GenerationInfo: map[string]any{
+				"id": result.Id,
+			},
+		}
+		for _, tc := range c.Message.ToolCalls {
+			choice.ToolCalls = append(choice.ToolCalls, &schema.ToolCall{
+				Id:   tc.Id,
+				Type: schema.ToolCallTypeFunction,
+				Function: schema.FunctionCall{
+					Name:      tc.Function.Name,
+					Arguments: tc.Function.Arguments,
+				},
+			})
+		}
+		choices = append(choices, choice)
+	}
+	if len(choices) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	return &schema.ContentResponse{Choices: choices}, nil
+}
+
+// checkOptionsV2 chatcompletion_pro 特有的参数、指定函数调用和没有 schema 的 JsonMode，v2 接口都不支持
+func checkOptionsV2(opts kpllms.CallOptions) error {
+	co := getCallOptions(opts)
+	unsupported := map[string]bool{
+		"web_search":           len(co.plugins) > 0,
+		"sample_messages":      len(co.sampleMessages) > 0,
+		"bot_settings":         len(co.botSettings) > 0,
+		"raw_glyph":            co.rawGlyph != "",
+		"json_mode":            opts.JsonMode && (opts.JsonSchema == nil || opts.JsonSchema.Schema == nil),
+		"tool_choice function": opts.ToolChoice.Type == schema.ToolChoiceTypeFunction,
+	}
+	for _, name := range []string{"web_search", "sample_messages", "bot_settings", "raw_glyph", "json_mode", "tool_choice function"} {
+		if unsupported[name] {
+			return fmt.Errorf("%w: minimax %s api does not support %s", kpllms.ErrUnsupportedCallOption, APIVersionV2, name)
+		}
+	}
+	return nil
+}
+
+// responseFormatV2 json 字符串直接作为 schema 发送
+func responseFormatV2(s *kpllms.JsonSchema) (*minimaxclientv2.ResponseFormat, error) {
+	schemaValue := s.Schema
+	switch v := s.Schema.(type) {
+	case []byte:
+		schemaValue = json.RawMessage(v)
+	case string:
+		schemaValue = json.RawMessage(v)
+	}
+	if raw, ok := schemaValue.(json.RawMessage); ok && !json.Valid(raw) {
+		return nil, fmt.Errorf("invalid json schema: %s", raw)
+	}
+	name := s.Name
+	if name == "" {
+		name = defaultJsonSchemaName
+	}
+	return &minimaxclientv2.ResponseFormat{
+		Type: "json_schema",
+		JsonSchema: &minimaxclientv2.JsonSchema{
+			Name:        name,
+			Description: s.Description,
+			Schema:      schemaValue,
+		},
+	}, nil
+}
+
+// toolFromToolV2 v2 接口的函数参数需要序列化为 json 字符串
+func toolFromToolV2(t *kpllms.Tool) (*minimaxclientv2.Tool, error) {
+	if t.Type != schema.ToolCallTypeFunction || t.Function == nil {
+		return nil, fmt.Errorf("tool type %v not supported", t.Type)
+	}
+	params, err := json.Marshal(t.Function.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	return &minimaxclientv2.Tool{
+		Type: schema.ToolCallTypeFunction,
+		Function: &minimaxclientv2.FunctionDefinition{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			Parameters:  string(params),
+		},
+	}, nil
+}
+
+func messagesToClientMessagesV2(messages []*schema.ChatMessage) ([]*minimaxclientv2.Message, error) {
+	msgs := make([]*minimaxclientv2.Message, 0, len(messages))
+	for _, mc := range messages {
+		content, err := contentV2(mc.Content)
+		if err != nil {
+			return nil, err
+		}
+		msg := &minimaxclientv2.Message{Role: mc.Role, Name: mc.Name, Content: content}
+		switch mc.Role {
+		case schema.RoleSystem, schema.RoleUser:
+		case schema.RoleAssistant:
+			for _, t := range mc.ToolCalls {
+				msg.ToolCalls = append(msg.ToolCalls, &minimaxclientv2.ToolCall{
+					Id:   t.Id,
+					Type: schema.ToolCallTypeFunction,
+					Function: minimaxclientv2.FunctionCall{
+						Name:      t.Function.Name,
+						Arguments: t.Function.Arguments,
+					},
+				})
+			}
+		case schema.RoleTool:
+			msg.ToolCallId = mc.ToolCallId
+		default:
+			return nil, fmt.Errorf("role %v not supported", mc.Role)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// contentV2 文本直接使用 string，包含图片时转为内容数组
+func contentV2(content any) (any, error) {
+	switch c := content.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return c, nil
+	case []any:
+		parts := make([]*minimaxclientv2.Content, 0, len(c))
+		for _, p := range c {
+			switch part := p.(type) {
+			case schema.TextContent:
+				parts = append(parts, &minimaxclientv2.Content{Type: schema.MultiContentText, Text: part.Text})
+			case *schema.TextContent:
+				parts = append(parts, &minimaxclientv2.Content{Type: schema.MultiContentText, Text: part.Text})
+			case schema.ImageContent:
+				parts = append(parts, imageContentV2(part.ImageUrl.Url))
+			case *schema.ImageContent:
+				parts = append(parts, imageContentV2(part.ImageUrl.Url))
+			default:
+				return nil, fmt.Errorf("content part type %T not supported", p)
+			}
+		}
+		return parts, nil
+	default:
+		return nil, fmt.Errorf("content type %T not supported", content)
+	}
+}
+
+func imageContentV2(url string) *minimaxclientv2.Content {
+	return &minimaxclientv2.Content{
+		Type:     schema.MultiContentImageUrl,
+		ImageUrl: &minimaxclientv2.ImageUrl{Url: url},
+	}
+}
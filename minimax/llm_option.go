@@ -1,6 +1,7 @@
 package minimax
 
 import (
+	"github.com/comqositi/kpllms"
 	"github.com/comqositi/kpllms/minimax/internal/minimaxclientv1"
 )
 
@@ -10,6 +11,7 @@ const (
 	baseURLEnvVarName = "OPENAI_BASE_URL"  //nolint:gosec
 
 	defaultModel          = "abab5.5-chat"
+	defaultModelV2        = "abab6.5s-chat"
 	defaultEmbeddingModel = "embo-01"
 
 	defaultSendType       = "BOT"
@@ -18,13 +20,26 @@ const (
 	defaultBotDescription = "靠谱大语言模型是一款由靠谱AI智能科技自研的，没有调用其他产品的接口的大型语言模型。靠谱AI智能科技是一家中国科技公司，一直致力于进行大模型相关的研究。"
 )
 
+// APIVersion 对话接口版本
+type APIVersion string
+
+const (
+	// APIVersionPro chatcompletion_pro 接口，使用 bot_setting 和 sender_type，默认使用
+	APIVersionPro APIVersion = "pro"
+	// APIVersionV2 chatcompletion_v2 接口，openai 格式的 messages，支持多个 tool_calls 和图片
+	APIVersionV2 APIVersion = "v2"
+)
+
 type options struct {
+	apiVersion     APIVersion
 	groupId        string
 	apiKey         string
 	baseUrl        string
 	httpClient     minimaxclientv1.Doer
 	embeddingModel string
 	model          string
+	// 每次调用的默认参数
+	defaultCallOptions []kpllms.CallOption
 }
 
 type Option func(*options)
@@ -65,6 +80,20 @@ func WithModel(value string) Option {
 	}
 }
 
+// WithAPIVersion 选择对话接口版本，默认 APIVersionPro，新模型（abab6.5、MiniMax-Text-01）建议使用 APIVersionV2
+func WithAPIVersion(value APIVersion) Option {
+	return func(o *options) {
+		o.apiVersion = value
+	}
+}
+
+// WithDefaultCallOptions 每次调用的默认参数，例如温度、联网搜索，调用时传入的参数优先，多次使用时追加
+func WithDefaultCallOptions(callOptions ...kpllms.CallOption) Option {
+	return func(o *options) {
+		o.defaultCallOptions = append(o.defaultCallOptions, callOptions...)
+	}
+}
+
 func SensitiveTypeToValue(code int64) string {
 	re := ""
 	switch code {
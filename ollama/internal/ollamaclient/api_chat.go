@@ -0,0 +1,156 @@
+package ollamaclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/comqositi/kpllms/internal/httputils"
+	"github.com/comqositi/kpllms/schema"
+)
+
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// ChatRequest /api/chat 接口请求
+type ChatRequest struct {
+	Model    string         `json:"model"`
+	Messages []*ChatMessage `json:"messages"`
+	Tools    []*Tool        `json:"tools,omitempty"`
+	// json 或 json schema
+	Format    any      `json:"format,omitempty"`
+	Options   *Options `json:"options,omitempty"`
+	Stream    bool     `json:"stream"`
+	KeepAlive string   `json:"keep_alive,omitempty"`
+
+	// 流式放回的回调函数
+	StreamingFunc func(ctx context.Context, chunk []byte, innerErr error) error `json:"-"`
+}
+
+// Options 模型参数，对应 Modelfile 中的 PARAMETER
+type Options struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	TopK             int      `json:"top_k,omitempty"`
+	NumCtx           int      `json:"num_ctx,omitempty"`
+	NumPredict       int      `json:"num_predict,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+}
+
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// base64 编码的图片，不带 data url 前缀
+	Images    []string    `json:"images,omitempty"`
+	ToolCalls []*ToolCall `json:"tool_calls,omitempty"`
+}
+
+// Tool 和 openai 的格式一致
+type Tool struct {
+	Type     string             `json:"type"`
+	Function FunctionDefinition `json:"function"`
+}
+
+type FunctionDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+// ToolCall ollama 的参数是 json 对象，没有 id
+type ToolCall struct {
+	Function FunctionCall `json:"function"`
+}
+
+type FunctionCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ChatResponse /api/chat 接口返回，流式返回时每行也是该结构，最后一行 done 为 true 并带有统计信息
+type ChatResponse struct {
+	Model      string      `json:"model"`
+	CreatedAt  string      `json:"created_at"`
+	Message    ChatMessage `json:"message"`
+	Done       bool        `json:"done"`
+	DoneReason string      `json:"done_reason"`
+	Error      string      `json:"error,omitempty"`
+
+	TotalDuration      int64 `json:"total_duration"`
+	LoadDuration       int64 `json:"load_duration"`
+	PromptEvalCount    int   `json:"prompt_eval_count"`
+	PromptEvalDuration int64 `json:"prompt_eval_duration"`
+	EvalCount          int   `json:"eval_count"`
+	EvalDuration       int64 `json:"eval_duration"`
+}
+
+// CreateChat 调用 /api/chat 接口，流式返回为 ndjson，拼接所有行
+func (c *Client) CreateChat(ctx context.Context, r *ChatRequest) (*ChatResponse, error) {
+	if r.Model == "" {
+		r.Model = c.Model
+	}
+	r.Stream = r.StreamingFunc != nil
+
+	var response ChatResponse
+	url := c.baseURL + "/api/chat"
+	if !r.Stream {
+		if err := httputils.HttpPost(ctx, url, r, c.setHeaders(), &response); err != nil {
+			return nil, convertError(err)
+		}
+	} else if err := c.stream(ctx, url, r, &response); err != nil {
+		return nil, err
+	}
+	if response.Message.Content == "" && len(response.Message.ToolCalls) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	return &response, nil
+}
+
+func (c *Client) stream(ctx context.Context, url string, r *ChatRequest, response *ChatResponse) error {
+	var content strings.Builder
+	var toolCalls []*ToolCall
+	// 回调中的错误会被 HttpStream 包装，这里记录接口返回的错误以保留类型
+	var apiErr error
+	err := httputils.HttpStream(ctx, url, r, c.setHeaders(), func(ctx context.Context, line string) error {
+		if strings.TrimSpace(line) == "" {
+			return nil
+		}
+		var chunk ChatResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return fmt.Errorf("unexpected line: %v", line)
+		}
+		// 生成过程中出错时返回 {"error":"..."}
+		if chunk.Error != "" {
+			apiErr = schema.NewHttpError(0, chunk.Error)
+			return apiErr
+		}
+		toolCalls = append(toolCalls, chunk.Message.ToolCalls...)
+		*response = chunk
+		if chunk.Message.Content == "" {
+			return nil
+		}
+		content.WriteString(chunk.Message.Content)
+		return r.StreamingFunc(ctx, []byte(chunk.Message.Content), nil)
+	})
+	if apiErr != nil {
+		return apiErr
+	}
+	if err != nil {
+		return convertError(err)
+	}
+	response.Message.Content = content.String()
+	response.Message.ToolCalls = toolCalls
+	if !response.Done {
+		return errors.New("ollama stream ended before done")
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package ollamaclient
+
+import (
+	"context"
+
+	"github.com/comqositi/kpllms/internal/httputils"
+)
+
+type embedRequest struct {
+	Model     string   `json:"model"`
+	Input     []string `json:"input"`
+	KeepAlive string   `json:"keep_alive,omitempty"`
+}
+
+type embedResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+// CreateEmbedding 调用 /api/embed 接口，一次请求处理所有文本
+func (c *Client) CreateEmbedding(ctx context.Context, texts []string, keepAlive string) ([][]float32, error) {
+	req := &embedRequest{Model: c.EmbeddingsModel, Input: texts, KeepAlive: keepAlive}
+	var resp embedResponse
+	if err := httputils.HttpPost(ctx, c.baseURL+"/api/embed", req, c.setHeaders(), &resp); err != nil {
+		return nil, convertError(err)
+	}
+	return resp.Embeddings, nil
+}
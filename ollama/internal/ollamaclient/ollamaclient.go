@@ -0,0 +1,92 @@
+package ollamaclient
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/comqositi/kpllms/schema"
+)
+
+// ollama 客户端，文档：https://github.com/ollama/ollama/blob/main/docs/api.md
+
+const (
+	defaultBaseURL        = "http://localhost:11434"
+	defaultChatModel      = "llama3.1"
+	defaultEmbeddingModel = "nomic-embed-text"
+)
+
+var ErrEmptyResponse = errors.New("empty response")
+
+// Client ollama 客户端，本地服务不需要鉴权
+type Client struct {
+	Model           string
+	EmbeddingsModel string
+	baseURL         string
+}
+
+// Option is an option for the Ollama client.
+type Option func(*Client) error
+
+// New 创建 ollama 客户端
+func New(opts ...Option) (*Client, error) {
+	c := &Client{}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.baseURL == "" {
+		c.baseURL = defaultBaseURL
+	}
+	c.baseURL = strings.TrimSuffix(c.baseURL, "/")
+	if c.Model == "" {
+		c.Model = defaultChatModel
+	}
+	if c.EmbeddingsModel == "" {
+		c.EmbeddingsModel = defaultEmbeddingModel
+	}
+	return c, nil
+}
+
+func WithModel(value string) Option {
+	return func(c *Client) error {
+		c.Model = value
+		return nil
+	}
+}
+
+func WithEmbeddingsModel(value string) Option {
+	return func(c *Client) error {
+		c.EmbeddingsModel = value
+		return nil
+	}
+}
+
+func WithBaseURL(value string) Option {
+	return func(c *Client) error {
+		c.baseURL = value
+		return nil
+	}
+}
+
+func (c *Client) setHeaders() map[string]string {
+	return map[string]string{
+		"Content-Type": "application/json",
+	}
+}
+
+// convertError ollama 出错时返回 {"error":"..."}，取出其中的错误信息
+func convertError(err error) error {
+	var httpErr *schema.HttpError
+	if !errors.As(err, &httpErr) {
+		return err
+	}
+	var e struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal([]byte(httpErr.ErrMsg), &e) == nil && e.Error != "" {
+		return schema.NewHttpError(httpErr.Code, e.Error)
+	}
+	return err
+}
@@ -0,0 +1,290 @@
+package ollama
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/ollama/internal/ollamaclient"
+	"github.com/comqositi/kpllms/schema"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmptyResponse            = errors.New("no response")
+	ErrUnexpectedResponseLength = errors.New("unexpected length of response")
+)
+
+// 远程图片下载大小上限
+const maxImageSize = 20 << 20
+
+type LLM struct {
+	client    *ollamaclient.Client
+	numCtx    int
+	keepAlive string
+	seed      *int
+}
+
+var (
+	_ kpllms.Model    = (*LLM)(nil)
+	_ kpllms.Embedder = (*LLM)(nil)
+)
+
+// New 创建 ollama 本地模型 model 和 embedder 的实现
+func New(opts ...Option) (*LLM, error) {
+	options := &options{
+		baseURL: os.Getenv(hostEnvVarName),
+		model:   os.Getenv(modelEnvVarName),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	// OLLAMA_HOST 可能不带协议，例如 0.0.0.0:11434
+	if options.baseURL != "" && !strings.Contains(options.baseURL, "://") {
+		options.baseURL = "http://" + options.baseURL
+	}
+	c, err := ollamaclient.New(
+		ollamaclient.WithBaseURL(options.baseURL),
+		ollamaclient.WithModel(options.model),
+		ollamaclient.WithEmbeddingsModel(options.embeddingModel),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &LLM{client: c, numCtx: options.numCtx, keepAlive: options.keepAlive, seed: options.seed}, nil
+}
+
+// Chat 实现大模型接口
+func (o *LLM) Chat(ctx context.Context, messages []*schema.ChatMessage, options ...kpllms.CallOption) (*schema.ContentResponse, error) {
+	opts := kpllms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	err := opts.CheckSupported("ollama", kpllms.OptionStop, kpllms.OptionFrequencyPenalty, kpllms.OptionPresencePenalty, kpllms.OptionSeed)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := messagesToClientMessages(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	// 参数范围由模型决定，本地部署不做检查，调用时的 seed 优先
+	seed := o.seed
+	if opts.Seed != nil {
+		seed = opts.Seed
+	}
+	req := &ollamaclient.ChatRequest{
+		Model:    opts.Model,
+		Messages: msgs,
+		Options: &ollamaclient.Options{
+			Temperature:      opts.Temperature,
+			TopP:             opts.TopP,
+			NumPredict:       kpllms.Deref(opts.MaxTokens),
+			NumCtx:           o.numCtx,
+			FrequencyPenalty: opts.FrequencyPenalty,
+			PresencePenalty:  opts.PresencePenalty,
+			Seed:             seed,
+			Stop:             opts.Stop,
+		},
+		KeepAlive:     o.keepAlive,
+		StreamingFunc: opts.StreamingFunc,
+	}
+	// 使用 json 格式返回
+	if opts.JsonMode {
+		req.Format = "json"
+	}
+
+	// ollama 没有 tool_choice 参数，不调用函数时不传工具
+	if opts.ToolChoice.Type != schema.ToolChoiceTypeNone {
+		for _, t := range opts.Tools {
+			if t.Type != schema.ToolCallTypeFunction || t.Function == nil {
+				return nil, fmt.Errorf("failed to convert llms tool to ollama tool: tool type %v not supported", t.Type)
+			}
+			req.Tools = append(req.Tools, &ollamaclient.Tool{
+				Type: schema.ToolCallTypeFunction,
+				Function: ollamaclient.FunctionDefinition{
+					Name:        t.Function.Name,
+					Description: t.Function.Description,
+					Parameters:  t.Function.Parameters,
+				},
+			})
+		}
+	}
+
+	result, err := o.client.CreateChat(ctx, req)
+	if err != nil {
+		if errors.Is(err, ollamaclient.ErrEmptyResponse) {
+			return nil, ErrEmptyResponse
+		}
+		return nil, err
+	}
+
+	choice := &schema.ContentChoice{
+		Content:    result.Message.Content,
+		StopReason: result.DoneReason,
+		GenerationInfo: map[string]any{
+			"model":          result.Model,
+			"total_duration": result.TotalDuration,
+			"load_duration":  result.LoadDuration,
+			"eval_duration":  result.EvalDuration,
+		},
+		Usage: &schema.Usage{
+			PromptTokens:     result.PromptEvalCount,
+			CompletionTokens: result.EvalCount,
+			TotalTokens:      result.PromptEvalCount + result.EvalCount,
+		},
+	}
+	for _, tc := range result.Message.ToolCalls {
+		// ollama 的函数调用没有 id，这里生成一个，返回结果时用于找回函数名
+		choice.ToolCalls = append(choice.ToolCalls, &schema.ToolCall{
+			Id:   "call_" + uuid.NewString(),
+			Type: schema.ToolCallTypeFunction,
+			Function: schema.FunctionCall{
+				Name:      tc.Function.Name,
+				Arguments: string(tc.Function.Arguments),
+			},
+		})
+	}
+	if len(choice.ToolCalls) > 0 {
+		choice.StopReason = "tool_calls"
+	}
+	return &schema.ContentResponse{Choices: []*schema.ContentChoice{choice}}, nil
+}
+
+func messagesToClientMessages(ctx context.Context, messages []*schema.ChatMessage) ([]*ollamaclient.ChatMessage, error) {
+	msgs := make([]*ollamaclient.ChatMessage, 0, len(messages))
+	for _, mc := range messages {
+		msg := &ollamaclient.ChatMessage{}
+		switch mc.Role {
+		case schema.RoleSystem:
+			msg.Role = ollamaclient.RoleSystem
+		case schema.RoleUser:
+			msg.Role = ollamaclient.RoleUser
+		case schema.RoleAssistant:
+			msg.Role = ollamaclient.RoleAssistant
+			for _, t := range mc.ToolCalls {
+				args := json.RawMessage(t.Function.Arguments)
+				if len(args) == 0 {
+					args = json.RawMessage("{}")
+				} else if !json.Valid(args) {
+					return nil, fmt.Errorf("tool call %s arguments is not valid json", t.Function.Name)
+				}
+				msg.ToolCalls = append(msg.ToolCalls, &ollamaclient.ToolCall{
+					Function: ollamaclient.FunctionCall{Name: t.Function.Name, Arguments: args},
+				})
+			}
+		case schema.RoleTool:
+			msg.Role = ollamaclient.RoleTool
+		default:
+			return nil, fmt.Errorf("role %v not supported", mc.Role)
+		}
+		if err := setContent(ctx, msg, mc.Content); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// setContent 文本拼接到 content 中，图片转为 base64 放到 images 中
+func setContent(ctx context.Context, msg *ollamaclient.ChatMessage, content any) error {
+	switch c := content.(type) {
+	case nil:
+		return nil
+	case string:
+		msg.Content = c
+		return nil
+	case []any:
+		var sb strings.Builder
+		for _, p := range c {
+			var url string
+			switch part := p.(type) {
+			case schema.TextContent:
+				sb.WriteString(part.Text)
+				continue
+			case *schema.TextContent:
+				sb.WriteString(part.Text)
+				continue
+			case schema.ImageContent:
+				url = part.ImageUrl.Url
+			case *schema.ImageContent:
+				url = part.ImageUrl.Url
+			default:
+				return fmt.Errorf("content part type %T not supported", p)
+			}
+			image, err := imageData(ctx, url)
+			if err != nil {
+				return err
+			}
+			msg.Images = append(msg.Images, image)
+		}
+		msg.Content = sb.String()
+		return nil
+	default:
+		return fmt.Errorf("content type %T not supported", content)
+	}
+}
+
+// imageData data:image/png;base64,xxx 去掉前缀，http 地址下载后转为 base64
+func imageData(ctx context.Context, url string) (string, error) {
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		meta, data, ok := strings.Cut(rest, ",")
+		if !ok || !strings.HasSuffix(meta, ";base64") {
+			return "", fmt.Errorf("unsupported image data url")
+		}
+		return data, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", schema.NewHttpError(0, err.Error())
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return "", schema.NewHttpError(0, err.Error())
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", schema.NewHttpError(resp.StatusCode, string(b))
+	}
+	if len(b) > maxImageSize {
+		return "", fmt.Errorf("image %s is larger than %d bytes", url, maxImageSize)
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// EmbedDocuments  实现 embedder 接口 文档存储向量：存入数据库的向量，被用于检索
+func (o *LLM) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings, err := o.client.CreateEmbedding(ctx, texts, o.keepAlive)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	if len(texts) != len(embeddings) {
+		return embeddings, ErrUnexpectedResponseLength
+	}
+	return embeddings, nil
+}
+
+// EmbedQuery  实现 embedder 接口 查询向量：对需要用于检索的文本进行想量化
+func (o *LLM) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := o.EmbedDocuments(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
@@ -0,0 +1,62 @@
+package ollama
+
+import "time"
+
+const (
+	hostEnvVarName  = "OLLAMA_HOST"  //nolint:gosec
+	modelEnvVarName = "OLLAMA_MODEL" //nolint:gosec
+)
+
+type options struct {
+	baseURL        string
+	model          string
+	embeddingModel string
+	numCtx         int
+	keepAlive      string
+	seed           *int
+}
+
+// Option is a functional option for the Ollama client.
+type Option func(*options)
+
+// WithBaseURL 设置 ollama 服务地址，未设置时读取 OLLAMA_HOST 环境变量，默认 http://localhost:11434
+func WithBaseURL(baseURL string) Option {
+	return func(o *options) {
+		o.baseURL = baseURL
+	}
+}
+
+// WithModel 设置模型，未设置时读取 OLLAMA_MODEL 环境变量，默认 llama3.1
+func WithModel(model string) Option {
+	return func(o *options) {
+		o.model = model
+	}
+}
+
+// WithEmbeddingModel 设置向量模型，默认 nomic-embed-text
+func WithEmbeddingModel(model string) Option {
+	return func(o *options) {
+		o.embeddingModel = model
+	}
+}
+
+// WithNumCtx 设置上下文窗口大小，ollama 默认只有 2048
+func WithNumCtx(numCtx int) Option {
+	return func(o *options) {
+		o.numCtx = numCtx
+	}
+}
+
+// WithKeepAlive 设置请求结束后模型在内存中保留的时间，负数表示一直保留，0 表示立即卸载
+func WithKeepAlive(keepAlive time.Duration) Option {
+	return func(o *options) {
+		o.keepAlive = keepAlive.String()
+	}
+}
+
+// WithSeed 设置随机种子，相同的种子和输入得到相同的输出
+func WithSeed(seed int) Option {
+	return func(o *options) {
+		o.seed = &seed
+	}
+}
@@ -0,0 +1,124 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+)
+
+func newTestServer(t *testing.T, handler func(path string, body map[string]any, w http.ResponseWriter), opts ...Option) *LLM {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		handler(r.URL.Path, body, w)
+	}))
+	t.Cleanup(srv.Close)
+	llm, err := New(append([]Option{WithBaseURL(srv.URL)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return llm
+}
+
+func TestChat(t *testing.T) {
+	var got map[string]any
+	llm := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		got = body
+		_, _ = io.WriteString(w, `{"model":"qwen2.5","message":{"role":"assistant","content":"",
+			"tool_calls":[{"function":{"name":"get_weather","arguments":{"city":"杭州"}}}]},
+			"done":true,"done_reason":"stop","prompt_eval_count":12,"eval_count":6}`)
+	}, WithModel("qwen2.5"), WithNumCtx(8192), WithKeepAlive(10*time.Minute), WithSeed(42))
+	tools := []*kpllms.Tool{{Type: schema.ToolCallTypeFunction, Function: &kpllms.FunctionDefinition{
+		Name: "get_weather", Parameters: map[string]any{"type": "object"},
+	}}}
+	resp, err := llm.Chat(context.Background(), []*schema.ChatMessage{
+		{Role: schema.RoleUser, Content: []any{
+			schema.TextContent{Text: "图里是哪"},
+			schema.ImageContent{ImageUrl: schema.ImageUrl{Url: "data:image/png;base64,aGVsbG8="}},
+		}},
+	}, kpllms.WithTools(tools), kpllms.WithJsonMode(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := got["options"].(map[string]any)
+	msg := got["messages"].([]any)[0].(map[string]any)
+	if got["format"] != "json" || got["keep_alive"] != "10m0s" || got["stream"] != false ||
+		options["num_ctx"] != float64(8192) || options["seed"] != float64(42) || len(got["tools"].([]any)) != 1 {
+		t.Fatalf("request = %v", got)
+	}
+	if msg["content"] != "图里是哪" || msg["images"].([]any)[0] != "aGVsbG8=" {
+		t.Fatalf("message = %v", msg)
+	}
+	c := resp.Choices[0]
+	if c.StopReason != "tool_calls" || c.Usage.TotalTokens != 18 || len(c.ToolCalls) != 1 ||
+		c.ToolCalls[0].Id == "" || c.ToolCalls[0].Function.Arguments != `{"city":"杭州"}` {
+		t.Fatalf("choice = %+v", c)
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	lines := []string{
+		`{"model":"llama3.1","message":{"role":"assistant","content":"你"},"done":false}`,
+		`{"model":"llama3.1","message":{"role":"assistant","content":"好"},"done":false}`,
+		`{"model":"llama3.1","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":5,"eval_count":2}`,
+	}
+	llm := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, l := range lines {
+			_, _ = fmt.Fprintln(w, l)
+		}
+	})
+	var streamed strings.Builder
+	resp, err := llm.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}},
+		kpllms.WithStreamingFunc(func(ctx context.Context, chunk []byte, innerErr error) error {
+			streamed.Write(chunk)
+			return nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := resp.Choices[0]
+	if streamed.String() != "你好" || c.Content != "你好" || c.StopReason != "stop" || c.Usage.TotalTokens != 7 {
+		t.Fatalf("choice = %+v", c)
+	}
+}
+
+func TestChatError(t *testing.T) {
+	llm := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error":"model \"nope\" not found, try pulling it first"}`)
+	})
+	_, err := llm.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "x"}}, kpllms.WithModel("nope"))
+	var httpErr *schema.HttpError
+	if !errors.As(err, &httpErr) || httpErr.Code != http.StatusNotFound || !strings.Contains(httpErr.ErrMsg, "try pulling") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	llm := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		if path != "/api/embed" || body["model"] != "nomic-embed-text" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, `{"model":"nomic-embed-text","embeddings":[[0.1,0.2],[0.3,0.4]]}`)
+	})
+	docs, err := llm.EmbedDocuments(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 || docs[1][1] != 0.4 {
+		t.Fatalf("docs = %v", docs)
+	}
+}
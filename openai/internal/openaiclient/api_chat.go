@@ -6,7 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"github.com/comqositi/kpllms/internal/httputils"
+	"net/http"
+	"sort"
 	"strings"
+	"time"
 )
 
 const (
@@ -21,16 +24,16 @@ type ChatRequest struct {
 	Model string `json:"model"`
 	// 上下文和用户提问
 	Messages []*ChatMessage `json:"messages"`
-	// 调整温度
-	Temperature      float64  `json:"temperature"`
-	TopP             float64  `json:"top_p,omitempty"`
+	// 调整温度，为空时使用服务端默认值，下同
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
 	MaxTokens        int      `json:"max_tokens,omitempty"`
 	N                int      `json:"n,omitempty"`
 	StopWords        []string `json:"stop,omitempty"`
 	Stream           bool     `json:"stream,omitempty"`
-	FrequencyPenalty float64  `json:"frequency_penalty,omitempty"`
-	PresencePenalty  float64  `json:"presence_penalty,omitempty"`
-	Seed             int      `json:"seed,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
 
 	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
 	LogProbs       bool            `json:"logprobs,omitempty"`
@@ -42,8 +45,16 @@ type ChatRequest struct {
 	// 指定工具调用的方式，string 或者 ToolChoice， 例如：auto，自动调用，指定调用
 	ToolChoice any `json:"tool_choice,omitempty"`
 
+	LogitBias   map[string]int `json:"logit_bias,omitempty"`
+	User        string         `json:"user,omitempty"`
+	ServiceTier string         `json:"service_tier,omitempty"`
+
 	// 流式放回的回调函数
 	StreamingFunc func(ctx context.Context, chunk []byte, innerErr error) error `json:"-"`
+	// n > 1 时按 index 流式返回所有结果的回调函数
+	ChoiceStreamingFunc func(ctx context.Context, index int, chunk []byte) error `json:"-"`
+	// 合并到请求体中的字段
+	ExtraBody map[string]any `json:"-"`
 }
 
 type ToolType string
@@ -77,7 +88,18 @@ type ToolCall struct {
 
 // ResponseFormat is the format of the response.
 type ResponseFormat struct {
+	// text、json_object 或者 json_schema
 	Type string `json:"type"`
+	// Type 为 json_schema 时必填
+	JsonSchema *ResponseFormatJSONSchema `json:"json_schema,omitempty"`
+}
+
+// ResponseFormatJSONSchema 结构化输出的 schema，文档：https://platform.openai.com/docs/guides/structured-outputs
+type ResponseFormatJSONSchema struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      any    `json:"schema"`
+	Strict      bool   `json:"strict,omitempty"`
 }
 
 // ChatMessage is a message in a chat request.
@@ -100,6 +122,12 @@ type ChatMessageResponse struct { //nolint:musttag
 	Content string `json:"content,omitempty"`
 	Name    string `json:"name,omitempty"`
 
+	// 推理模型的思考过程，deepseek 等兼容接口返回
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+
+	// 使用结构化输出时模型拒绝回答的说明
+	Refusal string `json:"refusal,omitempty"`
+
 	// 函数列表
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 
@@ -135,6 +163,25 @@ type ChatCompletionChoice struct {
 	Index        int                 `json:"index"`
 	Message      ChatMessageResponse `json:"message"`
 	FinishReason FinishReason        `json:"finish_reason"`
+	LogProbs     *LogProbs           `json:"logprobs,omitempty"`
+}
+
+// LogProbs 开启 logprobs 时返回每个 token 的对数概率
+type LogProbs struct {
+	Content []*TokenLogProb `json:"content"`
+}
+
+type TokenLogProb struct {
+	Token       string        `json:"token"`
+	LogProb     float64       `json:"logprob"`
+	Bytes       []int         `json:"bytes,omitempty"`
+	TopLogProbs []*TopLogProb `json:"top_logprobs,omitempty"`
+}
+
+type TopLogProb struct {
+	Token   string  `json:"token"`
+	LogProb float64 `json:"logprob"`
+	Bytes   []int   `json:"bytes,omitempty"`
 }
 
 // ChatUsage is the usage of a chat completion request.
@@ -153,23 +200,34 @@ type ChatCompletionResponse struct {
 	Object            string                  `json:"object,omitempty"`
 	Usage             ChatUsage               `json:"usage,omitempty"`
 	SystemFingerprint string                  `json:"system_fingerprint"`
+
+	// 以下字段不在接口返回的 json 中
+	Header           http.Header   `json:"-"`
+	TimeToFirstToken time.Duration `json:"-"`
+	Latency          time.Duration `json:"-"`
 }
 
 // StreamedChatResponsePayload is a chunk from the stream.
 type StreamedChatResponsePayload struct {
-	ID      string  `json:"id,omitempty"`
-	Created float64 `json:"created,omitempty"`
-	Model   string  `json:"model,omitempty"`
-	Object  string  `json:"object,omitempty"`
-	Choices []struct {
-		Index float64 `json:"index,omitempty"`
+	ID                string  `json:"id,omitempty"`
+	Created           float64 `json:"created,omitempty"`
+	Model             string  `json:"model,omitempty"`
+	Object            string  `json:"object,omitempty"`
+	SystemFingerprint string  `json:"system_fingerprint,omitempty"`
+	Choices           []struct {
+		Index int `json:"index,omitempty"`
 		Delta struct {
-			Role      string     `json:"role,omitempty"`
-			Content   string     `json:"content,omitempty"`
-			ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+			Role             string     `json:"role,omitempty"`
+			Content          string     `json:"content,omitempty"`
+			ReasoningContent string     `json:"reasoning_content,omitempty"`
+			Refusal          string     `json:"refusal,omitempty"`
+			ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
 		} `json:"delta,omitempty"`
 		FinishReason FinishReason `json:"finish_reason,omitempty"`
+		LogProbs     *LogProbs    `json:"logprobs,omitempty"`
 	} `json:"choices,omitempty"`
+	// 开启 stream_options.include_usage 或者部分兼容接口会在最后返回 token 消耗
+	Usage *ChatUsage `json:"usage,omitempty"`
 }
 
 // FunctionDefinition is a definition of a function that can be called by the model.
@@ -180,6 +238,8 @@ type FunctionDefinition struct {
 	Description string `json:"description,omitempty"`
 	// Parameters is a list of parameters for the function.
 	Parameters any `json:"parameters"`
+	// Strict 严格按照 Parameters 生成参数
+	Strict bool `json:"strict,omitempty"`
 }
 
 // FunctionCallBehavior is the behavior to use when calling functions.
@@ -203,18 +263,25 @@ type FunctionCall struct {
 }
 
 func (c *Client) createChat(ctx context.Context, payload *ChatRequest) (*ChatCompletionResponse, error) {
-	if payload.StreamingFunc != nil {
+	stream := payload.StreamingFunc != nil || payload.ChoiceStreamingFunc != nil
+	if stream {
 		payload.Stream = true
 	}
+	body, err := httputils.MergeBody(payload, payload.ExtraBody)
+	if err != nil {
+		return nil, err
+	}
 	var response ChatCompletionResponse
+	ctx = httputils.WithResponseHeader(ctx, &response.Header)
+	start := time.Now()
 	// 处理流式返回
-	if payload.StreamingFunc != nil {
+	if stream {
 		// 流式返回初始化一下， 避免赋值时报空指针
 		response.Choices = []*ChatCompletionChoice{
 			{},
 		}
-		err := httputils.HttpStream(ctx, c.buildURL("/chat/completions", c.Model), payload, c.setHeaders(), func(ctx context.Context, line string) error {
-			//fmt.Println(line)
+		var usage *ChatUsage
+		err := httputils.HttpStream(ctx, c.buildURL("/chat/completions", c.Model), body, c.setHeaders(), func(ctx context.Context, line string) error {
 			// func 内会返回流式返回的每行数据，对每行数据逐行处理
 			if line == "" {
 				// 空行不处理，空行是数据间隔行
@@ -234,60 +301,119 @@ func (c *Client) createChat(ctx context.Context, payload *ChatRequest) (*ChatCom
 			if err != nil {
 				return err
 			}
-			if len(streamResponse.Choices) == 0 {
-				return nil
+			if response.ID == "" {
+				response.ID = streamResponse.ID
+				response.Created = int64(streamResponse.Created)
+				response.Model = streamResponse.Model
+				response.SystemFingerprint = streamResponse.SystemFingerprint
+			}
+			if streamResponse.Usage != nil {
+				usage = streamResponse.Usage
 			}
-			// 写入最后一个结束标识
-			response.Choices[0].FinishReason = streamResponse.Choices[0].FinishReason
-			// 如果是非函数调用
-			if streamResponse.Choices[0].Delta.ToolCalls == nil {
+			// n > 1 时按 index 拼接每个结果，ChoiceStreamingFunc 回调所有结果，StreamingFunc 只回调第一个结果
+			for _, delta := range streamResponse.Choices {
+				choice := streamChoice(&response, delta.Index)
+				if delta.FinishReason != "" {
+					choice.FinishReason = delta.FinishReason
+				}
+				// 推理过程只拼接，不通过 StreamingFunc 返回
+				choice.Message.ReasoningContent += delta.Delta.ReasoningContent
+				choice.Message.Refusal += delta.Delta.Refusal
+				if delta.LogProbs != nil {
+					if choice.LogProbs == nil {
+						choice.LogProbs = &LogProbs{}
+					}
+					choice.LogProbs.Content = append(choice.LogProbs.Content, delta.LogProbs.Content...)
+				}
+				// openai 有并行返回函数的功能，函数调用无需 stream 流式返回，避免输出错误
+				for _, tc := range delta.Delta.ToolCalls {
+					mergeToolCall(&choice.Message, tc)
+				}
+				if response.TimeToFirstToken == 0 && (delta.Delta.Content != "" || delta.Delta.ReasoningContent != "" || delta.Delta.Refusal != "" || len(delta.Delta.ToolCalls) > 0) {
+					response.TimeToFirstToken = time.Since(start)
+				}
 				// 空文本不传
-				if streamResponse.Choices[0].Delta.Content == "" {
-					return nil
+				if delta.Delta.Content == "" {
+					continue
+				}
+				choice.Message.Content += delta.Delta.Content
+				if payload.ChoiceStreamingFunc != nil {
+					if err := payload.ChoiceStreamingFunc(ctx, delta.Index, []byte(delta.Delta.Content)); err != nil {
+						return err
+					}
+				}
+				if payload.StreamingFunc == nil || delta.Index != 0 {
+					continue
+				}
+				if err := payload.StreamingFunc(ctx, []byte(delta.Delta.Content), nil); err != nil {
+					return err
 				}
-				// 非函数调用
-				chunk := []byte(streamResponse.Choices[0].Delta.Content)
-				// 拼接所有内容
-				response.Choices[0].Message.Content += streamResponse.Choices[0].Delta.Content
-				// 调用用户 func
-				return payload.StreamingFunc(ctx, chunk, nil)
-			}
-
-			// 如果是函数调用， 遇到 type=function 加入一个函数,  openai有并行返回函数的功能
-			// 返回第几个函数
-			toolCallIndex := streamResponse.Choices[0].Delta.ToolCalls[0].Index
-			if streamResponse.Choices[0].Delta.ToolCalls[0].Type == "function" {
-				response.Choices[0].Message.ToolCalls = append(response.Choices[0].Message.ToolCalls, ToolCall{})
-				response.Choices[0].Message.ToolCalls[toolCallIndex].Index = toolCallIndex
-				response.Choices[0].Message.ToolCalls[toolCallIndex].ID = streamResponse.Choices[0].Delta.ToolCalls[0].ID
-				response.Choices[0].Message.ToolCalls[toolCallIndex].Type = streamResponse.Choices[0].Delta.ToolCalls[0].Type
-				response.Choices[0].Message.ToolCalls[toolCallIndex].Function.Name = streamResponse.Choices[0].Delta.ToolCalls[0].Function.Name
-				//fmt.Println(len(response.Choices[0].Message.ToolCalls))
 			}
-			response.Choices[0].Message.ToolCalls[toolCallIndex].Function.Arguments += streamResponse.Choices[0].Delta.ToolCalls[0].Function.Arguments
-
-			// 如果是 function则无需 stream 流式返回，避免输出错误
-
 			return nil
 		})
 		if err != nil {
 			return nil, err
 		}
-		//  openai stream 模式没有返回消耗的 token，此处自己计算
-		PromptTokens := NumTokensFromMessages(payload.Messages, c.Model)
-		CompletionTokens := CountTokens(c.Model, response.Choices[0].Message.Content)
-		response.Usage = ChatUsage{
-			PromptTokens:     PromptTokens,
-			CompletionTokens: CompletionTokens,
-			TotalTokens:      PromptTokens + CompletionTokens,
+		sort.Slice(response.Choices, func(i, j int) bool {
+			return response.Choices[i].Index < response.Choices[j].Index
+		})
+		if usage != nil {
+			response.Usage = *usage
+		} else {
+			// 接口没有返回消耗的 token 时自己估算
+			PromptTokens := NumTokensFromMessages(payload.Messages, c.Model)
+			CompletionTokens := 0
+			for _, choice := range response.Choices {
+				CompletionTokens += CountTokens(c.Model, choice.Message.Content)
+			}
+			response.Usage = ChatUsage{
+				PromptTokens:     PromptTokens,
+				CompletionTokens: CompletionTokens,
+				TotalTokens:      PromptTokens + CompletionTokens,
+			}
 		}
 
 	} else {
 		// 处理非流式返回
-		err := httputils.HttpPost(ctx, c.buildURL("/chat/completions", c.Model), payload, c.setHeaders(), &response)
+		err := httputils.HttpPost(ctx, c.buildURL("/chat/completions", c.Model), body, c.setHeaders(), &response)
 		if err != nil {
 			return nil, err
 		}
 	}
+	response.Latency = time.Since(start)
 	return &response, nil
 }
+
+// streamChoice 返回 index 对应的结果，不存在时创建
+func streamChoice(response *ChatCompletionResponse, index int) *ChatCompletionChoice {
+	for _, c := range response.Choices {
+		if c.Index == index {
+			return c
+		}
+	}
+	c := &ChatCompletionChoice{Index: index}
+	response.Choices = append(response.Choices, c)
+	return c
+}
+
+// mergeToolCall 按 index 拼接流式返回的函数调用，同一个 index 返回了新的 id 时认为是新的函数调用
+func mergeToolCall(msg *ChatMessageResponse, delta ToolCall) {
+	for i := range msg.ToolCalls {
+		tc := &msg.ToolCalls[i]
+		if tc.Index != delta.Index || (delta.ID != "" && tc.ID != "" && delta.ID != tc.ID) {
+			continue
+		}
+		if delta.ID != "" {
+			tc.ID = delta.ID
+		}
+		if delta.Type != "" {
+			tc.Type = delta.Type
+		}
+		if delta.Function.Name != "" {
+			tc.Function.Name = delta.Function.Name
+		}
+		tc.Function.Arguments += delta.Function.Arguments
+		return
+	}
+	msg.ToolCalls = append(msg.ToolCalls, delta)
+}
@@ -10,14 +10,14 @@ import (
 type CompletionRequest struct {
 	Model            string   `json:"model"`
 	Prompt           string   `json:"prompt"`
-	Temperature      float64  `json:"temperature"`
+	Temperature      *float64 `json:"temperature,omitempty"`
 	MaxTokens        int      `json:"max_tokens,omitempty"`
 	N                int      `json:"n,omitempty"`
-	FrequencyPenalty float64  `json:"frequency_penalty,omitempty"`
-	PresencePenalty  float64  `json:"presence_penalty,omitempty"`
-	TopP             float64  `json:"top_p,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
 	StopWords        []string `json:"stop,omitempty"`
-	Seed             int      `json:"seed,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
 
 	// StreamingFunc is a function to be called for each chunk of a streaming response.
 	// Return an error to stop streaming early.
@@ -5,6 +5,7 @@ import (
 	"log"
 	"strings"
 
+	"github.com/comqositi/kpllms/internal/tokenutils"
 	"github.com/pkoukk/tiktoken-go"
 )
 
@@ -54,15 +55,7 @@ func GetModelContextSize(model string) int {
 
 // CountTokens gets the number of tokens the text contains.
 func CountTokens(model, text string) int {
-	e, err := tiktoken.EncodingForModel(model)
-	if err != nil {
-		e, err = tiktoken.GetEncoding("gpt2")
-		if err != nil {
-			log.Printf("[WARN] Failed to calculate number of tokens for model, falling back to approximate count")
-			return 0
-		}
-	}
-	return len(e.Encode(text, nil, nil))
+	return tokenutils.CountTokens(model, text)
 }
 
 // CalculateMaxTokens calculates the max number of tokens that could be added to a text.
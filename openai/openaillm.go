@@ -2,18 +2,25 @@ package openai
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/comqositi/kpllms"
 	"github.com/comqositi/kpllms/schema"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/comqositi/kpllms/openai/internal/openaiclient"
 )
 
 type LLM struct {
 	client *openaiclient.Client
+	// 兼容 openai 接口的厂商预设，用于去掉不支持的字段
+	preset *Preset
+	// 每次调用的默认参数
+	defaultOptions []kpllms.CallOption
 }
 
 const (
@@ -21,26 +28,38 @@ const (
 	OpenaiRoleAssistant = "assistant"
 	OpenaiRoleUser      = "user"
 	OpenaiRoleTool      = "tool"
+
+	// 剩余额度响应头的前缀，例如 x-ratelimit-remaining-requests
+	rateLimitRemainingHeader = "x-ratelimit-remaining-"
+
+	responseFormatJSONSchema = "json_schema"
+	// 结构化输出必须有名称，没有设置时使用的默认名称
+	defaultJsonSchemaName = "response"
 )
 
 var (
 	_                             kpllms.Model = (*LLM)(nil)
 	ErrEmptyResponse                           = errors.New("no response")
-	ErrMissingToken                            = errors.New("missing the OpenAI API key, set it in the OPENAI_API_KEY environment variable") //nolint:lll
+	ErrMissingToken                            = errors.New("missing API key")
 	ErrMissingAzureModel                       = errors.New("model needs to be provided when using Azure API")
 	ErrMissingAzureEmbeddingModel              = errors.New("embeddings model needs to be provided when using Azure API")
 
 	ErrUnexpectedResponseLength = errors.New("unexpected length of response")
+	ErrEmbeddingsNotSupported   = errors.New("embeddings are not supported by this preset")
 )
 
 // New 创建大模型 model 的实现
 func New(opts ...Option) (*LLM, error) {
-	_, c, err := newClient(opts...)
+	options, c, err := newClient(opts...)
+	if errors.Is(err, ErrMissingToken) {
+		return nil, fmt.Errorf("%w: set the OpenAI API key in the %s environment variable", ErrMissingToken, tokenEnvVarName)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &LLM{
-		client: c,
+		client:         c,
+		defaultOptions: options.callOptionsOf(),
 	}, err
 }
 
@@ -89,12 +108,24 @@ func getEnvs(keys ...string) string {
 	return ""
 }
 
+// samplingRanges openai 支持的采样参数范围
+var samplingRanges = kpllms.SamplingRanges{
+	Temperature: kpllms.Range{Min: 0, Max: 2},
+	TopP:        kpllms.Range{Min: 0, Max: 1},
+	Penalty:     kpllms.Range{Min: -2, Max: 2},
+}
+
 // Chat 实现大模型接口
 func (o *LLM) Chat(ctx context.Context, messages []*schema.ChatMessage, options ...kpllms.CallOption) (*schema.ContentResponse, error) {
 
-	opts := kpllms.CallOptions{}
-	for _, opt := range options {
-		opt(&opts)
+	opts := o.ResolveCallOptions(options...)
+	if err := samplingRanges.Validate(&opts); err != nil {
+		return nil, err
+	}
+	err := opts.CheckSupported("openai", kpllms.OptionStop, kpllms.OptionN, kpllms.OptionFrequencyPenalty,
+		kpllms.OptionPresencePenalty, kpllms.OptionSeed, kpllms.OptionUser, kpllms.OptionLogProbs, kpllms.OptionChoiceStreaming)
+	if err != nil {
+		return nil, err
 	}
 
 	chatMsgs := make([]*openaiclient.ChatMessage, 0, len(messages))
@@ -142,17 +173,37 @@ func (o *LLM) Chat(ctx context.Context, messages []*schema.ChatMessage, options
 	}
 
 	req := &openaiclient.ChatRequest{
-		Model:         opts.Model,
-		Messages:      chatMsgs,
-		StreamingFunc: opts.StreamingFunc,
-		Temperature:   opts.Temperature,
-		MaxTokens:     opts.MaxTokens,
-		TopP:          opts.TopP,
+		Model:               opts.Model,
+		Messages:            chatMsgs,
+		StreamingFunc:       opts.StreamingFunc,
+		ChoiceStreamingFunc: opts.ChoiceStreamingFunc,
+		Temperature:         opts.Temperature,
+		MaxTokens:           kpllms.Deref(opts.MaxTokens),
+		TopP:                opts.TopP,
+		FrequencyPenalty:    opts.FrequencyPenalty,
+		PresencePenalty:     opts.PresencePenalty,
+		Seed:                opts.Seed,
+		StopWords:           opts.Stop,
+		N:                   opts.N,
+		User:                opts.User,
+		LogProbs:            opts.LogProbs || opts.TopLogProbs > 0,
+		TopLogProbs:         opts.TopLogProbs,
+		ExtraBody:           opts.ExtraBody,
 	}
+	co := getCallOptions(opts)
+	req.LogitBias = co.logitBias
+	req.ServiceTier = co.serviceTier
 
-	// 使用 json 格式返回
+	// 使用 json 格式返回，指定 schema 时使用结构化输出
 	if opts.JsonMode {
 		req.ResponseFormat = ResponseFormatJSON
+		if opts.JsonSchema != nil && opts.JsonSchema.Schema != nil {
+			format, err := responseFormatOf(opts.JsonSchema)
+			if err != nil {
+				return nil, err
+			}
+			req.ResponseFormat = format
+		}
 	}
 
 	// 组装工具
@@ -177,6 +228,10 @@ func (o *LLM) Chat(ctx context.Context, messages []*schema.ChatMessage, options
 		req.ToolChoice = "none"
 	}
 
+	if o.preset != nil {
+		o.preset.adaptRequest(req)
+	}
+
 	result, err := o.client.CreateChat(ctx, req)
 	if err != nil {
 		return nil, err
@@ -189,16 +244,32 @@ func (o *LLM) Chat(ctx context.Context, messages []*schema.ChatMessage, options
 	for i, c := range result.Choices {
 		choices = append(choices, &schema.ContentChoice{
 
-			Content:    c.Message.Content,
-			StopReason: fmt.Sprint(c.FinishReason),
-			Usage: &schema.Usage{
+			Content:          c.Message.Content,
+			ReasoningContent: c.Message.ReasoningContent,
+			StopReason:       fmt.Sprint(c.FinishReason),
+			GenerationInfo: map[string]any{
+				"id":                 result.ID,
+				"model":              result.Model,
+				"system_fingerprint": result.SystemFingerprint,
+			},
+		})
+		// 一次请求的 token 消耗只放在第一个结果中，避免 n > 1 时重复累加
+		if i == 0 {
+			choices[i].Usage = &schema.Usage{
 				PromptTokens:     result.Usage.PromptTokens,
 				CompletionTokens: result.Usage.CompletionTokens,
 				TotalTokens:      result.Usage.TotalTokens,
-			},
-		})
+			}
+		}
 
-		if c.FinishReason == "tool_calls" {
+		if c.Message.Refusal != "" {
+			choices[i].Refusal = &schema.Refusal{Message: c.Message.Refusal}
+		}
+		if c.LogProbs != nil {
+			choices[i].LogProbs = logProbsOf(c.LogProbs.Content)
+		}
+		// 部分兼容接口返回函数调用时 finish_reason 不是 tool_calls
+		if len(c.Message.ToolCalls) > 0 {
 			for _, tool := range c.Message.ToolCalls {
 				choices[i].ToolCalls = append(choices[i].ToolCalls, &schema.ToolCall{
 					Id:   tool.ID,
@@ -212,12 +283,83 @@ func (o *LLM) Chat(ctx context.Context, messages []*schema.ChatMessage, options
 
 		}
 	}
-	response := &schema.ContentResponse{Choices: choices}
+	response := &schema.ContentResponse{Choices: choices, Metadata: metadataOf(result)}
 
 	return response, nil
 
 }
 
+// ResolveCallOptions 返回合并默认参数后实际使用的调用参数，用于调试
+func (o *LLM) ResolveCallOptions(options ...kpllms.CallOption) kpllms.CallOptions {
+	return kpllms.ResolveCallOptions(o.defaultOptions, options...)
+}
+
+// logProbsOf 转换为通用的 token 概率
+func logProbsOf(content []*openaiclient.TokenLogProb) []*schema.TokenLogProb {
+	logProbs := make([]*schema.TokenLogProb, 0, len(content))
+	for _, c := range content {
+		lp := &schema.TokenLogProb{Token: c.Token, LogProb: c.LogProb, Bytes: c.Bytes}
+		for _, top := range c.TopLogProbs {
+			lp.TopLogProbs = append(lp.TopLogProbs, &schema.TopLogProb{Token: top.Token, LogProb: top.LogProb, Bytes: top.Bytes})
+		}
+		logProbs = append(logProbs, lp)
+	}
+	return logProbs
+}
+
+// metadataOf 从返回结果和响应头中读取元信息
+func metadataOf(result *openaiclient.ChatCompletionResponse) *schema.ResponseMetadata {
+	m := &schema.ResponseMetadata{
+		ID:                result.ID,
+		RequestID:         result.Header.Get("x-request-id"),
+		Model:             result.Model,
+		Created:           result.Created,
+		SystemFingerprint: result.SystemFingerprint,
+		TimeToFirstToken:  result.TimeToFirstToken,
+		Latency:           result.Latency,
+	}
+	for k, v := range result.Header {
+		name := strings.TrimPrefix(strings.ToLower(k), rateLimitRemainingHeader)
+		if len(name) == len(k) || len(v) == 0 {
+			continue
+		}
+		if n, err := strconv.Atoi(v[0]); err == nil {
+			if m.RateLimitRemaining == nil {
+				m.RateLimitRemaining = make(map[string]int)
+			}
+			m.RateLimitRemaining[name] = n
+		}
+	}
+	// 流式返回时按照第一个 token 之后的耗时计算生成速度
+	if generating := result.Latency - result.TimeToFirstToken; result.TimeToFirstToken > 0 && generating > 0 {
+		m.TokensPerSecond = float64(result.Usage.CompletionTokens) / generating.Seconds()
+	}
+	return m
+}
+
+// responseFormatOf json 字符串校验后直接作为 schema 发送，保留属性的顺序
+func responseFormatOf(s *kpllms.JsonSchema) (*ResponseFormat, error) {
+	format := &openaiclient.ResponseFormatJSONSchema{
+		Name:        s.Name,
+		Description: s.Description,
+		Schema:      s.Schema,
+		Strict:      s.Strict,
+	}
+	if format.Name == "" {
+		format.Name = defaultJsonSchemaName
+	}
+	switch v := s.Schema.(type) {
+	case []byte:
+		format.Schema = json.RawMessage(v)
+	case string:
+		format.Schema = json.RawMessage(v)
+	}
+	if raw, ok := format.Schema.(json.RawMessage); ok && !json.Valid(raw) {
+		return nil, fmt.Errorf("invalid json schema %q: not valid json", format.Name)
+	}
+	return &ResponseFormat{Type: responseFormatJSONSchema, JsonSchema: format}, nil
+}
+
 func toolFromTool(t *kpllms.Tool) (openaiclient.Tool, error) {
 	tool := openaiclient.Tool{
 		Type: openaiclient.ToolType(t.Type),
@@ -228,6 +370,7 @@ func toolFromTool(t *kpllms.Tool) (openaiclient.Tool, error) {
 			Name:        t.Function.Name,
 			Description: t.Function.Description,
 			Parameters:  t.Function.Parameters,
+			Strict:      t.Function.Strict,
 		}
 	default:
 		return openaiclient.Tool{}, fmt.Errorf("tool type %v not supported", t.Type)
@@ -237,6 +380,9 @@ func toolFromTool(t *kpllms.Tool) (openaiclient.Tool, error) {
 
 // EmbedDocuments  实现 embedder 接口 文档存储向量：存入数据库的向量，被用于检索
 func (o *LLM) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	if o.preset != nil && !o.preset.Embeddings {
+		return nil, ErrEmbeddingsNotSupported
+	}
 	embeddings, err := o.client.CreateEmbedding(ctx, &openaiclient.EmbeddingRequest{
 		Input: texts,
 		Model: o.client.EmbeddingsModel,
@@ -255,16 +401,10 @@ func (o *LLM) EmbedDocuments(ctx context.Context, texts []string) ([][]float32,
 
 // EmbedQuery  实现 embedder 接口 查询向量：对需要用于检索的文本进行想量化
 func (o *LLM) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
-	embeddings, err := o.client.CreateEmbedding(ctx, &openaiclient.EmbeddingRequest{
-		Input: []string{text},
-		Model: o.client.Model,
-	})
+	embeddings, err := o.EmbedDocuments(ctx, []string{text})
 	if err != nil {
 		return nil, err
 	}
-	if len(embeddings) == 0 {
-		return nil, ErrEmptyResponse
-	}
 	return embeddings[0], nil
 
 }
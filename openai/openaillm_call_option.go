@@ -0,0 +1,58 @@
+package openai
+
+import "github.com/comqositi/kpllms"
+
+// openai 特有的调用参数，通过 kpllms.CallOptions.ProviderOptions 传递
+
+type callOptionsKey struct{}
+
+type callOptions struct {
+	logitBias   map[string]int
+	serviceTier string
+}
+
+func withCallOption(f func(*callOptions)) kpllms.CallOption {
+	return func(o *kpllms.CallOptions) {
+		if o.ProviderOptions == nil {
+			o.ProviderOptions = make(map[any]any)
+		}
+		co, _ := o.ProviderOptions[callOptionsKey{}].(*callOptions)
+		if co == nil {
+			co = &callOptions{}
+			o.ProviderOptions[callOptionsKey{}] = co
+		}
+		f(co)
+	}
+}
+
+// getCallOptions 没有设置时返回空的参数
+func getCallOptions(opts kpllms.CallOptions) *callOptions {
+	if co, ok := opts.ProviderOptions[callOptionsKey{}].(*callOptions); ok {
+		return co
+	}
+	return &callOptions{}
+}
+
+// WithSeed 相同的 seed 和参数尽量返回相同的结果，等同于 kpllms.WithSeed
+func WithSeed(seed int) kpllms.CallOption {
+	return kpllms.WithSeed(seed)
+}
+
+// WithLogitBias 调整 token 出现的概率，key 为 token id，value 取值 -100 到 100
+func WithLogitBias(logitBias map[string]int) kpllms.CallOption {
+	return withCallOption(func(o *callOptions) {
+		o.logitBias = logitBias
+	})
+}
+
+// WithUser 终端用户的唯一标识，用于 openai 监控滥用，等同于 kpllms.WithUser
+func WithUser(user string) kpllms.CallOption {
+	return kpllms.WithUser(user)
+}
+
+// WithServiceTier 服务等级，auto 或 default
+func WithServiceTier(serviceTier string) kpllms.CallOption {
+	return withCallOption(func(o *callOptions) {
+		o.serviceTier = serviceTier
+	})
+}
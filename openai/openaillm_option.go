@@ -1,6 +1,7 @@
 package openai
 
 import (
+	"github.com/comqositi/kpllms"
 	"github.com/comqositi/kpllms/openai/internal/openaiclient"
 )
 
@@ -33,6 +34,8 @@ type options struct {
 	httpClient   openaiclient.Doer
 
 	responseFormat *ResponseFormat
+	// 每次调用的默认参数
+	defaultCallOptions []kpllms.CallOption
 
 	// required when APIType is APITypeAzure or APITypeAzureAD
 	apiVersion     string
@@ -45,6 +48,9 @@ type Option func(*options)
 // ResponseFormat is the response format for the OpenAI client.
 type ResponseFormat = openaiclient.ResponseFormat
 
+// ResponseFormatJSONSchema 结构化输出的 schema，调用时推荐使用 kpllms.WithJsonSchema
+type ResponseFormatJSONSchema = openaiclient.ResponseFormatJSONSchema
+
 // ResponseFormatJSON is the JSON response format.
 var ResponseFormatJSON = &ResponseFormat{Type: "json_object"} //nolint:gochecknoglobals
 
@@ -114,8 +120,35 @@ func WithHTTPClient(client openaiclient.Doer) Option {
 }
 
 // WithResponseFormat allows setting a custom response format.
+// json_object 等同于默认开启 JsonMode，json_schema 等同于默认的 kpllms.WithJsonSchema，
+// 调用时可以通过 kpllms.WithJsonMode(false) 关闭
 func WithResponseFormat(responseFormat *ResponseFormat) Option {
 	return func(opts *options) {
 		opts.responseFormat = responseFormat
 	}
 }
+
+// WithDefaultCallOptions 每次调用的默认参数，例如模型、温度，调用时传入的参数优先，多次使用时追加
+func WithDefaultCallOptions(callOptions ...kpllms.CallOption) Option {
+	return func(opts *options) {
+		opts.defaultCallOptions = append(opts.defaultCallOptions, callOptions...)
+	}
+}
+
+// callOptionsOf 客户端的默认调用参数，response format 放在最前面，可以被其他默认参数覆盖
+func (o *options) callOptionsOf() []kpllms.CallOption {
+	defaults := make([]kpllms.CallOption, 0, len(o.defaultCallOptions)+1)
+	switch f := o.responseFormat; {
+	case f == nil:
+	case f.Type == ResponseFormatJSON.Type:
+		defaults = append(defaults, kpllms.WithJsonMode(true))
+	case f.Type == responseFormatJSONSchema && f.JsonSchema != nil:
+		defaults = append(defaults, kpllms.WithJsonSchema(&kpllms.JsonSchema{
+			Name:        f.JsonSchema.Name,
+			Description: f.JsonSchema.Description,
+			Schema:      f.JsonSchema.Schema,
+			Strict:      f.JsonSchema.Strict,
+		}))
+	}
+	return append(defaults, o.defaultCallOptions...)
+}
@@ -0,0 +1,344 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+)
+
+func TestChatProviderOptions(t *testing.T) {
+	var got map[string]any
+	url := newPresetServer(t, func(r *http.Request, body map[string]any, w http.ResponseWriter) {
+		got = body
+		_, _ = io.WriteString(w, `{"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`)
+	})
+	llm, err := New(WithToken("sk"), WithBaseURL(url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "hi"}}
+	_, err = llm.Chat(context.Background(), msgs,
+		WithSeed(42), WithUser("u1"), WithServiceTier("auto"), WithLogitBias(map[string]int{"50256": -100}),
+		// 其他厂商的参数被忽略
+		kpllms.WithProviderOption("minimax", "x"),
+		kpllms.WithExtraBody(map[string]any{"metadata": map[string]any{"k": "v"}, "temperature": nil}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["seed"] != float64(42) || got["user"] != "u1" || got["service_tier"] != "auto" || got["logit_bias"].(map[string]any)["50256"] != float64(-100) {
+		t.Fatalf("request = %v", got)
+	}
+	if _, ok := got["temperature"]; ok || got["metadata"].(map[string]any)["k"] != "v" {
+		t.Fatalf("extra body not merged: %v", got)
+	}
+}
+
+func TestChatSamplingOptions(t *testing.T) {
+	var got map[string]any
+	url := newPresetServer(t, func(r *http.Request, body map[string]any, w http.ResponseWriter) {
+		got = body
+		_, _ = io.WriteString(w, `{"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`)
+	})
+	llm, err := New(WithToken("sk"), WithBaseURL(url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "hi"}}
+
+	// 未设置的参数不发送
+	if _, err = llm.Chat(context.Background(), msgs); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"temperature", "top_p", "max_tokens", "frequency_penalty", "presence_penalty", "seed"} {
+		if _, ok := got[k]; ok {
+			t.Fatalf("%s should not be sent: %v", k, got)
+		}
+	}
+
+	// 显式设置为 0 时发送
+	_, err = llm.Chat(context.Background(), msgs, kpllms.WithTemperature(0), kpllms.WithTopP(0),
+		kpllms.WithFrequencyPenalty(0), kpllms.WithPresencePenalty(0), kpllms.WithSeed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"temperature", "top_p", "frequency_penalty", "presence_penalty", "seed"} {
+		if got[k] != float64(0) {
+			t.Fatalf("%s should be 0: %v", k, got)
+		}
+	}
+
+	for _, opt := range []kpllms.CallOption{kpllms.WithTemperature(2.5), kpllms.WithTopP(-0.1), kpllms.WithPresencePenalty(3), kpllms.WithMaxTokens(0)} {
+		if _, err = llm.Chat(context.Background(), msgs, opt); !errors.Is(err, kpllms.ErrInvalidCallOption) {
+			t.Fatalf("err = %v", err)
+		}
+	}
+}
+
+func TestChatDefaultCallOptions(t *testing.T) {
+	var got map[string]any
+	url := newPresetServer(t, func(r *http.Request, body map[string]any, w http.ResponseWriter) {
+		got = body
+		_, _ = io.WriteString(w, `{"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"{}"}}]}`)
+	})
+	llm, err := New(WithToken("sk"), WithBaseURL(url), WithResponseFormat(ResponseFormatJSON),
+		WithDefaultCallOptions(kpllms.WithModel("gpt-4o-mini"), kpllms.WithTemperature(0.2), kpllms.WithMaxTokens(100),
+			kpllms.WithExtraBody(map[string]any{"store": true})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "hi"}}
+	if _, err = llm.Chat(context.Background(), msgs); err != nil {
+		t.Fatal(err)
+	}
+	if got["model"] != "gpt-4o-mini" || got["temperature"] != 0.2 || got["max_tokens"] != float64(100) || got["store"] != true ||
+		got["response_format"].(map[string]any)["type"] != "json_object" {
+		t.Fatalf("request = %v", got)
+	}
+
+	// 调用时的参数优先，ExtraBody 按字段合并
+	callOpts := []kpllms.CallOption{kpllms.WithTemperature(0.9), kpllms.WithJsonMode(false), kpllms.WithExtraBody(map[string]any{"user": "u1"})}
+	if _, err = llm.Chat(context.Background(), msgs, callOpts...); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got["response_format"]; ok || got["temperature"] != 0.9 || got["max_tokens"] != float64(100) || got["store"] != true || got["user"] != "u1" {
+		t.Fatalf("request = %v", got)
+	}
+	resolved := llm.ResolveCallOptions(callOpts...)
+	if resolved.Model != "gpt-4o-mini" || *resolved.Temperature != 0.9 || resolved.JsonMode || len(resolved.ExtraBody) != 2 {
+		t.Fatalf("resolved = %+v", resolved)
+	}
+}
+
+func TestChatMultipleChoices(t *testing.T) {
+	var got map[string]any
+	url := newPresetServer(t, func(r *http.Request, body map[string]any, w http.ResponseWriter) {
+		got = body
+		if body["stream"] != true {
+			_, _ = io.WriteString(w, `{"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"A"}},
+				{"index":1,"finish_reason":"tool_calls","message":{"role":"assistant","tool_calls":[{"id":"c1","type":"function","function":{"name":"f","arguments":"{}"}}]}}]}`)
+			return
+		}
+		for _, chunk := range []string{
+			`{"choices":[{"index":1,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"c1","type":"function","function":{"name":"f","arguments":""}}]}}]}`,
+			`{"choices":[{"index":0,"delta":{"role":"assistant","content":"你"}}]}`,
+			`{"choices":[{"index":1,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"a\":"}}]}}]}`,
+			`{"choices":[{"index":0,"delta":{"content":"好"},"finish_reason":"stop"},{"index":1,"delta":{"content":"嗨"}}]}`,
+			`{"choices":[{"index":1,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"1}"}}]},"finish_reason":"tool_calls"}]}`,
+		} {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	})
+	llm, err := New(WithToken("sk"), WithBaseURL(url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "hi"}}
+	resp, err := llm.Chat(context.Background(), msgs, kpllms.WithN(2), kpllms.WithStop("\n\n"), kpllms.WithUser("u1"), kpllms.WithLogProbs(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["n"] != float64(2) || got["stop"].([]any)[0] != "\n\n" || got["user"] != "u1" || got["logprobs"] != true || got["top_logprobs"] != float64(2) {
+		t.Fatalf("request = %v", got)
+	}
+	if len(resp.Choices) != 2 || resp.Choices[0].Content != "A" || len(resp.Choices[1].ToolCalls) != 1 {
+		t.Fatalf("choices = %+v", resp.Choices)
+	}
+	if resp.Choices[0].Usage == nil || resp.Choices[1].Usage != nil {
+		t.Fatalf("usage should only be on the first choice: %+v", resp.Choices)
+	}
+
+	var streamed string
+	byIndex := map[int]string{}
+	resp, err = llm.Chat(context.Background(), msgs, kpllms.WithN(2), kpllms.WithStreamingFunc(func(ctx context.Context, chunk []byte, innerErr error) error {
+		streamed += string(chunk)
+		return nil
+	}), kpllms.WithChoiceStreamingFunc(func(ctx context.Context, index int, chunk []byte) error {
+		byIndex[index] += string(chunk)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(byIndex, map[int]string{0: "你好", 1: "嗨"}) {
+		t.Fatalf("choice stream = %v", byIndex)
+	}
+	if streamed != "你好" || len(resp.Choices) != 2 || resp.Choices[0].Content != "你好" || resp.Choices[0].StopReason != "stop" {
+		t.Fatalf("streamed = %q, choices = %+v", streamed, resp.Choices)
+	}
+	if c := resp.Choices[1]; c.Content != "嗨" || c.StopReason != "tool_calls" || len(c.ToolCalls) != 1 || c.ToolCalls[0].Id != "c1" || c.ToolCalls[0].Function.Arguments != `{"a":1}` {
+		t.Fatalf("choice = %+v", c)
+	}
+}
+
+func TestChatLogProbs(t *testing.T) {
+	const logprobs = `{"content":[{"token":" ","logprob":0},{"token":"Yes","logprob":-0.1,"bytes":[89,101,115],
+		"top_logprobs":[{"token":"Yes","logprob":-0.1},{"token":" yes","logprob":-3},{"token":"No","logprob":-2.5}]}]}`
+	url := newPresetServer(t, func(r *http.Request, body map[string]any, w http.ResponseWriter) {
+		if body["stream"] != true {
+			_, _ = io.WriteString(w, `{"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":" Yes"},"logprobs":`+logprobs+`}]}`)
+			return
+		}
+		_, _ = fmt.Fprintf(w, "data: %s\n\n", `{"choices":[{"index":0,"delta":{"content":" "},"logprobs":{"content":[{"token":" ","logprob":0}]}}]}`)
+		_, _ = fmt.Fprintf(w, "data: %s\n\n", `{"choices":[{"index":0,"delta":{"content":"Yes"},"finish_reason":"stop","logprobs":{"content":[{"token":"Yes","logprob":-0.1,"top_logprobs":[{"token":"Yes","logprob":-0.1},{"token":" yes","logprob":-3},{"token":"No","logprob":-2.5}]}]}}]}`)
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	})
+	llm, err := New(WithToken("sk"), WithBaseURL(url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "是否"}}
+	noop := func(ctx context.Context, chunk []byte, innerErr error) error { return nil }
+	for _, opts := range [][]kpllms.CallOption{{kpllms.WithLogProbs(3)}, {kpllms.WithLogProbs(3), kpllms.WithStreamingFunc(noop)}} {
+		resp, err := llm.Chat(context.Background(), msgs, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lps := resp.Choices[0].LogProbs
+		if len(lps) != 2 || lps[1].Token != "Yes" || len(lps[1].TopLogProbs) != 3 {
+			t.Fatalf("logprobs = %+v", lps)
+		}
+		if p := schema.SequenceProbability(lps); math.Abs(p-math.Exp(-0.1)) > 1e-9 {
+			t.Fatalf("sequence probability = %v", p)
+		}
+		if p := schema.LabelProbability(lps, "No"); math.Abs(p-math.Exp(-2.5)) > 1e-9 {
+			t.Fatalf("label probability = %v", p)
+		}
+		if p := schema.LabelProbability(lps, "Yes"); math.Abs(p-math.Exp(-0.1)) > 1e-9 {
+			t.Fatalf("label probability = %v", p)
+		}
+	}
+}
+
+func TestChatMetadata(t *testing.T) {
+	url := newPresetServer(t, func(r *http.Request, body map[string]any, w http.ResponseWriter) {
+		w.Header().Set("X-Request-Id", "req-1")
+		w.Header().Set("X-Ratelimit-Remaining-Requests", "99")
+		w.Header().Set("X-Ratelimit-Remaining-Tokens", "1000")
+		w.Header().Set("X-Ratelimit-Reset-Tokens", "6ms")
+		if body["stream"] != true {
+			_, _ = io.WriteString(w, `{"id":"chatcmpl-1","created":1700000000,"model":"gpt-4o-2024-08-06","system_fingerprint":"fp_1",
+				"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"你好"}}]}`)
+			return
+		}
+		const chunk = `{"id":"chatcmpl-1","created":1700000000,"model":"gpt-4o-2024-08-06","system_fingerprint":"fp_1","choices":[{"index":0,"delta":{"content":"%s"}}]}`
+		_, _ = fmt.Fprintf(w, "data: "+chunk+"\n\n", "你")
+		w.(http.Flusher).Flush()
+		time.Sleep(20 * time.Millisecond)
+		_, _ = fmt.Fprintf(w, "data: "+chunk+"\n\n", "好")
+		_, _ = io.WriteString(w, `data: {"id":"chatcmpl-1","choices":[],"usage":{"prompt_tokens":8,"completion_tokens":2,"total_tokens":10}}`+"\n\n")
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	})
+	llm, err := New(WithToken("sk"), WithBaseURL(url), WithModel("gpt-4o"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}}
+	noop := func(ctx context.Context, chunk []byte, innerErr error) error { return nil }
+	for _, stream := range []bool{false, true} {
+		var opts []kpllms.CallOption
+		if stream {
+			opts = append(opts, kpllms.WithStreamingFunc(noop))
+		}
+		resp, err := llm.Chat(context.Background(), msgs, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := resp.Metadata
+		if m.ID != "chatcmpl-1" || m.RequestID != "req-1" || m.Model != "gpt-4o-2024-08-06" || m.Created != 1700000000 || m.SystemFingerprint != "fp_1" {
+			t.Fatalf("stream=%v metadata = %+v", stream, m)
+		}
+		if !reflect.DeepEqual(m.RateLimitRemaining, map[string]int{"requests": 99, "tokens": 1000}) {
+			t.Fatalf("rate limit = %v", m.RateLimitRemaining)
+		}
+		if resp.Choices[0].GenerationInfo["id"] != "chatcmpl-1" {
+			t.Fatalf("generation info = %v", resp.Choices[0].GenerationInfo)
+		}
+		if m.Latency <= 0 {
+			t.Fatalf("latency = %v", m.Latency)
+		}
+		if !stream {
+			if m.TimeToFirstToken != 0 || m.TokensPerSecond != 0 {
+				t.Fatalf("non-stream timings = %+v", m)
+			}
+			continue
+		}
+		// 使用接口返回的 token 消耗计算生成速度
+		if m.TimeToFirstToken <= 0 || m.Latency-m.TimeToFirstToken < 20*time.Millisecond || m.TokensPerSecond <= 0 {
+			t.Fatalf("stream timings = %+v", m)
+		}
+		if u := resp.Choices[0].Usage; u.PromptTokens != 8 || u.CompletionTokens != 2 || u.TotalTokens != 10 {
+			t.Fatalf("usage = %+v", u)
+		}
+	}
+}
+
+func TestChatStructuredOutputs(t *testing.T) {
+	url := newPresetServer(t, func(r *http.Request, body map[string]any, w http.ResponseWriter) {
+		format, _ := json.Marshal(body["response_format"])
+		want := `{"json_schema":{"name":"answer","schema":{"additionalProperties":false,"properties":{"city":{"type":"string"}},"required":["city"],"type":"object"},"strict":true},"type":"json_schema"}`
+		if string(format) != want {
+			t.Errorf("response_format = %s", format)
+		}
+		tools, _ := json.Marshal(body["tools"])
+		if !strings.Contains(string(tools), `"strict":true`) {
+			t.Errorf("tools = %s", tools)
+		}
+		if body["stream"] == true {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", `{"choices":[{"index":0,"delta":{"refusal":"抱歉，"}}]}`)
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", `{"choices":[{"index":0,"delta":{"refusal":"无法回答"},"finish_reason":"stop"}]}`)
+			_, _ = io.WriteString(w, "data: [DONE]\n\n")
+			return
+		}
+		_, _ = io.WriteString(w, `{"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":null,"refusal":"抱歉，无法回答"}}]}`)
+	})
+	llm, err := New(WithToken("sk"), WithBaseURL(url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "天气"}}
+	noop := func(ctx context.Context, chunk []byte, innerErr error) error { return nil }
+	tools := []*kpllms.Tool{{Type: "function", Function: &kpllms.FunctionDefinition{
+		Name: "weather", Parameters: map[string]any{"type": "object"}, Strict: true,
+	}}}
+	opts := []kpllms.CallOption{
+		kpllms.WithTools(tools),
+		kpllms.WithJsonSchema(&kpllms.JsonSchema{
+			Name:   "answer",
+			Schema: `{"type":"object","properties":{"city":{"type":"string"}},"required":["city"],"additionalProperties":false}`,
+			Strict: true,
+		}),
+	}
+	for _, stream := range []bool{false, true} {
+		callOpts := opts
+		if stream {
+			callOpts = append(callOpts, kpllms.WithStreamingFunc(noop))
+		}
+		resp, err := llm.Chat(context.Background(), msgs, callOpts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := resp.Choices[0]
+		if c.Refusal == nil || c.Refusal.Message != "抱歉，无法回答" || c.Content != "" {
+			t.Fatalf("stream=%v choice = %+v", stream, c)
+		}
+	}
+
+	// schema 不是合法的 json 时不发送请求
+	_, err = llm.Chat(context.Background(), msgs, kpllms.WithJsonSchema(&kpllms.JsonSchema{Name: "answer", Schema: `{"type":`}))
+	if err == nil || !strings.Contains(err.Error(), `invalid json schema "answer"`) {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package openai
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/comqositi/kpllms/openai/internal/openaiclient"
+)
+
+// 兼容 openai 接口的厂商预设，新增厂商只需要在 presets 中加一项
+
+const (
+	PresetDeepSeek = "deepseek"
+	PresetMoonshot = "moonshot"
+	PresetBaichuan = "baichuan"
+	PresetYi       = "yi"
+	PresetVLLM     = "vllm"
+)
+
+// ErrUnknownPreset 没有注册的预设
+var ErrUnknownPreset = errors.New("unknown openai compatible preset")
+
+// Capabilities 厂商支持的能力，不支持的字段在请求前去掉
+type Capabilities struct {
+	// 是否支持 tools 和 tool_choice
+	Tools bool
+	// 是否支持 response_format json_object
+	JsonMode bool
+	// 是否支持 response_format json_schema，不支持时降级为 json_object
+	JsonSchema bool
+	// 是否支持 temperature=0，不支持时使用 MinTemperature
+	ZeroTemperature bool
+	// 是否支持 /embeddings
+	Embeddings bool
+}
+
+// Preset 兼容 openai 接口的厂商配置
+type Preset struct {
+	Name string
+	// 默认接口地址
+	BaseURL string
+	// 按顺序读取 token 的环境变量
+	TokenEnvVarNames []string
+	// 覆盖接口地址和默认模型的环境变量
+	BaseURLEnvVarName string
+	ModelEnvVarName   string
+	// 不需要 token 时填写的占位 token，例如本地部署的 vllm
+	DefaultToken   string
+	DefaultModel   string
+	EmbeddingModel string
+	// 模型的上下文长度
+	ContextSizes map[string]int
+	// 不支持 temperature=0 时使用的最小温度，为 0 时不发送 temperature，使用厂商默认值
+	MinTemperature float64
+
+	Capabilities
+}
+
+// ContextSize 返回模型的上下文长度，未知模型返回 0
+func (p *Preset) ContextSize(model string) int {
+	return p.ContextSizes[model]
+}
+
+var (
+	presetsMu sync.RWMutex
+	presets   = map[string]*Preset{
+		PresetDeepSeek: {
+			Name:              PresetDeepSeek,
+			BaseURL:           "https://api.deepseek.com/v1",
+			TokenEnvVarNames:  []string{"DEEPSEEK_API_KEY"},
+			BaseURLEnvVarName: "DEEPSEEK_BASE_URL",
+			ModelEnvVarName:   "DEEPSEEK_MODEL",
+			DefaultModel:      "deepseek-chat",
+			ContextSizes:      map[string]int{"deepseek-chat": 65536, "deepseek-reasoner": 65536},
+			Capabilities:      Capabilities{Tools: true, JsonMode: true, ZeroTemperature: true},
+		},
+		PresetMoonshot: {
+			Name:              PresetMoonshot,
+			BaseURL:           "https://api.moonshot.cn/v1",
+			TokenEnvVarNames:  []string{"MOONSHOT_API_KEY"},
+			BaseURLEnvVarName: "MOONSHOT_BASE_URL",
+			ModelEnvVarName:   "MOONSHOT_MODEL",
+			DefaultModel:      "moonshot-v1-8k",
+			ContextSizes:      map[string]int{"moonshot-v1-8k": 8192, "moonshot-v1-32k": 32768, "moonshot-v1-128k": 131072},
+			Capabilities:      Capabilities{Tools: true, JsonMode: true, ZeroTemperature: true},
+		},
+		PresetBaichuan: {
+			Name:              PresetBaichuan,
+			BaseURL:           "https://api.baichuan-ai.com/v1",
+			TokenEnvVarNames:  []string{"BAICHUAN_API_KEY"},
+			BaseURLEnvVarName: "BAICHUAN_BASE_URL",
+			ModelEnvVarName:   "BAICHUAN_MODEL",
+			DefaultModel:      "Baichuan4",
+			EmbeddingModel:    "Baichuan-Text-Embedding",
+			ContextSizes:      map[string]int{"Baichuan4": 32768, "Baichuan3-Turbo": 32768, "Baichuan3-Turbo-128k": 131072},
+			MinTemperature:    0.01,
+			Capabilities:      Capabilities{Tools: true, Embeddings: true},
+		},
+		PresetYi: {
+			Name:              PresetYi,
+			BaseURL:           "https://api.lingyiwanwu.com/v1",
+			TokenEnvVarNames:  []string{"YI_API_KEY"},
+			BaseURLEnvVarName: "YI_BASE_URL",
+			ModelEnvVarName:   "YI_MODEL",
+			DefaultModel:      "yi-lightning",
+			ContextSizes:      map[string]int{"yi-lightning": 16384, "yi-large": 32768},
+			Capabilities:      Capabilities{ZeroTemperature: true},
+		},
+		PresetVLLM: {
+			Name:              PresetVLLM,
+			BaseURL:           "http://localhost:8000/v1",
+			TokenEnvVarNames:  []string{"VLLM_API_KEY"},
+			BaseURLEnvVarName: "VLLM_BASE_URL",
+			ModelEnvVarName:   "VLLM_MODEL",
+			DefaultToken:      "EMPTY",
+			Capabilities:      Capabilities{Tools: true, JsonMode: true, JsonSchema: true, ZeroTemperature: true, Embeddings: true},
+		},
+	}
+)
+
+// RegisterPreset 注册或覆盖一个预设
+func RegisterPreset(p *Preset) {
+	presetsMu.Lock()
+	defer presetsMu.Unlock()
+	presets[p.Name] = p
+}
+
+// GetPreset 按名称获取预设
+func GetPreset(name string) (*Preset, bool) {
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+	p, ok := presets[name]
+	return p, ok
+}
+
+// Presets 返回所有预设的名称
+func Presets() []string {
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewWithPreset 使用预设创建兼容 openai 接口的模型，opts 可以覆盖预设中的配置
+func NewWithPreset(name string, opts ...Option) (*LLM, error) {
+	p, ok := GetPreset(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPreset, name)
+	}
+	presetOpts := []Option{
+		WithToken(firstNonEmpty(getEnvs(p.TokenEnvVarNames...), p.DefaultToken)),
+		WithBaseURL(firstNonEmpty(os.Getenv(p.BaseURLEnvVarName), p.BaseURL)),
+		WithModel(firstNonEmpty(os.Getenv(p.ModelEnvVarName), p.DefaultModel)),
+		WithEmbeddingModel(p.EmbeddingModel),
+	}
+	options, c, err := newClient(append(presetOpts, opts...)...)
+	if errors.Is(err, ErrMissingToken) {
+		return nil, fmt.Errorf("%w: set the %s API key in the %v environment variable", ErrMissingToken, p.Name, p.TokenEnvVarNames)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &LLM{client: c, preset: p, defaultOptions: options.callOptionsOf()}, nil
+}
+
+// Preset 返回创建模型时使用的预设，直接用 New 创建时为 nil
+func (o *LLM) Preset() *Preset {
+	return o.preset
+}
+
+// adaptRequest 去掉厂商不支持的字段
+func (p *Preset) adaptRequest(req *openaiclient.ChatRequest) {
+	if !p.Tools {
+		req.Tools = nil
+		req.ToolChoice = nil
+	}
+	if !p.JsonMode {
+		req.ResponseFormat = nil
+	}
+	if !p.JsonSchema && req.ResponseFormat != nil && req.ResponseFormat.Type == responseFormatJSONSchema {
+		req.ResponseFormat = ResponseFormatJSON
+	}
+	// 不支持 temperature=0 时，设置为 0 的温度改为 MinTemperature，没有配置 MinTemperature 时不发送
+	if !p.ZeroTemperature && req.Temperature != nil && *req.Temperature <= 0 {
+		req.Temperature = nil
+		if p.MinTemperature > 0 {
+			minTemperature := p.MinTemperature
+			req.Temperature = &minTemperature
+		}
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
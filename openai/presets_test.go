@@ -0,0 +1,162 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+)
+
+func newPresetServer(t *testing.T, handler func(r *http.Request, body map[string]any, w http.ResponseWriter)) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		handler(r, body, w)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestPresetReasoningContent(t *testing.T) {
+	url := newPresetServer(t, func(r *http.Request, body map[string]any, w http.ResponseWriter) {
+		if r.Header.Get("Authorization") != "Bearer sk-deepseek" || body["model"] != "deepseek-reasoner" {
+			t.Errorf("unexpected request: %v %v", r.Header, body)
+		}
+		if body["stream"] == true {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", `{"choices":[{"index":0,"delta":{"reasoning_content":"先想"}}]}`)
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", `{"choices":[{"index":0,"delta":{"reasoning_content":"一想"}}]}`)
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", `{"choices":[{"index":0,"delta":{"content":"答案"},"finish_reason":"stop"}]}`)
+			_, _ = io.WriteString(w, "data: [DONE]\n\n")
+			return
+		}
+		_, _ = io.WriteString(w, `{"choices":[{"index":0,"finish_reason":"stop",
+			"message":{"role":"assistant","content":"答案","reasoning_content":"先想一想"}}],"usage":{"total_tokens":9}}`)
+	})
+	t.Setenv("DEEPSEEK_API_KEY", "sk-deepseek")
+	t.Setenv("DEEPSEEK_MODEL", "deepseek-reasoner")
+	llm, err := NewWithPreset(PresetDeepSeek, WithBaseURL(url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if llm.Preset().ContextSize("deepseek-reasoner") != 65536 {
+		t.Fatal("unexpected context size")
+	}
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "1+1"}}
+	resp, err := llm.Chat(context.Background(), msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := resp.Choices[0]; c.Content != "答案" || c.ReasoningContent != "先想一想" {
+		t.Fatalf("choice = %+v", c)
+	}
+
+	var streamed string
+	resp, err = llm.Chat(context.Background(), msgs, kpllms.WithStreamingFunc(func(ctx context.Context, chunk []byte, innerErr error) error {
+		streamed += string(chunk)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := resp.Choices[0]; streamed != "答案" || c.Content != "答案" || c.ReasoningContent != "先想一想" {
+		t.Fatalf("streamed = %q, choice = %+v", streamed, c)
+	}
+}
+
+func TestPresetStripsUnsupportedFields(t *testing.T) {
+	var got map[string]any
+	url := newPresetServer(t, func(r *http.Request, body map[string]any, w http.ResponseWriter) {
+		got = body
+		_, _ = io.WriteString(w, `{"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"ok"}}]}`)
+	})
+	tools := []*kpllms.Tool{{Type: schema.ToolCallTypeFunction, Function: &kpllms.FunctionDefinition{Name: "f"}}}
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "hi"}}
+
+	yi, err := NewWithPreset(PresetYi, WithToken("sk-yi"), WithBaseURL(url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = yi.Chat(context.Background(), msgs, kpllms.WithTools(tools), kpllms.WithJsonMode(true)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got["tools"]; ok {
+		t.Fatalf("tools should be stripped: %v", got)
+	}
+	// 没有设置 temperature 时不发送
+	if _, ok := got["response_format"]; ok || got["temperature"] != nil {
+		t.Fatalf("unexpected request: %v", got)
+	}
+
+	baichuan, err := NewWithPreset(PresetBaichuan, WithToken("sk-bc"), WithBaseURL(url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = baichuan.Chat(context.Background(), msgs, kpllms.WithTools(tools), kpllms.WithTemperature(0)); err != nil {
+		t.Fatal(err)
+	}
+	if got["temperature"] != 0.01 || got["model"] != "Baichuan4" || len(got["tools"].([]any)) != 1 {
+		t.Fatalf("unexpected request: %v", got)
+	}
+
+	// 不支持 temperature=0 且没有配置最小温度时不发送 temperature
+	RegisterPreset(&Preset{Name: "no-zero-temperature", BaseURL: url, DefaultToken: "x", DefaultModel: "m"})
+	noZero, err := NewWithPreset("no-zero-temperature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = noZero.Chat(context.Background(), msgs, kpllms.WithTemperature(0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got["temperature"]; ok {
+		t.Fatalf("temperature should be omitted: %v", got)
+	}
+
+	// 不支持结构化输出时降级为 json_object
+	moonshot, err := NewWithPreset(PresetMoonshot, WithToken("sk-ms"), WithBaseURL(url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonSchema := &kpllms.JsonSchema{Name: "answer", Schema: map[string]any{"type": "object"}}
+	if _, err = moonshot.Chat(context.Background(), msgs, kpllms.WithJsonSchema(jsonSchema)); err != nil {
+		t.Fatal(err)
+	}
+	if format, _ := got["response_format"].(map[string]any); format["type"] != "json_object" || format["json_schema"] != nil {
+		t.Fatalf("unexpected response_format: %v", got["response_format"])
+	}
+}
+
+func TestPresetRegistry(t *testing.T) {
+	if _, err := NewWithPreset("nope"); !errors.Is(err, ErrUnknownPreset) {
+		t.Fatalf("err = %v", err)
+	}
+	t.Setenv("MOONSHOT_API_KEY", "")
+	if _, err := NewWithPreset(PresetMoonshot); !errors.Is(err, ErrMissingToken) ||
+		!strings.Contains(err.Error(), "MOONSHOT_API_KEY") || strings.Contains(err.Error(), "OPENAI_API_KEY") {
+		t.Fatalf("expected missing moonshot token error, got %v", err)
+	}
+	// vllm 本地部署不需要 token
+	if _, err := NewWithPreset(PresetVLLM); err != nil {
+		t.Fatal(err)
+	}
+
+	RegisterPreset(&Preset{Name: "custom", BaseURL: "http://localhost:1234/v1", DefaultToken: "x", DefaultModel: "m"})
+	llm, err := NewWithPreset("custom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if llm.Preset().Name != "custom" {
+		t.Fatal("unexpected preset")
+	}
+	if _, err = llm.EmbedQuery(context.Background(), "x"); !errors.Is(err, ErrEmbeddingsNotSupported) {
+		t.Fatalf("err = %v", err)
+	}
+}
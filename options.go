@@ -10,20 +10,55 @@ type CallOption func(*CallOptions)
 type CallOptions struct {
 	// 模型代号， 例如：gpt-4
 	Model string
-	// 最大输出 token 数
-	MaxTokens int
+	// 最大输出 token 数，为空时使用厂商默认值，下同
+	MaxTokens *int
 	// 温度 0-2
-	Temperature float64
-	// 流式输出
+	Temperature *float64
+	// 流式输出，N > 1 时只输出第一个结果
 	StreamingFunc func(ctx context.Context, chunk []byte, innerErr error) error
+	// 按结果序号流式输出所有结果，index 从 0 开始，仅部分厂商支持
+	ChoiceStreamingFunc func(ctx context.Context, index int, chunk []byte) error
 	// 采样率 0.1 = 10%
-	TopP float64
+	TopP *float64
+	// 频率惩罚，降低重复 token 出现的概率
+	FrequencyPenalty *float64
+	// 存在惩罚，提高谈论新话题的概率
+	PresencePenalty *float64
+	// 随机种子，相同的 seed 和参数尽量返回相同的结果
+	Seed *int
+	// 停止词，生成到停止词时结束
+	Stop []string
+	// 返回的结果数量，0 表示使用默认值 1
+	N int
+	// 终端用户的唯一标识
+	User string
+	// 返回每个 token 的概率
+	LogProbs bool
+	// 每个 token 返回概率最高的候选 token 数量，需要开启 LogProbs
+	TopLogProbs int
 	/// 是否严格要求返回 json 格式, true: 强制 json 格式返回
 	JsonMode bool
 	// 函数定义
 	Tools []*Tool
 	// 函数调用方式  auto， none  指定：{"type":"auto/none/function","function":}, none: 不调用，auto：自动调用，默认是自动调用， functon，指定调用
 	ToolChoice ToolChoice
+	// JsonMode 下要求返回的 json 结构，为空时只要求返回 json
+	JsonSchema *JsonSchema
+	// 厂商特有的参数，key 由各厂商的包定义，厂商忽略不属于自己的 key
+	ProviderOptions map[any]any
+	// 合并到请求体中的字段，用于传递还没有封装的参数，值为 nil 时删除该字段，仅部分厂商支持
+	ExtraBody map[string]any
+}
+
+// JsonSchema 描述要求模型返回的 json 结构
+type JsonSchema struct {
+	Name        string
+	Description string
+	// schema.Definition、map[string]any 或者 json 字符串（json.RawMessage、[]byte、string），
+	// 使用 json 字符串时保留属性的顺序
+	Schema any
+	// 严格按照 schema 返回，仅部分厂商支持
+	Strict bool
 }
 
 type ResponseFormat struct {
@@ -34,12 +69,16 @@ type ResponseFormat struct {
 type Tool struct {
 	Type     string              `json:"type"`
 	Function *FunctionDefinition `json:"function,omitempty"`
+	// 厂商内置工具（Type 不是 function）的配置，例如智谱的 web_search、retrieval，由各厂商的包构造
+	Config any `json:"-"`
 }
 
 type FunctionDefinition struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	Parameters  any    `json:"parameters,omitempty"`
+	// 严格按照 Parameters 生成参数，仅 openai 支持
+	Strict bool `json:"strict,omitempty"`
 }
 
 type ToolChoice struct {
@@ -59,13 +98,13 @@ func WithModel(model string) CallOption {
 
 func WithMaxTokens(maxTokens int) CallOption {
 	return func(o *CallOptions) {
-		o.MaxTokens = maxTokens
+		o.MaxTokens = &maxTokens
 	}
 }
 
 func WithTemperature(temperature float64) CallOption {
 	return func(o *CallOptions) {
-		o.Temperature = temperature
+		o.Temperature = &temperature
 	}
 }
 
@@ -77,7 +116,59 @@ func WithStreamingFunc(streamingFunc func(ctx context.Context, chunk []byte, inn
 
 func WithTopP(topP float64) CallOption {
 	return func(o *CallOptions) {
-		o.TopP = topP
+		o.TopP = &topP
+	}
+}
+
+func WithFrequencyPenalty(penalty float64) CallOption {
+	return func(o *CallOptions) {
+		o.FrequencyPenalty = &penalty
+	}
+}
+
+func WithPresencePenalty(penalty float64) CallOption {
+	return func(o *CallOptions) {
+		o.PresencePenalty = &penalty
+	}
+}
+
+func WithSeed(seed int) CallOption {
+	return func(o *CallOptions) {
+		o.Seed = &seed
+	}
+}
+
+func WithStop(stop ...string) CallOption {
+	return func(o *CallOptions) {
+		o.Stop = stop
+	}
+}
+
+// WithChoiceStreamingFunc 流式输出 N 个结果，每段文本带上结果序号，可以和 WithStreamingFunc 同时使用
+func WithChoiceStreamingFunc(f func(ctx context.Context, index int, chunk []byte) error) CallOption {
+	return func(o *CallOptions) {
+		o.ChoiceStreamingFunc = f
+	}
+}
+
+// WithN 返回 n 个结果。流式输出时 StreamingFunc 只回调第一个结果，其他结果通过 WithChoiceStreamingFunc 获取
+func WithN(n int) CallOption {
+	return func(o *CallOptions) {
+		o.N = n
+	}
+}
+
+func WithUser(user string) CallOption {
+	return func(o *CallOptions) {
+		o.User = user
+	}
+}
+
+// WithLogProbs 返回每个 token 的概率，topLogProbs 为每个 token 返回的候选数量
+func WithLogProbs(topLogProbs int) CallOption {
+	return func(o *CallOptions) {
+		o.LogProbs = true
+		o.TopLogProbs = topLogProbs
 	}
 }
 
@@ -98,3 +189,55 @@ func WithToolChoice(choice ToolChoice) CallOption {
 		o.ToolChoice = choice
 	}
 }
+
+// WithJsonSchema 要求按照 schema 返回 json，同时开启 JsonMode
+func WithJsonSchema(jsonSchema *JsonSchema) CallOption {
+	return func(o *CallOptions) {
+		o.JsonMode = true
+		o.JsonSchema = jsonSchema
+	}
+}
+
+// WithProviderOption 设置厂商特有的参数，一般由各厂商的包封装后使用
+func WithProviderOption(key, value any) CallOption {
+	return func(o *CallOptions) {
+		if o.ProviderOptions == nil {
+			o.ProviderOptions = make(map[any]any)
+		}
+		o.ProviderOptions[key] = value
+	}
+}
+
+// WithExtraBody 合并到请求体中的字段，多次调用时合并
+func WithExtraBody(extra map[string]any) CallOption {
+	return func(o *CallOptions) {
+		if o.ExtraBody == nil {
+			o.ExtraBody = make(map[string]any, len(extra))
+		}
+		for k, v := range extra {
+			o.ExtraBody[k] = v
+		}
+	}
+}
+
+// Deref 返回参数的值，未设置时返回零值
+func Deref[T any](v *T) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+	return *v
+}
+
+// ResolveCallOptions 合并客户端默认参数和本次调用的参数：先应用 defaults 再应用 options，
+// 普通字段以本次调用为准，ExtraBody 和 ProviderOptions 中同一厂商的参数按字段合并
+func ResolveCallOptions(defaults []CallOption, options ...CallOption) CallOptions {
+	opts := CallOptions{}
+	for _, opt := range defaults {
+		opt(&opts)
+	}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	return opts
+}
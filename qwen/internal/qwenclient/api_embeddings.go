@@ -0,0 +1,63 @@
+package qwenclient
+
+import (
+	"context"
+	"sort"
+
+	"github.com/comqositi/kpllms/internal/httputils"
+)
+
+const (
+	textEmbeddingPath = "/services/embeddings/text-embedding/text-embedding"
+
+	TextTypeDocument = "document"
+	TextTypeQuery    = "query"
+
+	// text-embedding-v3 单次最多 10 条
+	maxEmbeddingBatchSize = 10
+)
+
+type embeddingRequest struct {
+	Model string `json:"model"`
+	Input struct {
+		Texts []string `json:"texts"`
+	} `json:"input"`
+	Parameters struct {
+		TextType string `json:"text_type,omitempty"`
+	} `json:"parameters"`
+}
+
+type embeddingResponse struct {
+	RequestID string `json:"request_id"`
+	Output    struct {
+		Embeddings []struct {
+			TextIndex int       `json:"text_index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"embeddings"`
+	} `json:"output"`
+	Usage struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// CreateEmbedding 文本向量化，textType 为 document 或 query，超过单次上限时分批请求
+func (c *Client) CreateEmbedding(ctx context.Context, texts []string, textType string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbeddingBatchSize {
+		end := min(start+maxEmbeddingBatchSize, len(texts))
+		req := &embeddingRequest{Model: c.EmbeddingsModel}
+		req.Input.Texts = texts[start:end]
+		req.Parameters.TextType = textType
+
+		var resp embeddingResponse
+		if err := httputils.HttpPost(ctx, c.baseURL+textEmbeddingPath, req, c.setHeaders(false), &resp); err != nil {
+			return nil, convertError(err)
+		}
+		data := resp.Output.Embeddings
+		sort.Slice(data, func(i, j int) bool { return data[i].TextIndex < data[j].TextIndex })
+		for _, d := range data {
+			embeddings = append(embeddings, d.Embedding)
+		}
+	}
+	return embeddings, nil
+}
@@ -0,0 +1,279 @@
+package qwenclient
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/comqositi/kpllms/internal/httputils"
+)
+
+// dashscope 文本生成和多模态生成接口实现，
+// 文档：https://help.aliyun.com/zh/model-studio/developer-reference/use-qwen-by-calling-api
+
+const (
+	textGenerationPath       = "/services/aigc/text-generation/generation"
+	multimodalGenerationPath = "/services/aigc/multimodal-generation/generation"
+
+	ResultFormatMessage = "message"
+
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// GenerationRequest 生成接口请求
+type GenerationRequest struct {
+	Model      string     `json:"model"`
+	Input      Input      `json:"input"`
+	Parameters Parameters `json:"parameters"`
+
+	// 使用多模态生成接口，消息内容为 [{"image":""},{"text":""}] 数组
+	Multimodal bool `json:"-"`
+	// 流式放回的回调函数
+	StreamingFunc func(ctx context.Context, chunk []byte, innerErr error) error `json:"-"`
+}
+
+type Input struct {
+	Messages []*Message `json:"messages"`
+}
+
+type Parameters struct {
+	ResultFormat      string          `json:"result_format,omitempty"`
+	IncrementalOutput bool            `json:"incremental_output,omitempty"`
+	EnableSearch      bool            `json:"enable_search,omitempty"`
+	Temperature       *float64        `json:"temperature,omitempty"`
+	TopP              *float64        `json:"top_p,omitempty"`
+	MaxTokens         int             `json:"max_tokens,omitempty"`
+	PresencePenalty   *float64        `json:"presence_penalty,omitempty"`
+	Seed              *int            `json:"seed,omitempty"`
+	Stop              []string        `json:"stop,omitempty"`
+	Tools             []Tool          `json:"tools,omitempty"`
+	ToolChoice        any             `json:"tool_choice,omitempty"`
+	ResponseFormat    *ResponseFormat `json:"response_format,omitempty"`
+}
+
+type ResponseFormat struct {
+	Type string `json:"type"`
+}
+
+// Message 对话消息，文本生成时 Content 为 string，多模态生成时为 []ContentPart
+type Message struct {
+	Role       string     `json:"role"`
+	Content    any        `json:"content"`
+	Name       string     `json:"name,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// ContentPart 多模态消息内容，只会设置其中一个字段
+type ContentPart struct {
+	Text  string `json:"text,omitempty"`
+	Image string `json:"image,omitempty"`
+}
+
+type Tool struct {
+	Type     string             `json:"type"`
+	Function FunctionDefinition `json:"function"`
+}
+
+type FunctionDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+type ToolCall struct {
+	Index    int          `json:"index"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function FunctionCall `json:"function"`
+}
+
+type FunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+// ToolChoice 指定调用的函数 {"type":"function","function":{"name":"xx"}}
+type ToolChoice struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name string `json:"name"`
+	} `json:"function"`
+}
+
+// ResponseContent 返回的消息内容，文本生成为字符串，多模态生成为 [{"text":""}] 数组，统一解析为字符串
+type ResponseContent string
+
+func (c *ResponseContent) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '[' {
+		var parts []ContentPart
+		if err := json.Unmarshal(b, &parts); err != nil {
+			return err
+		}
+		var sb strings.Builder
+		for _, p := range parts {
+			sb.WriteString(p.Text)
+		}
+		*c = ResponseContent(sb.String())
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*c = ResponseContent(s)
+	return nil
+}
+
+type ResponseMessage struct {
+	Role      string          `json:"role"`
+	Content   ResponseContent `json:"content"`
+	ToolCalls []ToolCall      `json:"tool_calls,omitempty"`
+}
+
+type Choice struct {
+	FinishReason string          `json:"finish_reason"`
+	Message      ResponseMessage `json:"message"`
+}
+
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+type Output struct {
+	Choices []*Choice `json:"choices"`
+	// 联网搜索时返回的搜索结果
+	SearchInfo any `json:"search_info,omitempty"`
+}
+
+// GenerationResponse 生成接口返回，流式返回时每个 chunk 也是该结构
+type GenerationResponse struct {
+	RequestID string `json:"request_id"`
+	Output    Output `json:"output"`
+	Usage     Usage  `json:"usage"`
+}
+
+// CreateGeneration 调用生成接口，流式返回使用 incremental_output 增量输出并拼接结果
+func (c *Client) CreateGeneration(ctx context.Context, r *GenerationRequest) (*GenerationResponse, error) {
+	if r.Model == "" {
+		r.Model = c.Model
+	}
+	// 只有 message 格式才能返回 tool_calls
+	r.Parameters.ResultFormat = ResultFormatMessage
+	r.Parameters.IncrementalOutput = r.StreamingFunc != nil
+
+	url := c.baseURL + textGenerationPath
+	if r.Multimodal {
+		url = c.baseURL + multimodalGenerationPath
+	}
+
+	var response GenerationResponse
+	if r.StreamingFunc == nil {
+		if err := httputils.HttpPost(ctx, url, r, c.setHeaders(false), &response); err != nil {
+			return nil, convertError(err)
+		}
+	} else if err := c.stream(ctx, url, r, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Output.Choices) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	return &response, nil
+}
+
+// stream 解析 SSE，格式：
+//
+//	id:1
+//	event:result
+//	:HTTP_STATUS/200
+//	data:{...}
+func (c *Client) stream(ctx context.Context, url string, r *GenerationRequest, response *GenerationResponse) error {
+	choice := &Choice{}
+	response.Output.Choices = []*Choice{choice}
+	var event string
+	status := 0
+	// HttpStream 会把回调返回的错误转为 HttpError，这里单独保存接口错误
+	var apiErr error
+	err := httputils.HttpStream(ctx, url, r, c.setHeaders(true), func(ctx context.Context, line string) error {
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			return nil
+		case strings.HasPrefix(line, ":HTTP_STATUS/"):
+			status, _ = strconv.Atoi(strings.TrimPrefix(line, ":HTTP_STATUS/"))
+			return nil
+		case !strings.HasPrefix(line, "data:"):
+			return nil
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if event == "error" {
+			var e errorResponse
+			if err := json.Unmarshal([]byte(data), &e); err != nil {
+				return err
+			}
+			apiErr = newError(status, e)
+			return apiErr
+		}
+
+		var chunk GenerationResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return err
+		}
+		response.RequestID = chunk.RequestID
+		response.Usage = chunk.Usage
+		if chunk.Output.SearchInfo != nil {
+			response.Output.SearchInfo = chunk.Output.SearchInfo
+		}
+		if len(chunk.Output.Choices) == 0 {
+			return nil
+		}
+		delta := chunk.Output.Choices[0]
+		if delta.FinishReason != "" && delta.FinishReason != "null" {
+			choice.FinishReason = delta.FinishReason
+		}
+		choice.Message.Role = RoleAssistant
+		for _, tc := range delta.Message.ToolCalls {
+			mergeToolCall(&choice.Message, tc)
+		}
+		if delta.Message.Content == "" {
+			return nil
+		}
+		choice.Message.Content += delta.Message.Content
+		return r.StreamingFunc(ctx, []byte(delta.Message.Content), nil)
+	})
+	if apiErr != nil {
+		return apiErr
+	}
+	if err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+// mergeToolCall 按 index 拼接流式返回的工具调用，id 和函数名只在第一个片段中返回
+func mergeToolCall(msg *ResponseMessage, delta ToolCall) {
+	for i := range msg.ToolCalls {
+		tc := &msg.ToolCalls[i]
+		if tc.Index != delta.Index {
+			continue
+		}
+		if delta.ID != "" {
+			tc.ID = delta.ID
+		}
+		if delta.Function.Name != "" {
+			tc.Function.Name = delta.Function.Name
+		}
+		tc.Function.Arguments += delta.Function.Arguments
+		return
+	}
+	if delta.Type == "" {
+		delta.Type = "function"
+	}
+	msg.ToolCalls = append(msg.ToolCalls, delta)
+}
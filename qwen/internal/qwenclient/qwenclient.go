@@ -0,0 +1,149 @@
+package qwenclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/comqositi/kpllms/schema"
+)
+
+const (
+	defaultBaseURL        = "https://dashscope.aliyuncs.com/api/v1"
+	defaultChatModel      = "qwen-plus"
+	defaultEmbeddingModel = "text-embedding-v3"
+)
+
+// dashscope 客户端
+
+var (
+	ErrEmptyResponse = errors.New("empty response")
+	ErrMissingToken  = errors.New("api key 不能为空")
+)
+
+// Client DashScope 客户端
+type Client struct {
+	token           string
+	Model           string
+	EmbeddingsModel string
+	baseURL         string
+}
+
+// Option is an option for the DashScope client.
+type Option func(*Client) error
+
+// New 创建 DashScope 客户端
+func New(opts ...Option) (*Client, error) {
+	c := &Client{}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.token == "" {
+		return nil, ErrMissingToken
+	}
+	if c.baseURL == "" {
+		c.baseURL = defaultBaseURL
+	}
+	c.baseURL = strings.TrimSuffix(c.baseURL, "/")
+	if c.Model == "" {
+		c.Model = defaultChatModel
+	}
+	if c.EmbeddingsModel == "" {
+		c.EmbeddingsModel = defaultEmbeddingModel
+	}
+	return c, nil
+}
+
+func WithToken(value string) Option {
+	return func(c *Client) error {
+		c.token = value
+		return nil
+	}
+}
+
+func WithModel(value string) Option {
+	return func(c *Client) error {
+		c.Model = value
+		return nil
+	}
+}
+
+func WithEmbeddingsModel(value string) Option {
+	return func(c *Client) error {
+		c.EmbeddingsModel = value
+		return nil
+	}
+}
+
+func WithBaseURL(value string) Option {
+	return func(c *Client) error {
+		c.baseURL = value
+		return nil
+	}
+}
+
+// 设置权限
+func (c *Client) setHeaders(stream bool) map[string]string {
+	m := map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": "Bearer " + c.token,
+	}
+	if stream {
+		m["X-DashScope-SSE"] = "enable"
+	}
+	return m
+}
+
+// errorResponse DashScope 错误返回 {"code":"InvalidApiKey","message":"...","request_id":"..."}
+type errorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id"`
+}
+
+// codeStatus DashScope 错误码对应的 http 状态码，与其他厂商一样统一返回 schema.HttpError
+var codeStatus = map[string]int{
+	"InvalidParameter":           http.StatusBadRequest,
+	"DataInspectionFailed":       http.StatusBadRequest,
+	"InvalidApiKey":              http.StatusUnauthorized,
+	"AccessDenied":               http.StatusForbidden,
+	"AccessDenied.Unpurchased":   http.StatusForbidden,
+	"Model.AccessDenied":         http.StatusForbidden,
+	"Arrearage":                  http.StatusForbidden,
+	"ModelNotFound":              http.StatusNotFound,
+	"Throttling":                 http.StatusTooManyRequests,
+	"Throttling.RateQuota":       http.StatusTooManyRequests,
+	"Throttling.AllocationQuota": http.StatusTooManyRequests,
+	"Throttling.User":            http.StatusTooManyRequests,
+	"InternalError":              http.StatusInternalServerError,
+	"InternalError.Algo":         http.StatusInternalServerError,
+	"RequestTimeOut":             http.StatusGatewayTimeout,
+}
+
+// newError 把 DashScope 错误转为 schema.HttpError，status 为接口返回的 http 状态码，未知时按错误码推断
+func newError(status int, e errorResponse) error {
+	if s, ok := codeStatus[e.Code]; ok && (status == 0 || status == http.StatusOK) {
+		status = s
+	}
+	if status == 0 || status == http.StatusOK {
+		status = http.StatusInternalServerError
+	}
+	return schema.NewHttpError(status, fmt.Sprintf("%s: %s, request_id: %s", e.Code, e.Message, e.RequestID))
+}
+
+// convertError httputils 返回的 HttpError 中 ErrMsg 是原始 body，解析出错误码和信息
+func convertError(err error) error {
+	var httpErr *schema.HttpError
+	if !errors.As(err, &httpErr) || httpErr.Code == 0 {
+		return err
+	}
+	var e errorResponse
+	if json.Unmarshal([]byte(httpErr.ErrMsg), &e) != nil || e.Code == "" {
+		return err
+	}
+	return newError(httpErr.Code, e)
+}
@@ -0,0 +1,287 @@
+package qwen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/qwen/internal/qwenclient"
+	"github.com/comqositi/kpllms/schema"
+)
+
+var (
+	ErrEmptyResponse            = errors.New("no response")
+	ErrMissingToken             = errors.New("missing the DashScope API key, set it in the DASHSCOPE_API_KEY environment variable") //nolint:lll
+	ErrUnexpectedResponseLength = errors.New("unexpected length of response")
+)
+
+type LLM struct {
+	client       *qwenclient.Client
+	enableSearch bool
+}
+
+var (
+	_ kpllms.Model    = (*LLM)(nil)
+	_ kpllms.Embedder = (*LLM)(nil)
+)
+
+// New 创建通义千问大模型 model 和 embedder 的实现
+func New(opts ...Option) (*LLM, error) {
+	options := &options{
+		token:   os.Getenv(tokenEnvVarName),
+		model:   os.Getenv(modelEnvVarName),
+		baseURL: os.Getenv(baseURLEnvVarName),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.token == "" {
+		return nil, ErrMissingToken
+	}
+	c, err := qwenclient.New(
+		qwenclient.WithToken(options.token),
+		qwenclient.WithModel(options.model),
+		qwenclient.WithEmbeddingsModel(options.embeddingModel),
+		qwenclient.WithBaseURL(options.baseURL),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &LLM{client: c, enableSearch: options.enableSearch}, nil
+}
+
+// samplingRanges 通义千问支持的采样参数范围
+var samplingRanges = kpllms.SamplingRanges{
+	Temperature: kpllms.Range{Min: 0, Max: 2},
+	TopP:        kpllms.Range{Min: 0, Max: 1, ExclusiveMin: true},
+	Penalty:     kpllms.Range{Min: -2, Max: 2},
+}
+
+// Chat 实现大模型接口，消息中包含图片时使用多模态生成接口
+func (o *LLM) Chat(ctx context.Context, messages []*schema.ChatMessage, options ...kpllms.CallOption) (*schema.ContentResponse, error) {
+	opts := kpllms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if err := samplingRanges.Validate(&opts); err != nil {
+		return nil, err
+	}
+	if err := opts.CheckSupported("qwen", kpllms.OptionStop, kpllms.OptionPresencePenalty, kpllms.OptionSeed); err != nil {
+		return nil, err
+	}
+
+	multimodal := hasImage(messages)
+	msgs, err := messagesToClientMessages(messages, multimodal)
+	if err != nil {
+		return nil, err
+	}
+	req := &qwenclient.GenerationRequest{
+		Model:      opts.Model,
+		Input:      qwenclient.Input{Messages: msgs},
+		Multimodal: multimodal,
+		Parameters: qwenclient.Parameters{
+			EnableSearch: o.enableSearch,
+			Temperature:  opts.Temperature,
+			TopP:         opts.TopP,
+			MaxTokens:    kpllms.Deref(opts.MaxTokens),
+			// 通义千问只支持 presence_penalty
+			PresencePenalty: opts.PresencePenalty,
+			Seed:            opts.Seed,
+			Stop:            opts.Stop,
+		},
+		StreamingFunc: opts.StreamingFunc,
+	}
+	// 使用 json 格式返回
+	if opts.JsonMode {
+		req.Parameters.ResponseFormat = &qwenclient.ResponseFormat{Type: "json_object"}
+	}
+
+	// 组装工具
+	for _, t := range opts.Tools {
+		if t.Type != schema.ToolCallTypeFunction || t.Function == nil {
+			return nil, fmt.Errorf("failed to convert llms tool to qwen tool: tool type %v not supported", t.Type)
+		}
+		req.Parameters.Tools = append(req.Parameters.Tools, qwenclient.Tool{
+			Type: schema.ToolCallTypeFunction,
+			Function: qwenclient.FunctionDefinition{
+				Name:        t.Function.Name,
+				Description: t.Function.Description,
+				Parameters:  t.Function.Parameters,
+			},
+		})
+	}
+	switch opts.ToolChoice.Type {
+	case schema.ToolChoiceTypeFunction:
+		choice := qwenclient.ToolChoice{Type: schema.ToolChoiceTypeFunction}
+		choice.Function.Name = opts.ToolChoice.Function.Name
+		req.Parameters.ToolChoice = choice
+	case schema.ToolChoiceTypeNone:
+		req.Parameters.ToolChoice = schema.ToolChoiceTypeNone
+	}
+
+	result, err := o.client.CreateGeneration(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &schema.Usage{
+		PromptTokens:     result.Usage.InputTokens,
+		CompletionTokens: result.Usage.OutputTokens,
+		TotalTokens:      result.Usage.TotalTokens,
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	choices := make([]*schema.ContentChoice, 0, len(result.Output.Choices))
+	for _, c := range result.Output.Choices {
+		choice := &schema.ContentChoice{
+			Content:    string(c.Message.Content),
+			StopReason: c.FinishReason,
+			Usage:      usage,
+			GenerationInfo: map[string]any{
+				"request_id": result.RequestID,
+			},
+		}
+		if result.Output.SearchInfo != nil {
+			choice.GenerationInfo["search_info"] = result.Output.SearchInfo
+		}
+		for _, tc := range c.Message.ToolCalls {
+			choice.ToolCalls = append(choice.ToolCalls, &schema.ToolCall{
+				Id:   tc.ID,
+				Type: schema.ToolCallTypeFunction,
+				Function: schema.FunctionCall{
+					Name:      tc.Function.Name,
+					Arguments: tc.Function.Arguments,
+				},
+			})
+		}
+		choices = append(choices, choice)
+	}
+	return &schema.ContentResponse{Choices: choices}, nil
+}
+
+func hasImage(messages []*schema.ChatMessage) bool {
+	for _, m := range messages {
+		parts, ok := m.Content.([]any)
+		if !ok {
+			continue
+		}
+		for _, p := range parts {
+			switch p.(type) {
+			case schema.ImageContent, *schema.ImageContent:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func messagesToClientMessages(messages []*schema.ChatMessage, multimodal bool) ([]*qwenclient.Message, error) {
+	msgs := make([]*qwenclient.Message, 0, len(messages))
+	for _, mc := range messages {
+		msg := &qwenclient.Message{Name: mc.Name}
+		switch mc.Role {
+		case schema.RoleSystem:
+			msg.Role = qwenclient.RoleSystem
+		case schema.RoleUser:
+			msg.Role = qwenclient.RoleUser
+		case schema.RoleAssistant:
+			msg.Role = qwenclient.RoleAssistant
+			for i, t := range mc.ToolCalls {
+				msg.ToolCalls = append(msg.ToolCalls, qwenclient.ToolCall{
+					Index: i,
+					ID:    t.Id,
+					Type:  schema.ToolCallTypeFunction,
+					Function: qwenclient.FunctionCall{
+						Name:      t.Function.Name,
+						Arguments: t.Function.Arguments,
+					},
+				})
+			}
+		case schema.RoleTool:
+			msg.Role = qwenclient.RoleTool
+			msg.ToolCallID = mc.ToolCallId
+		default:
+			return nil, fmt.Errorf("role %v not supported", mc.Role)
+		}
+		content, err := convertContent(mc.Content, multimodal)
+		if err != nil {
+			return nil, err
+		}
+		msg.Content = content
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// convertContent 文本生成接口只支持字符串，多模态生成接口统一转为 [{"text":""},{"image":""}]
+func convertContent(content any, multimodal bool) (any, error) {
+	var items []any
+	switch c := content.(type) {
+	case nil:
+		items = nil
+	case string:
+		if !multimodal {
+			return c, nil
+		}
+		items = []any{schema.TextContent{Type: schema.MultiContentText, Text: c}}
+	case []any:
+		items = c
+	default:
+		return nil, fmt.Errorf("content type %T not supported", content)
+	}
+
+	parts := make([]qwenclient.ContentPart, 0, len(items))
+	for _, item := range items {
+		switch p := item.(type) {
+		case schema.TextContent:
+			parts = append(parts, qwenclient.ContentPart{Text: p.Text})
+		case *schema.TextContent:
+			parts = append(parts, qwenclient.ContentPart{Text: p.Text})
+		case schema.ImageContent:
+			parts = append(parts, qwenclient.ContentPart{Image: p.ImageUrl.Url})
+		case *schema.ImageContent:
+			parts = append(parts, qwenclient.ContentPart{Image: p.ImageUrl.Url})
+		default:
+			return nil, fmt.Errorf("content part type %T not supported", item)
+		}
+	}
+	if multimodal {
+		return parts, nil
+	}
+	var sb strings.Builder
+	for _, p := range parts {
+		sb.WriteString(p.Text)
+	}
+	return sb.String(), nil
+}
+
+// EmbedDocuments  实现 embedder 接口 文档存储向量：存入数据库的向量，被用于检索
+func (o *LLM) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings, err := o.client.CreateEmbedding(ctx, texts, qwenclient.TextTypeDocument)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	if len(texts) != len(embeddings) {
+		return embeddings, ErrUnexpectedResponseLength
+	}
+	return embeddings, nil
+}
+
+// EmbedQuery  实现 embedder 接口 查询向量：对需要用于检索的文本进行想量化
+func (o *LLM) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := o.client.CreateEmbedding(ctx, []string{text}, qwenclient.TextTypeQuery)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	return embeddings[0], nil
+}
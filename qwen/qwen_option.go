@@ -0,0 +1,53 @@
+package qwen
+
+const (
+	tokenEnvVarName   = "DASHSCOPE_API_KEY"  //nolint:gosec
+	modelEnvVarName   = "QWEN_MODEL"         //nolint:gosec
+	baseURLEnvVarName = "DASHSCOPE_BASE_URL" //nolint:gosec
+)
+
+type options struct {
+	token          string
+	model          string
+	embeddingModel string
+	baseURL        string
+	enableSearch   bool
+}
+
+// Option is a functional option for the Qwen client.
+type Option func(*options)
+
+// WithToken 设置 DashScope API key，未设置时读取 DASHSCOPE_API_KEY 环境变量
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithModel 设置模型，未设置时读取 QWEN_MODEL 环境变量，默认 qwen-plus
+func WithModel(model string) Option {
+	return func(o *options) {
+		o.model = model
+	}
+}
+
+// WithEmbeddingModel 设置向量模型，默认 text-embedding-v3
+func WithEmbeddingModel(model string) Option {
+	return func(o *options) {
+		o.embeddingModel = model
+	}
+}
+
+// WithBaseURL 设置接口地址，未设置时读取 DASHSCOPE_BASE_URL 环境变量，默认 https://dashscope.aliyuncs.com/api/v1
+func WithBaseURL(baseURL string) Option {
+	return func(o *options) {
+		o.baseURL = baseURL
+	}
+}
+
+// WithEnableSearch 开启联网搜索，搜索结果会放在 GenerationInfo 的 search_info 中
+func WithEnableSearch(enable bool) Option {
+	return func(o *options) {
+		o.enableSearch = enable
+	}
+}
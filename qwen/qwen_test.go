@@ -0,0 +1,181 @@
+package qwen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+)
+
+func newTestServer(t *testing.T, handler func(path string, sse bool, body map[string]any, w http.ResponseWriter)) *LLM {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"code":"InvalidApiKey","message":"Invalid API-key provided.","request_id":"r0"}`)
+			return
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		handler(strings.TrimPrefix(r.URL.Path, "/api/v1"), r.Header.Get("X-DashScope-SSE") == "enable", body, w)
+	}))
+	t.Cleanup(srv.Close)
+	llm, err := New(WithToken("key"), WithBaseURL(srv.URL+"/api/v1"), WithEnableSearch(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return llm
+}
+
+func TestChat(t *testing.T) {
+	var got map[string]any
+	var gotPath string
+	llm := newTestServer(t, func(path string, _ bool, body map[string]any, w http.ResponseWriter) {
+		got, gotPath = body, path
+		_, _ = io.WriteString(w, `{"request_id":"r1","output":{"choices":[{"finish_reason":"tool_calls","message":{"role":"assistant","content":"",
+			"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"杭州\"}"}}]}}]},
+			"usage":{"input_tokens":20,"output_tokens":8,"total_tokens":28}}`)
+	})
+	tools := []*kpllms.Tool{{Type: schema.ToolCallTypeFunction, Function: &kpllms.FunctionDefinition{Name: "get_weather", Parameters: map[string]any{"type": "object"}}}}
+	resp, err := llm.Chat(context.Background(), []*schema.ChatMessage{
+		{Role: schema.RoleSystem, Content: "你是天气助手"},
+		{Role: schema.RoleUser, Content: "杭州天气"},
+	}, kpllms.WithTools(tools), kpllms.WithJsonMode(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotPath != "/services/aigc/text-generation/generation" || got["model"] != "qwen-plus" {
+		t.Fatalf("path = %s, body = %v", gotPath, got)
+	}
+	params := got["parameters"].(map[string]any)
+	if params["result_format"] != "message" || params["enable_search"] != true || params["incremental_output"] != nil ||
+		params["response_format"].(map[string]any)["type"] != "json_object" || len(params["tools"].([]any)) != 1 {
+		t.Fatalf("unexpected parameters: %v", params)
+	}
+	c := resp.Choices[0]
+	if c.StopReason != "tool_calls" || c.Usage.TotalTokens != 28 || c.GenerationInfo["request_id"] != "r1" {
+		t.Fatalf("unexpected choice: %+v", c)
+	}
+	if len(c.ToolCalls) != 1 || c.ToolCalls[0].Id != "call_1" || c.ToolCalls[0].Function.Arguments != `{"city":"杭州"}` {
+		t.Fatalf("unexpected tool calls: %+v", c.ToolCalls)
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	chunks := []string{
+		`{"output":{"choices":[{"finish_reason":"null","message":{"role":"assistant","content":"你好"}}]},"usage":{"input_tokens":5,"output_tokens":1}}`,
+		`{"output":{"choices":[{"finish_reason":"null","message":{"role":"assistant","content":"","tool_calls":[{"index":0,"id":"call_2","type":"function","function":{"name":"search","arguments":"{\"q\":"}}]}}]}}`,
+		`{"output":{"choices":[{"finish_reason":"null","message":{"role":"assistant","content":"","tool_calls":[{"index":0,"id":"","function":{"arguments":"\"天气\"}"}}]}}]}}`,
+		`{"output":{"choices":[{"finish_reason":"tool_calls","message":{"role":"assistant","content":""}}]},"usage":{"input_tokens":5,"output_tokens":9},"request_id":"r2"}`,
+	}
+	llm := newTestServer(t, func(_ string, sse bool, body map[string]any, w http.ResponseWriter) {
+		if !sse || body["parameters"].(map[string]any)["incremental_output"] != true {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for i, c := range chunks {
+			_, _ = fmt.Fprintf(w, "id:%d\nevent:result\n:HTTP_STATUS/200\ndata:%s\n\n", i+1, c)
+		}
+	})
+	var streamed strings.Builder
+	resp, err := llm.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}},
+		kpllms.WithStreamingFunc(func(ctx context.Context, chunk []byte, innerErr error) error {
+			streamed.Write(chunk)
+			return nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := resp.Choices[0]
+	if streamed.String() != "你好" || c.Content != "你好" || c.StopReason != "tool_calls" || c.Usage.TotalTokens != 14 {
+		t.Fatalf("streamed = %q, choice = %+v, usage = %+v", streamed.String(), c, c.Usage)
+	}
+	if len(c.ToolCalls) != 1 || c.ToolCalls[0].Id != "call_2" || c.ToolCalls[0].Function.Name != "search" || c.ToolCalls[0].Function.Arguments != `{"q":"天气"}` {
+		t.Fatalf("unexpected tool calls: %+v", c.ToolCalls[0])
+	}
+}
+
+func TestChatMultimodal(t *testing.T) {
+	var got map[string]any
+	var gotPath string
+	llm := newTestServer(t, func(path string, _ bool, body map[string]any, w http.ResponseWriter) {
+		got, gotPath = body, path
+		_, _ = io.WriteString(w, `{"output":{"choices":[{"finish_reason":"stop","message":{"role":"assistant","content":[{"text":"一只猫"}]}}]}}`)
+	})
+	resp, err := llm.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: []any{
+		schema.ImageContent{Type: schema.MultiContentImageUrl, ImageUrl: schema.ImageUrl{Url: "https://example.com/cat.png"}},
+		schema.TextContent{Type: schema.MultiContentText, Text: "图里是什么"},
+	}}}, kpllms.WithModel("qwen-vl-plus"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(got["input"])
+	if gotPath != "/services/aigc/multimodal-generation/generation" ||
+		string(b) != `{"messages":[{"content":[{"image":"https://example.com/cat.png"},{"text":"图里是什么"}],"role":"user"}]}` {
+		t.Fatalf("path = %s, input = %s", gotPath, b)
+	}
+	if resp.Choices[0].Content != "一只猫" {
+		t.Fatalf("content = %q", resp.Choices[0].Content)
+	}
+}
+
+func TestChatError(t *testing.T) {
+	llm := newTestServer(t, func(_ string, sse bool, _ map[string]any, w http.ResponseWriter) {
+		if sse {
+			// 流式返回时内容审核失败以 error 事件返回
+			_, _ = io.WriteString(w, "id:1\nevent:error\n:HTTP_STATUS/400\ndata:{\"code\":\"DataInspectionFailed\",\"message\":\"Output data may contain inappropriate content.\",\"request_id\":\"r3\"}\n\n")
+			return
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = io.WriteString(w, `{"code":"Throttling.RateQuota","message":"Requests rate limit exceeded","request_id":"r4"}`)
+	})
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}}
+
+	_, err := llm.Chat(context.Background(), msgs)
+	var httpErr *schema.HttpError
+	if !errors.As(err, &httpErr) || httpErr.Code != http.StatusTooManyRequests || !strings.HasPrefix(httpErr.ErrMsg, "Throttling.RateQuota:") {
+		t.Fatalf("err = %v", err)
+	}
+
+	_, err = llm.Chat(context.Background(), msgs, kpllms.WithStreamingFunc(func(context.Context, []byte, error) error { return nil }))
+	if !errors.As(err, &httpErr) || httpErr.Code != http.StatusBadRequest || !strings.HasPrefix(httpErr.ErrMsg, "DataInspectionFailed:") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	var textTypes []string
+	llm := newTestServer(t, func(path string, _ bool, body map[string]any, w http.ResponseWriter) {
+		if path != "/services/embeddings/text-embedding/text-embedding" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		textTypes = append(textTypes, body["parameters"].(map[string]any)["text_type"].(string))
+		texts := body["input"].(map[string]any)["texts"].([]any)
+		out := make([]map[string]any, 0, len(texts))
+		for i := range texts {
+			out = append(out, map[string]any{"text_index": i, "embedding": []float32{float32(i)}})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"output": map[string]any{"embeddings": out}})
+	})
+	docs, err := llm.EmbedDocuments(context.Background(), make([]string, 12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = llm.EmbedQuery(context.Background(), "q"); err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 12 || docs[11][0] != 1 || strings.Join(textTypes, ",") != "document,document,query" {
+		t.Fatalf("docs = %v, text types = %v", docs, textTypes)
+	}
+}
@@ -0,0 +1,116 @@
+package rag
+
+import "github.com/comqositi/kpllms/internal/tokenutils"
+
+// Strategy 资料组合策略
+type Strategy string
+
+const (
+	// StrategyStuff 将所有资料一次性放入系统提示词，调用一次模型
+	StrategyStuff Strategy = "stuff"
+	// StrategyMapReduce 逐篇提取相关内容，再汇总回答，适合资料较多的场景
+	StrategyMapReduce Strategy = "map_reduce"
+	// StrategyRefine 先用第一篇资料回答，再逐篇完善回答
+	StrategyRefine Strategy = "refine"
+)
+
+const (
+	defaultContextWindow  = 4096
+	defaultMaxTokens      = 512
+	defaultMaxConcurrency = 4
+	defaultModelName      = "gpt-3.5-turbo"
+	// 每条消息的格式开销，与 openai 的计算方式保持一致
+	tokensPerMessage = 4
+)
+
+type options struct {
+	strategy       Strategy
+	stuffTemplate  string
+	mapTemplate    string
+	refineTemplate string
+	contextWindow  int
+	countTokens    func(string) int
+	maxConcurrency int
+}
+
+// Option RAG 链配置
+type Option func(*options)
+
+func defaultOptions() options {
+	return options{
+		strategy:       StrategyStuff,
+		stuffTemplate:  DefaultStuffTemplate,
+		mapTemplate:    DefaultMapTemplate,
+		refineTemplate: DefaultRefineTemplate,
+		contextWindow:  defaultContextWindow,
+		countTokens:    countTokensFor(defaultModelName),
+		maxConcurrency: defaultMaxConcurrency,
+	}
+}
+
+// WithStrategy 设置资料组合策略，默认 StrategyStuff
+func WithStrategy(strategy Strategy) Option {
+	return func(o *options) {
+		o.strategy = strategy
+	}
+}
+
+// WithPromptTemplate 设置 stuff 策略和 map-reduce 汇总阶段的系统提示词模板
+func WithPromptTemplate(tmpl string) Option {
+	return func(o *options) {
+		o.stuffTemplate = tmpl
+	}
+}
+
+// WithMapTemplate 设置 map-reduce 策略中逐篇提取的系统提示词模板
+func WithMapTemplate(tmpl string) Option {
+	return func(o *options) {
+		o.mapTemplate = tmpl
+	}
+}
+
+// WithRefineTemplate 设置 refine 策略的系统提示词模板
+func WithRefineTemplate(tmpl string) Option {
+	return func(o *options) {
+		o.refineTemplate = tmpl
+	}
+}
+
+// WithContextWindow 设置模型的上下文长度（token），默认 4096，
+// 放入提示词的资料不会超过 上下文长度 - 提示词 - 问题 - 最大输出 token（kpllms.WithMaxTokens，默认 512）
+func WithContextWindow(tokens int) Option {
+	return func(o *options) {
+		o.contextWindow = tokens
+	}
+}
+
+// WithModelName 使用该模型对应的 tiktoken 编码计算 token，默认 gpt-3.5-turbo
+func WithModelName(model string) Option {
+	return func(o *options) {
+		o.countTokens = countTokensFor(model)
+	}
+}
+
+// WithTokenCounter 设置自定义的 token 计算方法，适用于非 openai 模型
+func WithTokenCounter(countTokens func(string) int) Option {
+	return func(o *options) {
+		o.countTokens = countTokens
+	}
+}
+
+// WithMaxConcurrency 设置 map 阶段的最大并发数，默认 4
+func WithMaxConcurrency(n int) Option {
+	return func(o *options) {
+		o.maxConcurrency = n
+	}
+}
+
+// countTokensFor 使用 tiktoken 计算 token，词表加载失败时按字符数估算
+func countTokensFor(model string) func(string) int {
+	return func(text string) int {
+		if n := tokenutils.CountTokens(model, text); n > 0 || text == "" {
+			return n
+		}
+		return tokenutils.ApproximateTokens(text)
+	}
+}
@@ -0,0 +1,28 @@
+package rag
+
+// 默认提示词模板，使用 text/template 语法，可用变量：
+// .Context 检索到的资料，已按 [编号] 格式化；.Question 用户问题；.ExistingAnswer 已有回答（仅 refine）
+
+// DefaultStuffTemplate stuff 策略和 map-reduce 汇总阶段的系统提示词
+const DefaultStuffTemplate = `使用以下检索到的资料回答用户的问题。如果资料中没有答案，请直接说不知道，不要编造。回答时使用 [编号] 标注引用的资料。
+
+资料：
+{{.Context}}`
+
+// DefaultMapTemplate map-reduce 策略中逐篇提取资料的系统提示词
+const DefaultMapTemplate = `根据以下资料，提取与用户问题相关的内容，保留关键信息和原文表述。如果资料与问题无关，只回复：` + NoRelevantContent + `
+
+资料：
+{{.Context}}`
+
+// DefaultRefineTemplate refine 策略中逐篇完善回答的系统提示词
+const DefaultRefineTemplate = `已有回答：
+{{.ExistingAnswer}}
+
+以下是新的资料。如果新资料有帮助，请据此完善已有回答；如果没有帮助，请原样返回已有回答。回答时使用 [编号] 标注引用的资料。
+
+资料：
+{{.Context}}`
+
+// NoRelevantContent map 阶段判定资料与问题无关时的回复，该资料不进入汇总阶段
+const NoRelevantContent = "无相关内容"
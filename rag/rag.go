@@ -0,0 +1,168 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/retriever"
+	"github.com/comqositi/kpllms/schema"
+)
+
+var (
+	ErrEmptyResponse         = errors.New("rag: no response")
+	ErrNoDocuments           = errors.New("rag: 没有检索到资料")
+	ErrContextWindowExceeded = errors.New("rag: 提示词和问题已超过模型上下文长度")
+)
+
+// Chain 检索增强生成：检索资料，放入系统提示词，调用模型回答，并返回引用的资料
+type Chain struct {
+	retriever retriever.Retriever
+	model     kpllms.Model
+	opts      options
+
+	stuffTmpl  *template.Template
+	mapTmpl    *template.Template
+	refineTmpl *template.Template
+}
+
+// Result RAG 调用结果
+type Result struct {
+	// 最终回答
+	Answer string
+	// 实际放入提示词的资料，编号与回答中的 [编号] 对应，从 1 开始
+	SourceDocuments []schema.Document
+	// 产生最终回答的那一次模型调用的返回
+	Response *schema.ContentResponse
+	// 所有模型调用的 token 消耗之和
+	Usage *schema.Usage
+}
+
+// promptData 模板变量
+type promptData struct {
+	Context        string
+	Question       string
+	ExistingAnswer string
+}
+
+// New 创建 RAG 链
+func New(r retriever.Retriever, model kpllms.Model, opts ...Option) (*Chain, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	switch o.strategy {
+	case StrategyStuff, StrategyMapReduce, StrategyRefine:
+	default:
+		return nil, fmt.Errorf("rag: strategy %v not supported", o.strategy)
+	}
+	if o.maxConcurrency <= 0 {
+		o.maxConcurrency = 1
+	}
+
+	c := &Chain{retriever: r, model: model, opts: o}
+	var err error
+	if c.stuffTmpl, err = template.New("stuff").Parse(o.stuffTemplate); err != nil {
+		return nil, fmt.Errorf("rag: parse prompt template: %w", err)
+	}
+	if c.mapTmpl, err = template.New("map").Parse(o.mapTemplate); err != nil {
+		return nil, fmt.Errorf("rag: parse map template: %w", err)
+	}
+	if c.refineTmpl, err = template.New("refine").Parse(o.refineTemplate); err != nil {
+		return nil, fmt.Errorf("rag: parse refine template: %w", err)
+	}
+	return c, nil
+}
+
+// Call 检索并回答问题，options 会传给每一次模型调用，
+// StreamingFunc 只在产生最终回答的那一次调用中生效
+func (c *Chain) Call(ctx context.Context, question string, options ...kpllms.CallOption) (*Result, error) {
+	docs, err := c.retriever.GetRelevantDocuments(ctx, question)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, ErrNoDocuments
+	}
+
+	result := &Result{Usage: &schema.Usage{}}
+	switch c.opts.strategy {
+	case StrategyMapReduce:
+		err = c.mapReduce(ctx, question, docs, options, result)
+	case StrategyRefine:
+		err = c.refine(ctx, question, docs, options, result)
+	default:
+		err = c.stuff(ctx, question, docs, options, result)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// chat 渲染系统提示词并调用模型，final 为 false 时关闭流式输出
+func (c *Chain) chat(ctx context.Context, tmpl *template.Template, data promptData, options []kpllms.CallOption, final bool, result *Result) (string, error) {
+	system, err := render(tmpl, data)
+	if err != nil {
+		return "", err
+	}
+	messages := []*schema.ChatMessage{
+		{Role: schema.RoleSystem, Content: system},
+		{Role: schema.RoleUser, Content: data.Question},
+	}
+	if !final {
+		options = append(options[:len(options):len(options)], kpllms.WithStreamingFunc(nil))
+	}
+	resp, err := c.model.Chat(ctx, messages, options...)
+	if err != nil {
+		return "", err
+	}
+	if resp == nil || len(resp.Choices) == 0 {
+		return "", ErrEmptyResponse
+	}
+	if u := resp.Choices[0].Usage; u != nil {
+		result.Usage.PromptTokens += u.PromptTokens
+		result.Usage.CompletionTokens += u.CompletionTokens
+		result.Usage.TotalTokens += u.TotalTokens
+	}
+	result.Response = resp
+	return resp.Choices[0].Content, nil
+}
+
+// budget 返回可用于资料的 token 数，template 为空资料时的系统提示词
+func (c *Chain) budget(tmpl *template.Template, data promptData, options []kpllms.CallOption) (int, error) {
+	opts := kpllms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	maxTokens := kpllms.Deref(opts.MaxTokens)
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+	data.Context = ""
+	system, err := render(tmpl, data)
+	if err != nil {
+		return 0, err
+	}
+	used := c.opts.countTokens(system) + c.opts.countTokens(data.Question) + tokensPerMessage*2 + maxTokens
+	if used >= c.opts.contextWindow {
+		return 0, ErrContextWindowExceeded
+	}
+	return c.opts.contextWindow - used, nil
+}
+
+// formatDocument 按 [编号] 格式化资料，编号从 1 开始
+func formatDocument(index int, doc schema.Document) string {
+	return fmt.Sprintf("[%d] %s", index, strings.TrimSpace(doc.PageContent))
+}
+
+func render(tmpl *template.Template, data promptData) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("rag: render %s template: %w", tmpl.Name(), err)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,175 @@
+package rag
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+)
+
+type staticRetriever []schema.Document
+
+func (r staticRetriever) GetRelevantDocuments(context.Context, string) ([]schema.Document, error) {
+	return r, nil
+}
+
+// echoModel 记录每次调用的系统提示词和是否流式，回复由 reply 生成
+type echoModel struct {
+	mu       sync.Mutex
+	systems  []string
+	streamed int
+	reply    func(system string) string
+}
+
+func (m *echoModel) Chat(ctx context.Context, messages []*schema.ChatMessage, options ...kpllms.CallOption) (*schema.ContentResponse, error) {
+	opts := kpllms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	system := messages[0].Content.(string)
+	answer := m.reply(system)
+	m.mu.Lock()
+	m.systems = append(m.systems, system)
+	m.mu.Unlock()
+	if opts.StreamingFunc != nil {
+		m.mu.Lock()
+		m.streamed++
+		m.mu.Unlock()
+		if err := opts.StreamingFunc(ctx, []byte(answer), nil); err != nil {
+			return nil, err
+		}
+	}
+	return &schema.ContentResponse{Choices: []*schema.ContentChoice{{
+		Content: answer,
+		Usage:   &schema.Usage{PromptTokens: 10, CompletionTokens: 1, TotalTokens: 11},
+	}}}, nil
+}
+
+var testDocs = staticRetriever{
+	{Id: "1", PageContent: "靠谱扫地机器人 KP-X200 续航 120 分钟。"},
+	{Id: "2", PageContent: "今天天气晴。"},
+	{Id: "3", PageContent: "KP-X200 充电时间 4 小时。"},
+}
+
+func runeCounter(s string) int { return utf8.RuneCountInString(s) }
+
+func TestStuff(t *testing.T) {
+	model := &echoModel{reply: func(string) string { return "续航 120 分钟 [1]" }}
+	chain, err := New(testDocs, model, WithTokenCounter(runeCounter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed strings.Builder
+	res, err := chain.Call(context.Background(), "KP-X200 续航多久？", kpllms.WithStreamingFunc(func(ctx context.Context, chunk []byte, innerErr error) error {
+		streamed.Write(chunk)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Answer != "续航 120 分钟 [1]" || streamed.String() != res.Answer {
+		t.Fatalf("unexpected answer %q, streamed %q", res.Answer, streamed.String())
+	}
+	if len(res.SourceDocuments) != 3 || !strings.Contains(model.systems[0], "[3] KP-X200 充电时间 4 小时。") {
+		t.Fatalf("unexpected prompt %q", model.systems[0])
+	}
+}
+
+func TestStuffContextWindow(t *testing.T) {
+	model := &echoModel{reply: func(string) string { return "ok" }}
+	question := "KP-X200 续航多久？"
+	promptTokens := runeCounter(strings.Replace(DefaultStuffTemplate, "{{.Context}}", "", 1)) + runeCounter(question) + tokensPerMessage*2
+	// 只够放下第一篇资料
+	window := promptTokens + 100 + runeCounter("[1] "+testDocs[0].PageContent+"\n\n")
+	chain, err := New(testDocs, model, WithTokenCounter(runeCounter), WithContextWindow(window))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := chain.Call(context.Background(), question, kpllms.WithMaxTokens(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.SourceDocuments) != 1 || res.SourceDocuments[0].Id != "1" {
+		t.Fatalf("unexpected source documents %+v", res.SourceDocuments)
+	}
+
+	chain, _ = New(testDocs, model, WithTokenCounter(runeCounter), WithContextWindow(promptTokens+100))
+	if _, err = chain.Call(context.Background(), question, kpllms.WithMaxTokens(100)); err != ErrContextWindowExceeded {
+		t.Fatalf("expected ErrContextWindowExceeded, got %v", err)
+	}
+}
+
+func TestMapReduce(t *testing.T) {
+	model := &echoModel{reply: func(system string) string {
+		switch {
+		case strings.Contains(system, "天气"):
+			return NoRelevantContent + "。"
+		case strings.Contains(system, "提取"):
+			return strings.TrimSpace(system[strings.LastIndex(system, "]")+1:])
+		default:
+			return "续航 120 分钟 [1]，充电 4 小时 [2]"
+		}
+	}}
+	chain, err := New(testDocs, model, WithStrategy(StrategyMapReduce), WithTokenCounter(runeCounter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := chain.Call(context.Background(), "KP-X200 参数", kpllms.WithStreamingFunc(func(context.Context, []byte, error) error {
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(model.systems) != 4 || model.streamed != 1 {
+		t.Fatalf("unexpected calls %d, streamed %d", len(model.systems), model.streamed)
+	}
+	if len(res.SourceDocuments) != 2 || res.SourceDocuments[0].Id != "1" || res.SourceDocuments[1].Id != "3" {
+		t.Fatalf("unexpected source documents %+v", res.SourceDocuments)
+	}
+	if res.Usage.TotalTokens != 44 {
+		t.Fatalf("unexpected usage %+v", res.Usage)
+	}
+}
+
+func TestMapReduceNoRelevantDocuments(t *testing.T) {
+	model := &echoModel{reply: func(string) string { return NoRelevantContent }}
+	chain, err := New(testDocs, model, WithStrategy(StrategyMapReduce), WithTokenCounter(runeCounter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = chain.Call(context.Background(), "KP-X200 参数"); err != ErrNoDocuments {
+		t.Fatalf("expected ErrNoDocuments, got %v", err)
+	}
+	// 只有 map 阶段的调用
+	if len(model.systems) != len(testDocs) {
+		t.Fatalf("unexpected calls %d", len(model.systems))
+	}
+}
+
+func TestRefine(t *testing.T) {
+	calls := 0
+	model := &echoModel{reply: func(system string) string {
+		calls++
+		return strings.Repeat("答", calls)
+	}}
+	chain, err := New(testDocs, model, WithStrategy(StrategyRefine), WithTokenCounter(runeCounter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := chain.Call(context.Background(), "KP-X200 参数", kpllms.WithStreamingFunc(func(context.Context, []byte, error) error {
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Answer != "答答答" || model.streamed != 1 || len(res.SourceDocuments) != 3 {
+		t.Fatalf("unexpected result %q, streamed %d", res.Answer, model.streamed)
+	}
+	if !strings.Contains(model.systems[2], "已有回答：\n答答") || !strings.Contains(model.systems[2], "[3] KP-X200") {
+		t.Fatalf("unexpected refine prompt %q", model.systems[2])
+	}
+}
@@ -0,0 +1,155 @@
+package rag
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+)
+
+// stuff 在上下文长度允许的范围内，按检索顺序放入尽可能多的资料，调用一次模型
+func (c *Chain) stuff(ctx context.Context, question string, docs []schema.Document, options []kpllms.CallOption, result *Result) error {
+	data := promptData{Question: question}
+	budget, err := c.budget(c.stuffTmpl, data, options)
+	if err != nil {
+		return err
+	}
+	parts := make([]string, 0, len(docs))
+	for _, d := range docs {
+		part := formatDocument(len(parts)+1, d)
+		n := c.opts.countTokens(part + "\n\n")
+		if n > budget {
+			continue
+		}
+		budget -= n
+		parts = append(parts, part)
+		result.SourceDocuments = append(result.SourceDocuments, d)
+	}
+	if len(parts) == 0 {
+		return ErrContextWindowExceeded
+	}
+	data.Context = strings.Join(parts, "\n\n")
+	result.Answer, err = c.chat(ctx, c.stuffTmpl, data, options, true, result)
+	return err
+}
+
+// mapReduce 逐篇并发提取与问题相关的内容，丢弃无关资料后汇总回答
+func (c *Chain) mapReduce(ctx context.Context, question string, docs []schema.Document, options []kpllms.CallOption, result *Result) error {
+	mapBudget, err := c.budget(c.mapTmpl, promptData{Question: question}, options)
+	if err != nil {
+		return err
+	}
+
+	outputs := make([]string, len(docs))
+	errs := make([]error, len(docs))
+	usages := make([]*Result, len(docs))
+	sem := make(chan struct{}, c.opts.maxConcurrency)
+	var wg sync.WaitGroup
+	for i, d := range docs {
+		part := formatDocument(1, d)
+		if c.opts.countTokens(part) > mapBudget {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, part string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			usages[i] = &Result{Usage: &schema.Usage{}}
+			outputs[i], errs[i] = c.chat(ctx, c.mapTmpl, promptData{Context: part, Question: question}, options, false, usages[i])
+		}(i, part)
+	}
+	wg.Wait()
+	for i := range docs {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		if usages[i] != nil {
+			result.Usage.PromptTokens += usages[i].Usage.PromptTokens
+			result.Usage.CompletionTokens += usages[i].Usage.CompletionTokens
+			result.Usage.TotalTokens += usages[i].Usage.TotalTokens
+		}
+	}
+
+	data := promptData{Question: question}
+	budget, err := c.budget(c.stuffTmpl, data, options)
+	if err != nil {
+		return err
+	}
+	parts := make([]string, 0, len(docs))
+	relevant := 0
+	for i, d := range docs {
+		out := strings.TrimSpace(outputs[i])
+		if out == "" || strings.TrimRight(out, "。.") == NoRelevantContent {
+			continue
+		}
+		relevant++
+		part := formatDocument(len(parts)+1, schema.Document{PageContent: out})
+		n := c.opts.countTokens(part + "\n\n")
+		if n > budget {
+			continue
+		}
+		budget -= n
+		parts = append(parts, part)
+		result.SourceDocuments = append(result.SourceDocuments, d)
+	}
+	// 所有资料都与问题无关时不再调用模型汇总
+	if relevant == 0 {
+		return ErrNoDocuments
+	}
+	if len(parts) == 0 {
+		return ErrContextWindowExceeded
+	}
+	data.Context = strings.Join(parts, "\n\n")
+	result.Answer, err = c.chat(ctx, c.stuffTmpl, data, options, true, result)
+	return err
+}
+
+// refine 用第一篇资料生成回答，再用后续资料逐篇完善
+func (c *Chain) refine(ctx context.Context, question string, docs []schema.Document, options []kpllms.CallOption, result *Result) error {
+	answered, streamed := false, false
+	for i, d := range docs {
+		tmpl := c.stuffTmpl
+		data := promptData{Question: question, ExistingAnswer: result.Answer}
+		if answered {
+			tmpl = c.refineTmpl
+		}
+		budget, err := c.budget(tmpl, data, options)
+		if err != nil {
+			if answered {
+				// 已有回答过长时停止完善
+				break
+			}
+			return err
+		}
+		part := formatDocument(len(result.SourceDocuments)+1, d)
+		if c.opts.countTokens(part) > budget {
+			continue
+		}
+		data.Context = part
+		final := i == len(docs)-1
+		answer, err := c.chat(ctx, tmpl, data, options, final, result)
+		if err != nil {
+			return err
+		}
+		result.Answer = answer
+		result.SourceDocuments = append(result.SourceDocuments, d)
+		answered, streamed = true, final
+	}
+	if !answered {
+		return ErrContextWindowExceeded
+	}
+	if !streamed {
+		// 最后几篇资料被跳过时，最终回答没有经过流式输出，此处一次性输出
+		opts := kpllms.CallOptions{}
+		for _, opt := range options {
+			opt(&opts)
+		}
+		if opts.StreamingFunc != nil {
+			return opts.StreamingFunc(ctx, []byte(result.Answer), nil)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package reranker
+
+import (
+	"context"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+	"github.com/comqositi/kpllms/vectorstore"
+)
+
+// Embedding 使用向量余弦相似度重新打分，适用于召回阶段不是向量检索（例如 BM25）的场景
+type Embedding struct {
+	embedder kpllms.Embedder
+	opts     options
+}
+
+var _ Reranker = (*Embedding)(nil)
+
+// NewEmbedding 创建向量重排序器
+func NewEmbedding(embedder kpllms.Embedder, opts ...Option) *Embedding {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Embedding{embedder: embedder, opts: o}
+}
+
+// Rerank 实现 Reranker 接口
+func (e *Embedding) Rerank(ctx context.Context, query string, docs []schema.Document) ([]schema.Document, error) {
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	q, err := e.embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	texts := make([]string, 0, len(docs))
+	for _, d := range docs {
+		texts = append(texts, d.PageContent)
+	}
+	vectors, err := e.embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(docs) {
+		return nil, ErrUnexpectedResponseLength
+	}
+	out := make([]schema.Document, len(docs))
+	for i, d := range docs {
+		d.Score = vectorstore.CosineSimilarity(q, vectors[i])
+		out[i] = d
+	}
+	return sortAndCut(out, e.opts.topN), nil
+}
@@ -0,0 +1,86 @@
+package reranker
+
+import (
+	"context"
+	"strings"
+
+	"github.com/comqositi/kpllms/internal/httputils"
+	"github.com/comqositi/kpllms/schema"
+)
+
+// HTTP 调用 Cohere / Jina 等兼容的 /rerank 接口
+type HTTP struct {
+	opts options
+}
+
+var _ Reranker = (*HTTP)(nil)
+
+// rerankRequest Cohere 和 Jina 通用的请求格式
+type rerankRequest struct {
+	Model           string   `json:"model,omitempty"`
+	Query           string   `json:"query"`
+	Documents       []string `json:"documents"`
+	TopN            int      `json:"top_n,omitempty"`
+	ReturnDocuments bool     `json:"return_documents"`
+}
+
+type rerankResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float32 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+// NewHTTP 创建 http 重排序器
+func NewHTTP(opts ...Option) (*HTTP, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.baseURL == "" {
+		return nil, ErrMissingBaseURL
+	}
+	o.baseURL = strings.TrimSuffix(o.baseURL, "/")
+	return &HTTP{opts: o}, nil
+}
+
+// Rerank 实现 Reranker 接口，接口只返回部分结果时，只保留返回的文档
+func (h *HTTP) Rerank(ctx context.Context, query string, docs []schema.Document) ([]schema.Document, error) {
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	req := &rerankRequest{
+		Model:     h.opts.model,
+		Query:     query,
+		Documents: make([]string, 0, len(docs)),
+		TopN:      h.opts.topN,
+	}
+	for _, d := range docs {
+		req.Documents = append(req.Documents, d.PageContent)
+	}
+	var resp rerankResponse
+	if err := httputils.HttpPost(ctx, h.opts.baseURL+"/rerank", req, h.setHeaders(), &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Results) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	out := make([]schema.Document, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		if r.Index < 0 || r.Index >= len(docs) {
+			return nil, ErrUnexpectedResponseLength
+		}
+		d := docs[r.Index]
+		d.Score = r.RelevanceScore
+		out = append(out, d)
+	}
+	return sortAndCut(out, h.opts.topN), nil
+}
+
+func (h *HTTP) setHeaders() map[string]string {
+	m := map[string]string{"Content-Type": "application/json"}
+	if h.opts.token != "" {
+		m["Authorization"] = "Bearer " + h.opts.token
+	}
+	return m
+}
@@ -0,0 +1,146 @@
+package reranker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+)
+
+// 大模型打分的满分，得分会归一化到 0-1
+const maxLLMScore = 10
+
+const pointwisePrompt = `你是检索结果相关性评估助手。根据用户问题判断资料与问题的相关程度，给出 0-10 的整数分数，10 表示资料能完整回答问题，0 表示完全无关。
+只返回 JSON，格式：{"score": 分数}`
+
+const listwisePrompt = `你是检索结果相关性评估助手。根据用户问题，对下面每一篇编号资料分别判断相关程度，给出 0-10 的整数分数，10 表示资料能完整回答问题，0 表示完全无关。
+只返回 JSON，格式：{"scores": [{"index": 编号, "score": 分数}]}，需要包含所有编号。`
+
+// LLM 使用大模型作为评委打分，开启 JsonMode 要求模型返回 JSON
+type LLM struct {
+	model kpllms.Model
+	opts  options
+}
+
+var _ Reranker = (*LLM)(nil)
+
+// NewLLM 创建大模型重排序器，模型调用的参数通过 WithCallOptions 设置
+func NewLLM(model kpllms.Model, opts ...Option) *LLM {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.maxConcurrency <= 0 {
+		o.maxConcurrency = 1
+	}
+	return &LLM{model: model, opts: o}
+}
+
+// Rerank 实现 Reranker 接口
+func (l *LLM) Rerank(ctx context.Context, query string, docs []schema.Document) ([]schema.Document, error) {
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	var scores []float32
+	var err error
+	switch l.opts.mode {
+	case ModeListwise:
+		scores, err = l.listwise(ctx, query, docs)
+	case ModePointwise:
+		scores, err = l.pointwise(ctx, query, docs)
+	default:
+		return nil, fmt.Errorf("reranker: mode %v not supported", l.opts.mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := make([]schema.Document, len(docs))
+	for i, d := range docs {
+		d.Score = scores[i] / maxLLMScore
+		out[i] = d
+	}
+	return sortAndCut(out, l.opts.topN), nil
+}
+
+// pointwise 逐篇并发打分
+func (l *LLM) pointwise(ctx context.Context, query string, docs []schema.Document) ([]float32, error) {
+	scores := make([]float32, len(docs))
+	errs := make([]error, len(docs))
+	sem := make(chan struct{}, l.opts.maxConcurrency)
+	var wg sync.WaitGroup
+	for i, d := range docs {
+		wg.Add(1)
+		go func(i int, d schema.Document) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			var result struct {
+				Score float32 `json:"score"`
+			}
+			errs[i] = l.judge(ctx, pointwisePrompt, fmt.Sprintf("问题：%s\n\n资料：%s", query, d.PageContent), &result)
+			scores[i] = result.Score
+		}(i, d)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return scores, nil
+}
+
+// listwise 一次性对所有资料打分，缺失的编号按 0 分处理
+func (l *LLM) listwise(ctx context.Context, query string, docs []schema.Document) ([]float32, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "问题：%s\n\n资料：", query)
+	for i, d := range docs {
+		fmt.Fprintf(&sb, "\n[%d] %s", i+1, strings.TrimSpace(d.PageContent))
+	}
+	var result struct {
+		Scores []struct {
+			Index int     `json:"index"`
+			Score float32 `json:"score"`
+		} `json:"scores"`
+	}
+	if err := l.judge(ctx, listwisePrompt, sb.String(), &result); err != nil {
+		return nil, err
+	}
+	scores := make([]float32, len(docs))
+	for _, s := range result.Scores {
+		if s.Index >= 1 && s.Index <= len(docs) {
+			scores[s.Index-1] = s.Score
+		}
+	}
+	return scores, nil
+}
+
+// judge 调用模型并解析 JSON 结果
+func (l *LLM) judge(ctx context.Context, system, user string, result any) error {
+	messages := []*schema.ChatMessage{
+		{Role: schema.RoleSystem, Content: system},
+		{Role: schema.RoleUser, Content: user},
+	}
+	options := append(l.opts.callOptions[:len(l.opts.callOptions):len(l.opts.callOptions)], kpllms.WithJsonMode(true), kpllms.WithStreamingFunc(nil))
+	resp, err := l.model.Chat(ctx, messages, options...)
+	if err != nil {
+		return err
+	}
+	if resp == nil || len(resp.Choices) == 0 {
+		return ErrEmptyResponse
+	}
+	content := resp.Choices[0].Content
+	// 部分模型即使开启 JsonMode 也会包裹 ```json 代码块，只取第一个 { 到最后一个 } 之间的内容
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return fmt.Errorf("reranker: unexpected judge response: %s", content)
+	}
+	if err = json.Unmarshal([]byte(content[start:end+1]), result); err != nil {
+		return fmt.Errorf("reranker: unexpected judge response: %w", err)
+	}
+	return nil
+}
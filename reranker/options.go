@@ -0,0 +1,102 @@
+package reranker
+
+import (
+	"os"
+
+	"github.com/comqositi/kpllms"
+)
+
+const (
+	tokenEnvVarName   = "RERANK_API_KEY"  //nolint:gosec
+	baseURLEnvVarName = "RERANK_BASE_URL" //nolint:gosec
+	modelEnvVarName   = "RERANK_MODEL"    //nolint:gosec
+
+	defaultMaxConcurrency = 4
+)
+
+// Mode 大模型打分方式
+type Mode string
+
+const (
+	// ModePointwise 逐篇打分，每篇文档调用一次模型
+	ModePointwise Mode = "pointwise"
+	// ModeListwise 一次性对所有文档打分，只调用一次模型
+	ModeListwise Mode = "listwise"
+)
+
+type options struct {
+	topN int
+
+	// 大模型打分
+	mode           Mode
+	maxConcurrency int
+	callOptions    []kpllms.CallOption
+
+	// http 接口
+	baseURL string
+	token   string
+	model   string
+}
+
+// Option 重排序配置，各实现只读取与自己相关的配置
+type Option func(*options)
+
+func defaultOptions() options {
+	return options{
+		mode:           ModePointwise,
+		maxConcurrency: defaultMaxConcurrency,
+		baseURL:        os.Getenv(baseURLEnvVarName),
+		token:          os.Getenv(tokenEnvVarName),
+		model:          os.Getenv(modelEnvVarName),
+	}
+}
+
+// WithTopN 只返回得分最高的 n 篇文档，0 表示全部返回
+func WithTopN(n int) Option {
+	return func(o *options) {
+		o.topN = n
+	}
+}
+
+// WithMode 设置大模型打分方式，默认 ModePointwise
+func WithMode(mode Mode) Option {
+	return func(o *options) {
+		o.mode = mode
+	}
+}
+
+// WithMaxConcurrency 设置逐篇打分时的最大并发数，默认 4
+func WithMaxConcurrency(n int) Option {
+	return func(o *options) {
+		o.maxConcurrency = n
+	}
+}
+
+// WithCallOptions 大模型打分时传给每一次模型调用的参数，例如 kpllms.WithModel，多次使用时追加
+func WithCallOptions(callOptions ...kpllms.CallOption) Option {
+	return func(o *options) {
+		o.callOptions = append(o.callOptions, callOptions...)
+	}
+}
+
+// WithBaseURL 设置 rerank 接口地址，请求 {baseURL}/rerank，例如 https://api.jina.ai/v1，
+// 未设置时读取 RERANK_BASE_URL 环境变量
+func WithBaseURL(baseURL string) Option {
+	return func(o *options) {
+		o.baseURL = baseURL
+	}
+}
+
+// WithToken 设置 rerank 接口的 API key，未设置时读取 RERANK_API_KEY 环境变量
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithModel 设置 rerank 模型，例如 jina-reranker-v2-base-multilingual，未设置时读取 RERANK_MODEL 环境变量
+func WithModel(model string) Option {
+	return func(o *options) {
+		o.model = model
+	}
+}
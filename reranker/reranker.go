@@ -0,0 +1,54 @@
+package reranker
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/comqositi/kpllms/retriever"
+	"github.com/comqositi/kpllms/schema"
+)
+
+var (
+	ErrEmptyResponse            = errors.New("reranker: no response")
+	ErrMissingBaseURL           = errors.New("reranker: 缺少 rerank 接口地址，请设置 RERANK_BASE_URL 环境变量或使用 WithBaseURL")
+	ErrUnexpectedResponseLength = errors.New("reranker: unexpected length of response")
+)
+
+// Reranker 重排序接口：输入问题和候选文档，返回按相关性从高到低排序的文档，Score 为重排序得分
+type Reranker interface {
+	Rerank(ctx context.Context, query string, docs []schema.Document) ([]schema.Document, error)
+}
+
+// sortAndCut 按得分降序排序，得分相同时保持原顺序，并截取前 topN 个
+func sortAndCut(docs []schema.Document, topN int) []schema.Document {
+	sort.SliceStable(docs, func(i, j int) bool {
+		return docs[i].Score > docs[j].Score
+	})
+	if topN > 0 && len(docs) > topN {
+		docs = docs[:topN]
+	}
+	return docs
+}
+
+// Retriever 在检索器之后追加重排序
+type Retriever struct {
+	base     retriever.Retriever
+	reranker Reranker
+}
+
+var _ retriever.Retriever = (*Retriever)(nil)
+
+// NewRetriever 创建带重排序的检索器，base 负责召回，reranker 负责精排
+func NewRetriever(base retriever.Retriever, reranker Reranker) *Retriever {
+	return &Retriever{base: base, reranker: reranker}
+}
+
+// GetRelevantDocuments 实现 retriever.Retriever 接口
+func (r *Retriever) GetRelevantDocuments(ctx context.Context, query string) ([]schema.Document, error) {
+	docs, err := r.base.GetRelevantDocuments(ctx, query)
+	if err != nil || len(docs) == 0 {
+		return docs, err
+	}
+	return r.reranker.Rerank(ctx, query, docs)
+}
@@ -0,0 +1,184 @@
+package reranker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+)
+
+var testDocs = []schema.Document{
+	{Id: "a", PageContent: "苹果是一种水果"},
+	{Id: "b", PageContent: "北京是中国的首都"},
+	{Id: "c", PageContent: "香蕉富含钾元素"},
+}
+
+// keywordEmbedder 以是否包含关键词作为向量的维度
+type keywordEmbedder []string
+
+func (e keywordEmbedder) embed(text string) []float32 {
+	v := make([]float32, len(e)+1)
+	v[len(e)] = 0.1
+	for i, k := range e {
+		if strings.Contains(text, k) {
+			v[i] = 1
+		}
+	}
+	return v
+}
+
+func (e keywordEmbedder) EmbedDocuments(_ context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		out[i] = e.embed(t)
+	}
+	return out, nil
+}
+
+func (e keywordEmbedder) EmbedQuery(_ context.Context, text string) ([]float32, error) {
+	return e.embed(text), nil
+}
+
+func TestEmbedding(t *testing.T) {
+	r := NewEmbedding(keywordEmbedder{"首都", "水果"}, WithTopN(2))
+	docs, err := r.Rerank(context.Background(), "中国的首都在哪", testDocs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 || docs[0].Id != "b" {
+		t.Fatalf("unexpected result: %+v", docs)
+	}
+	if docs[0].Score <= docs[1].Score {
+		t.Fatalf("scores not sorted: %+v", docs)
+	}
+}
+
+// judgeModel 根据资料内容返回打分 JSON
+type judgeModel struct {
+	mu       sync.Mutex
+	calls    int
+	jsonMode bool
+	model    string
+	reply    func(user string) string
+}
+
+func (m *judgeModel) Chat(_ context.Context, messages []*schema.ChatMessage, options ...kpllms.CallOption) (*schema.ContentResponse, error) {
+	opts := kpllms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	m.mu.Lock()
+	m.calls++
+	m.jsonMode = opts.JsonMode
+	m.model = opts.Model
+	m.mu.Unlock()
+	user := messages[len(messages)-1].Content.(string)
+	return &schema.ContentResponse{Choices: []*schema.ContentChoice{{Content: m.reply(user)}}}, nil
+}
+
+func TestLLMPointwise(t *testing.T) {
+	m := &judgeModel{reply: func(user string) string {
+		if strings.Contains(user, "香蕉") {
+			return "```json\n{\"score\": 9}\n```"
+		}
+		return `{"score": 2}`
+	}}
+	r := NewLLM(m, WithMaxConcurrency(2))
+	docs, err := r.Rerank(context.Background(), "哪种水果含钾", testDocs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.calls != len(testDocs) || !m.jsonMode {
+		t.Fatalf("calls = %d, jsonMode = %v", m.calls, m.jsonMode)
+	}
+	if docs[0].Id != "c" || docs[0].Score != 0.9 {
+		t.Fatalf("unexpected result: %+v", docs)
+	}
+	// 同分时保持原顺序
+	if docs[1].Id != "a" || docs[2].Id != "b" {
+		t.Fatalf("unexpected order: %+v", docs)
+	}
+}
+
+func TestLLMListwise(t *testing.T) {
+	m := &judgeModel{reply: func(string) string {
+		return `{"scores": [{"index": 2, "score": 8}, {"index": 1, "score": 5}]}`
+	}}
+	r := NewLLM(m, WithMode(ModeListwise), WithTopN(2), WithCallOptions(kpllms.WithModel("judge")))
+	docs, err := r.Rerank(context.Background(), "首都", testDocs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.calls != 1 || m.model != "judge" {
+		t.Fatalf("calls = %d, model = %q", m.calls, m.model)
+	}
+	if len(docs) != 2 || docs[0].Id != "b" || docs[1].Id != "a" {
+		t.Fatalf("unexpected result: %+v", docs)
+	}
+}
+
+func TestLLMInvalidResponse(t *testing.T) {
+	m := &judgeModel{reply: func(string) string { return "无法判断" }}
+	if _, err := NewLLM(m).Rerank(context.Background(), "q", testDocs); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	var got rerankRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/rerank" || r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"results":[{"index":2,"relevance_score":0.3},{"index":0,"relevance_score":0.95}]}`))
+	}))
+	defer srv.Close()
+
+	r, err := NewHTTP(WithBaseURL(srv.URL+"/v1/"), WithToken("key"), WithModel("bge-reranker"), WithTopN(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := r.Rerank(context.Background(), "水果", testDocs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Model != "bge-reranker" || got.TopN != 2 || len(got.Documents) != len(testDocs) {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+	if len(docs) != 2 || docs[0].Id != "a" || docs[1].Id != "c" || docs[0].Score != 0.95 {
+		t.Fatalf("unexpected result: %+v", docs)
+	}
+}
+
+func TestHTTPMissingBaseURL(t *testing.T) {
+	t.Setenv(baseURLEnvVarName, "")
+	if _, err := NewHTTP(); !errors.Is(err, ErrMissingBaseURL) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+type staticRetriever []schema.Document
+
+func (r staticRetriever) GetRelevantDocuments(context.Context, string) ([]schema.Document, error) {
+	return r, nil
+}
+
+func TestRetriever(t *testing.T) {
+	r := NewRetriever(staticRetriever(testDocs), NewEmbedding(keywordEmbedder{"水果"}, WithTopN(1)))
+	docs, err := r.GetRelevantDocuments(context.Background(), "水果")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || docs[0].Id != "a" {
+		t.Fatalf("unexpected result: %+v", docs)
+	}
+}
@@ -0,0 +1,193 @@
+package retriever
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/comqositi/kpllms/schema"
+	"github.com/comqositi/kpllms/vectorstore"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultBM25K1 = 1.2
+	defaultBM25B  = 0.75
+)
+
+// BM25 关键词倒排索引，适合产品编码、专有名词等需要精确匹配的场景
+type BM25 struct {
+	tokenizer Tokenizer
+	k1        float64
+	b         float64
+
+	mu       sync.RWMutex
+	docs     map[string]*bm25Doc
+	postings map[string]map[string]int // 词 -> 文档 id -> 词频
+	totalLen int
+}
+
+type bm25Doc struct {
+	doc    schema.Document
+	terms  map[string]int
+	length int
+}
+
+type bm25Options struct {
+	tokenizer Tokenizer
+	k1        float64
+	b         float64
+}
+
+// BM25Option BM25 索引配置
+type BM25Option func(*bm25Options)
+
+// WithTokenizer 设置分词器，默认使用不带词典的 CJKTokenizer
+func WithTokenizer(tokenizer Tokenizer) BM25Option {
+	return func(o *bm25Options) {
+		o.tokenizer = tokenizer
+	}
+}
+
+// WithBM25Params 设置 BM25 的 k1 和 b 参数，默认 1.2 和 0.75
+func WithBM25Params(k1, b float64) BM25Option {
+	return func(o *bm25Options) {
+		o.k1 = k1
+		o.b = b
+	}
+}
+
+// NewBM25 创建 BM25 索引
+func NewBM25(opts ...BM25Option) *BM25 {
+	o := &bm25Options{
+		k1: defaultBM25K1,
+		b:  defaultBM25B,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.tokenizer == nil {
+		o.tokenizer = NewCJKTokenizer()
+	}
+	return &BM25{
+		tokenizer: o.tokenizer,
+		k1:        o.k1,
+		b:         o.b,
+		docs:      make(map[string]*bm25Doc),
+		postings:  make(map[string]map[string]int),
+	}
+}
+
+// AddDocuments 写入文档，id 为空时自动生成，id 已存在时覆盖
+func (idx *BM25) AddDocuments(_ context.Context, docs []schema.Document) ([]string, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	ids := make([]string, 0, len(docs))
+	for _, d := range docs {
+		if d.Id == "" {
+			d.Id = uuid.NewString()
+		}
+		idx.remove(d.Id)
+		tokens := idx.tokenizer.Tokenize(d.PageContent)
+		entry := &bm25Doc{doc: d, terms: make(map[string]int), length: len(tokens)}
+		for _, t := range tokens {
+			entry.terms[t]++
+		}
+		for t, tf := range entry.terms {
+			if idx.postings[t] == nil {
+				idx.postings[t] = make(map[string]int)
+			}
+			idx.postings[t][d.Id] = tf
+		}
+		entry.doc.Score = 0
+		idx.docs[d.Id] = entry
+		idx.totalLen += entry.length
+		ids = append(ids, d.Id)
+	}
+	return ids, nil
+}
+
+// Delete 根据 id 删除文档
+func (idx *BM25) Delete(_ context.Context, ids []string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, id := range ids {
+		idx.remove(id)
+	}
+	return nil
+}
+
+// remove 删除文档，调用方需持有写锁
+func (idx *BM25) remove(id string) {
+	entry, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for t := range entry.terms {
+		delete(idx.postings[t], id)
+		if len(idx.postings[t]) == 0 {
+			delete(idx.postings, t)
+		}
+	}
+	idx.totalLen -= entry.length
+	delete(idx.docs, id)
+}
+
+// Search 关键词检索，返回得分最高的 k 个文档，支持 vectorstore 的元数据过滤，不支持得分阈值
+func (idx *BM25) Search(_ context.Context, query string, k int, options ...vectorstore.Option) ([]schema.Document, error) {
+	if k <= 0 {
+		return nil, vectorstore.ErrInvalidK
+	}
+	opts := vectorstore.Options{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if len(idx.docs) == 0 {
+		return nil, nil
+	}
+	n := float64(len(idx.docs))
+	avgLen := float64(idx.totalLen) / n
+	scores := make(map[string]float64)
+	seen := make(map[string]struct{})
+	for _, t := range idx.tokenizer.Tokenize(query) {
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		posting := idx.postings[t]
+		if len(posting) == 0 {
+			continue
+		}
+		df := float64(len(posting))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range posting {
+			l := float64(idx.docs[id].length)
+			f := float64(tf)
+			scores[id] += idf * f * (idx.k1 + 1) / (f + idx.k1*(1-idx.b+idx.b*l/avgLen))
+		}
+	}
+
+	docs := make([]schema.Document, 0, len(scores))
+	for id, score := range scores {
+		d := idx.docs[id].doc
+		if !vectorstore.MatchFilters(d.Metadata, opts) {
+			continue
+		}
+		d.Score = float32(score)
+		docs = append(docs, d)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if docs[i].Score == docs[j].Score {
+			return docs[i].Id < docs[j].Id
+		}
+		return docs[i].Score > docs[j].Score
+	})
+	if len(docs) > k {
+		docs = docs[:k]
+	}
+	return docs, nil
+}
@@ -0,0 +1,229 @@
+package retriever
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/comqositi/kpllms/schema"
+	"github.com/comqositi/kpllms/vectorstore"
+)
+
+// FusionMode 混合检索的结果融合方式
+type FusionMode string
+
+const (
+	// FusionRRF 倒数排名融合（reciprocal rank fusion），只看排名，不受两路得分量纲影响
+	FusionRRF FusionMode = "rrf"
+	// FusionWeighted 两路得分各自 min-max 归一化后按权重加和
+	FusionWeighted FusionMode = "weighted"
+)
+
+const (
+	defaultHybridK      = 4
+	defaultRRFConstant  = 60
+	defaultVectorWeight = 0.5
+)
+
+type options struct {
+	k             int
+	candidateK    int
+	fusion        FusionMode
+	rrfConstant   float64
+	vectorWeight  float64
+	searchOptions []vectorstore.Option
+	bm25Options   []BM25Option
+}
+
+// Option 混合检索配置
+type Option func(*options)
+
+// WithK 设置最终返回的文档数量，默认 4
+func WithK(k int) Option {
+	return func(o *options) {
+		o.k = k
+	}
+}
+
+// WithCandidateK 设置每一路召回的候选数量，默认为 k 的 4 倍
+func WithCandidateK(k int) Option {
+	return func(o *options) {
+		o.candidateK = k
+	}
+}
+
+// WithFusion 设置融合方式，默认 FusionRRF
+func WithFusion(fusion FusionMode) Option {
+	return func(o *options) {
+		o.fusion = fusion
+	}
+}
+
+// WithRRFConstant 设置 RRF 的平滑常数，默认 60
+func WithRRFConstant(c float64) Option {
+	return func(o *options) {
+		o.rrfConstant = c
+	}
+}
+
+// WithVectorWeight 设置向量检索的权重 0-1，关键词检索权重为 1-weight，两种融合方式都生效，默认 0.5
+func WithVectorWeight(weight float64) Option {
+	return func(o *options) {
+		o.vectorWeight = weight
+	}
+}
+
+// WithSearchOptions 设置检索时的过滤条件，两路检索都会使用
+func WithSearchOptions(opts ...vectorstore.Option) Option {
+	return func(o *options) {
+		o.searchOptions = opts
+	}
+}
+
+// WithBM25Options 设置内置 BM25 索引的配置，例如自定义分词器
+func WithBM25Options(opts ...BM25Option) Option {
+	return func(o *options) {
+		o.bm25Options = opts
+	}
+}
+
+// Hybrid 混合检索：对同一批文档同时建立 BM25 关键词索引和向量索引，检索时按 RRF 或加权得分融合
+type Hybrid struct {
+	store vectorstore.VectorStore
+	bm25  *BM25
+	opts  options
+}
+
+var _ Retriever = (*Hybrid)(nil)
+
+// NewHybrid 创建混合检索器，store 负责向量检索，其中的 Embedder 决定语义相似度
+func NewHybrid(store vectorstore.VectorStore, opts ...Option) (*Hybrid, error) {
+	o := options{
+		k:            defaultHybridK,
+		fusion:       FusionRRF,
+		rrfConstant:  defaultRRFConstant,
+		vectorWeight: defaultVectorWeight,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.candidateK <= 0 {
+		o.candidateK = o.k * 4
+	}
+	if o.vectorWeight < 0 || o.vectorWeight > 1 {
+		return nil, fmt.Errorf("retriever: vector weight %v out of range [0, 1]", o.vectorWeight)
+	}
+	if o.fusion != FusionRRF && o.fusion != FusionWeighted {
+		return nil, fmt.Errorf("retriever: fusion %v not supported", o.fusion)
+	}
+	return &Hybrid{
+		store: store,
+		bm25:  NewBM25(o.bm25Options...),
+		opts:  o,
+	}, nil
+}
+
+// AddDocuments 同时写入向量库和关键词索引，两边使用相同的文档 id
+func (h *Hybrid) AddDocuments(ctx context.Context, docs []schema.Document) ([]string, error) {
+	ids, err := h.store.AddDocuments(ctx, docs)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) != len(docs) {
+		return nil, vectorstore.ErrUnexpectedResponseLength
+	}
+	withIds := make([]schema.Document, len(docs))
+	for i, d := range docs {
+		d.Id = ids[i]
+		withIds[i] = d
+	}
+	return h.bm25.AddDocuments(ctx, withIds)
+}
+
+// Delete 同时从向量库和关键词索引删除
+func (h *Hybrid) Delete(ctx context.Context, ids []string) error {
+	if err := h.store.Delete(ctx, ids); err != nil {
+		return err
+	}
+	return h.bm25.Delete(ctx, ids)
+}
+
+// GetRelevantDocuments 实现 Retriever 接口
+func (h *Hybrid) GetRelevantDocuments(ctx context.Context, query string) ([]schema.Document, error) {
+	vectorDocs, err := h.store.SimilaritySearch(ctx, query, h.opts.candidateK, h.opts.searchOptions...)
+	if err != nil {
+		return nil, err
+	}
+	keywordDocs, err := h.bm25.Search(ctx, query, h.opts.candidateK, h.opts.searchOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	var fused []schema.Document
+	if h.opts.fusion == FusionWeighted {
+		fused = weightedFusion(vectorDocs, keywordDocs, h.opts.vectorWeight)
+	} else {
+		fused = rrfFusion(vectorDocs, keywordDocs, h.opts.vectorWeight, h.opts.rrfConstant)
+	}
+	if len(fused) > h.opts.k {
+		fused = fused[:h.opts.k]
+	}
+	return fused, nil
+}
+
+// rrfFusion score = Σ weight / (c + rank)，rank 从 1 开始
+func rrfFusion(vectorDocs, keywordDocs []schema.Document, vectorWeight, c float64) []schema.Document {
+	scores := make(map[string]float64)
+	merged := make(map[string]schema.Document)
+	add := func(docs []schema.Document, weight float64) {
+		for rank, d := range docs {
+			scores[d.Id] += weight / (c + float64(rank+1))
+			merged[d.Id] = d
+		}
+	}
+	add(vectorDocs, vectorWeight)
+	add(keywordDocs, 1-vectorWeight)
+	return sortFused(scores, merged)
+}
+
+// weightedFusion 两路得分各自 min-max 归一化后加权求和
+func weightedFusion(vectorDocs, keywordDocs []schema.Document, vectorWeight float64) []schema.Document {
+	scores := make(map[string]float64)
+	merged := make(map[string]schema.Document)
+	add := func(docs []schema.Document, weight float64) {
+		if len(docs) == 0 {
+			return
+		}
+		lo, hi := docs[0].Score, docs[0].Score
+		for _, d := range docs {
+			lo = min(lo, d.Score)
+			hi = max(hi, d.Score)
+		}
+		for _, d := range docs {
+			norm := 1.0
+			if hi > lo {
+				norm = float64(d.Score-lo) / float64(hi-lo)
+			}
+			scores[d.Id] += weight * norm
+			merged[d.Id] = d
+		}
+	}
+	add(vectorDocs, vectorWeight)
+	add(keywordDocs, 1-vectorWeight)
+	return sortFused(scores, merged)
+}
+
+func sortFused(scores map[string]float64, merged map[string]schema.Document) []schema.Document {
+	docs := make([]schema.Document, 0, len(merged))
+	for id, d := range merged {
+		d.Score = float32(scores[id])
+		docs = append(docs, d)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if docs[i].Score == docs[j].Score {
+			return docs[i].Id < docs[j].Id
+		}
+		return docs[i].Score > docs[j].Score
+	})
+	return docs
+}
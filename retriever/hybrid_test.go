@@ -0,0 +1,90 @@
+package retriever
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/comqositi/kpllms/schema"
+	"github.com/comqositi/kpllms/vectorstore"
+)
+
+// lengthEmbedder 只按文本长度生成向量，模拟对产品编码不敏感的语义向量
+type lengthEmbedder struct{}
+
+func (lengthEmbedder) embed(text string) []float32 {
+	return []float32{1, float32(len([]rune(text))) / 100}
+}
+
+func (e lengthEmbedder) EmbedDocuments(_ context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+	for _, t := range texts {
+		out = append(out, e.embed(t))
+	}
+	return out, nil
+}
+
+func (e lengthEmbedder) EmbedQuery(_ context.Context, text string) ([]float32, error) {
+	return e.embed(text), nil
+}
+
+func TestCJKTokenizer(t *testing.T) {
+	cases := []struct {
+		tokenizer *CJKTokenizer
+		text      string
+		want      []string
+	}{
+		{NewCJKTokenizer(), "靠谱智能助理", []string{"靠谱", "谱智", "智能", "能助", "助理"}},
+		{NewCJKTokenizer(), "型号 KP-X200 的价格。", []string{"型号", "kp-x200", "的价", "价格"}},
+		{NewCJKTokenizer(), "买A", []string{"买", "a"}},
+		{NewCJKTokenizer("智能助理"), "靠谱智能助理", []string{"靠谱", "智能助理"}},
+	}
+	for _, c := range cases {
+		if got := c.tokenizer.Tokenize(c.text); !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("Tokenize(%q) = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestHybrid(t *testing.T) {
+	ctx := context.Background()
+	docs := []schema.Document{
+		{Id: "a", PageContent: "KP-X200 扫地机器人使用说明"},
+		{Id: "b", PageContent: "KP-X300 扫地机器人使用说明"},
+		{Id: "c", PageContent: "空气净化器滤网更换"},
+	}
+
+	for _, fusion := range []FusionMode{FusionRRF, FusionWeighted} {
+		store, err := vectorstore.NewMemory(lengthEmbedder{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := NewHybrid(store, WithK(2), WithFusion(fusion))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = h.AddDocuments(ctx, docs); err != nil {
+			t.Fatal(err)
+		}
+		got, err := h.GetRelevantDocuments(ctx, "KP-X300 使用说明")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 || got[0].Id != "b" {
+			t.Fatalf("%s: unexpected result %+v", fusion, got)
+		}
+
+		if err = h.Delete(ctx, []string{"b"}); err != nil {
+			t.Fatal(err)
+		}
+		got, err = h.GetRelevantDocuments(ctx, "KP-X300 使用说明")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range got {
+			if d.Id == "b" {
+				t.Fatalf("%s: deleted document returned", fusion)
+			}
+		}
+	}
+}
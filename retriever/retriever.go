@@ -0,0 +1,36 @@
+package retriever
+
+import (
+	"context"
+
+	"github.com/comqositi/kpllms/schema"
+	"github.com/comqositi/kpllms/vectorstore"
+)
+
+// Retriever 检索接口，根据问题返回相关文档
+type Retriever interface {
+	GetRelevantDocuments(ctx context.Context, query string) ([]schema.Document, error)
+}
+
+// VectorStoreRetriever 将向量库包装为检索器
+type VectorStoreRetriever struct {
+	store   vectorstore.VectorStore
+	k       int
+	options []vectorstore.Option
+}
+
+var _ Retriever = (*VectorStoreRetriever)(nil)
+
+// NewVectorStoreRetriever 创建向量检索器，k 为返回的文档数量
+func NewVectorStoreRetriever(store vectorstore.VectorStore, k int, options ...vectorstore.Option) *VectorStoreRetriever {
+	return &VectorStoreRetriever{
+		store:   store,
+		k:       k,
+		options: options,
+	}
+}
+
+// GetRelevantDocuments 实现 Retriever 接口
+func (r *VectorStoreRetriever) GetRelevantDocuments(ctx context.Context, query string) ([]schema.Document, error) {
+	return r.store.SimilaritySearch(ctx, query, r.k, r.options...)
+}
@@ -0,0 +1,117 @@
+package retriever
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer 关键词检索使用的分词器
+type Tokenizer interface {
+	Tokenize(text string) []string
+}
+
+// CJKTokenizer 中日韩文字按二元组（bigram）切分，命中词典的词整体保留；
+// 英文、数字按连续字符切分并转小写，产品编码中的 - _ . 会被保留，例如 "AB-1234"
+type CJKTokenizer struct {
+	dict   map[string]struct{}
+	maxLen int
+}
+
+var _ Tokenizer = (*CJKTokenizer)(nil)
+
+// NewCJKTokenizer 创建分词器，dictionary 为可选的自定义词典，使用正向最大匹配优先切出词典中的词
+func NewCJKTokenizer(dictionary ...string) *CJKTokenizer {
+	t := &CJKTokenizer{dict: make(map[string]struct{}, len(dictionary))}
+	for _, w := range dictionary {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w == "" {
+			continue
+		}
+		t.dict[w] = struct{}{}
+		if n := utf8.RuneCountInString(w); n > t.maxLen {
+			t.maxLen = n
+		}
+	}
+	return t
+}
+
+// Tokenize 实现 Tokenizer 接口
+func (t *CJKTokenizer) Tokenize(text string) []string {
+	runes := []rune(strings.ToLower(text))
+	tokens := make([]string, 0, len(runes))
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case isCJK(r):
+			j := i
+			for j < len(runes) && isCJK(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, t.segmentCJK(runes[i:j])...)
+			i = j
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && (isWordRune(runes[j]) || isJoiner(runes[j]) && j+1 < len(runes) && isWordRune(runes[j+1])) {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+// segmentCJK 先用词典正向最大匹配，未命中的连续片段按 bigram 切分
+func (t *CJKTokenizer) segmentCJK(runes []rune) []string {
+	tokens := make([]string, 0, len(runes))
+	start := 0
+	for i := 0; i < len(runes); {
+		n := t.matchDict(runes[i:])
+		if n == 0 {
+			i++
+			continue
+		}
+		tokens = append(tokens, bigrams(runes[start:i])...)
+		tokens = append(tokens, string(runes[i:i+n]))
+		i += n
+		start = i
+	}
+	return append(tokens, bigrams(runes[start:])...)
+}
+
+// matchDict 返回从开头能匹配到的最长词典词长度，未匹配返回 0
+func (t *CJKTokenizer) matchDict(runes []rune) int {
+	for n := min(t.maxLen, len(runes)); n > 1; n-- {
+		if _, ok := t.dict[string(runes[:n])]; ok {
+			return n
+		}
+	}
+	return 0
+}
+
+func bigrams(runes []rune) []string {
+	if len(runes) == 1 {
+		return []string{string(runes)}
+	}
+	out := make([]string, 0, len(runes))
+	for i := 0; i+1 < len(runes); i++ {
+		out = append(out, string(runes[i:i+2]))
+	}
+	return out
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+func isWordRune(r rune) bool {
+	return !isCJK(r) && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func isJoiner(r rune) bool {
+	return r == '-' || r == '_' || r == '.'
+}
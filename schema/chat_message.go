@@ -71,18 +71,36 @@ type ToolMessage struct {
 // 大模型 response
 type ContentResponse struct {
 	Choices []*ContentChoice
+
+	// 请求的元信息，目前只有 openai 兼容接口返回
+	Metadata *ResponseMetadata
 }
 
 type ContentChoice struct {
 	Content string
 
+	// 推理模型的思考过程，例如 deepseek-reasoner 返回的 reasoning_content
+	ReasoningContent string
+
 	StopReason string
 
 	GenerationInfo map[string]any
 
 	ToolCalls []*ToolCall
 
+	// 整个请求的 token 消耗，有多个结果时只在第一个结果中返回
 	Usage *Usage
+
+	// 每个 token 的对数概率，调用时开启 LogProbs 才有
+	LogProbs []*TokenLogProb
+
+	// 模型拒绝按照要求的 json 结构回答，没有拒绝时为 nil
+	Refusal *Refusal
+}
+
+// Refusal 模型拒绝回答的说明，出于安全原因拒绝时 Content 为空
+type Refusal struct {
+	Message string
 }
 
 type Usage struct {
@@ -0,0 +1,13 @@
+package schema
+
+// Document 文档，向量库、检索、切分等模块的统一数据结构
+type Document struct {
+	// 文档唯一标识，写入向量库时为空则自动生成
+	Id string
+	// 文档内容
+	PageContent string
+	// 元数据，例如：来源、页码、切分偏移量等
+	Metadata map[string]any
+	// 检索得分，越大越相关，仅检索结果中有值
+	Score float32
+}
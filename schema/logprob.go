@@ -0,0 +1,61 @@
+package schema
+
+import (
+	"math"
+	"strings"
+)
+
+// TokenLogProb 生成的 token 及其对数概率
+type TokenLogProb struct {
+	Token   string
+	LogProb float64
+	// token 的 utf-8 字节，一个字符被拆分到多个 token 时使用
+	Bytes []int
+	// 该位置概率最高的候选 token，包含生成的 token
+	TopLogProbs []*TopLogProb
+}
+
+// TopLogProb 候选 token 及其对数概率
+type TopLogProb struct {
+	Token   string
+	LogProb float64
+	Bytes   []int
+}
+
+// Probability 返回 token 的概率
+func (t *TokenLogProb) Probability() float64 {
+	return math.Exp(t.LogProb)
+}
+
+// SequenceProbability 返回整个序列的概率，即所有 token 概率的乘积
+func SequenceProbability(logProbs []*TokenLogProb) float64 {
+	sum := 0.0
+	for _, lp := range logProbs {
+		sum += lp.LogProb
+	}
+	return math.Exp(sum)
+}
+
+// LabelProbability 返回第一个非空白 token 为 label 的概率，用于分类场景，
+// 候选 token 去掉首尾空白后等于 label 的概率相加，没有候选 token 时只看生成的 token
+func LabelProbability(logProbs []*TokenLogProb, label string) float64 {
+	for _, lp := range logProbs {
+		if strings.TrimSpace(lp.Token) == "" {
+			continue
+		}
+		if len(lp.TopLogProbs) == 0 {
+			if strings.TrimSpace(lp.Token) == label {
+				return lp.Probability()
+			}
+			return 0
+		}
+		p := 0.0
+		for _, top := range lp.TopLogProbs {
+			if strings.TrimSpace(top.Token) == label {
+				p += math.Exp(top.LogProb)
+			}
+		}
+		return p
+	}
+	return 0
+}
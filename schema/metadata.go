@@ -0,0 +1,26 @@
+package schema
+
+import "time"
+
+// ResponseMetadata 请求的元信息，用于排查问题和统计耗时
+type ResponseMetadata struct {
+	// 厂商返回的结果 id
+	ID string
+	// 响应头 x-request-id，提交工单时使用
+	RequestID string
+	// 实际使用的模型，可能和请求的模型不同
+	Model string
+	// 结果创建时间，unix 秒
+	Created int64
+	// 后端配置的指纹，和 seed 一起判断结果是否可复现
+	SystemFingerprint string
+	// 响应头 x-ratelimit-remaining-* 的剩余额度，key 为去掉前缀后的名称，例如 requests、tokens
+	RateLimitRemaining map[string]int
+
+	// 流式返回第一个 token 的耗时
+	TimeToFirstToken time.Duration
+	// 整个请求的耗时
+	Latency time.Duration
+	// 流式返回第一个 token 之后的生成速度
+	TokensPerSecond float64
+}
@@ -0,0 +1,210 @@
+package sparkclient
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/comqositi/kpllms/schema"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+
+	// 返回帧的状态：0 第一帧，1 中间帧，2 最后一帧
+	StatusFirst    = 0
+	StatusContinue = 1
+	StatusLast     = 2
+)
+
+// ChatRequest chat 接口请求
+type ChatRequest struct {
+	Model       string
+	Messages    []*ChatMessage
+	Functions   []*Function
+	Temperature *float64
+	TopK        int
+	MaxTokens   int
+	// 用户 id，用于区分终端用户
+	UID string
+
+	// 流式放回的回调函数
+	StreamingFunc func(ctx context.Context, chunk []byte, innerErr error) error
+}
+
+type ChatMessage struct {
+	Role         string        `json:"role"`
+	Content      string        `json:"content"`
+	ContentType  string        `json:"content_type,omitempty"`
+	FunctionCall *FunctionCall `json:"function_call,omitempty"`
+}
+
+type Function struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// 请求帧，由 header、parameter、payload 三部分组成
+type requestFrame struct {
+	Header struct {
+		AppID string `json:"app_id"`
+		UID   string `json:"uid,omitempty"`
+	} `json:"header"`
+	Parameter struct {
+		Chat chatParameter `json:"chat"`
+	} `json:"parameter"`
+	Payload struct {
+		Message struct {
+			Text []*ChatMessage `json:"text"`
+		} `json:"message"`
+		Functions *struct {
+			Text []*Function `json:"text"`
+		} `json:"functions,omitempty"`
+	} `json:"payload"`
+}
+
+type chatParameter struct {
+	Domain      string   `json:"domain"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopK        int      `json:"top_k,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+}
+
+// 返回帧
+type responseFrame struct {
+	Header struct {
+		Error
+		Status int `json:"status"`
+	} `json:"header"`
+	Payload struct {
+		Choices struct {
+			Status int            `json:"status"`
+			Seq    int            `json:"seq"`
+			Text   []*ChatMessage `json:"text"`
+		} `json:"choices"`
+		Usage *struct {
+			Text Usage `json:"text"`
+		} `json:"usage"`
+	} `json:"payload"`
+}
+
+type Usage struct {
+	QuestionTokens   int `json:"question_tokens"`
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatResponse 拼接所有返回帧后的结果
+type ChatResponse struct {
+	Sid          string
+	Content      string
+	FunctionCall *FunctionCall
+	Usage        Usage
+}
+
+// CreateChat 调用 chat 接口，星火总是分帧返回，设置 StreamingFunc 时逐帧回调
+func (c *Client) CreateChat(ctx context.Context, r *ChatRequest) (*ChatResponse, error) {
+	if r.Model == "" {
+		r.Model = c.Model
+	}
+	u, err := c.signURL(c.chatURL(r.Model), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, u, nil)
+	if err != nil {
+		// 握手失败时返回 http 错误，例如签名错误返回 401
+		if resp != nil {
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			return nil, schema.NewHttpError(resp.StatusCode, string(body))
+		}
+		return nil, err
+	}
+	defer conn.Close()
+
+	// ctx 取消时关闭连接，中断阻塞的读取
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	if err = conn.WriteJSON(c.newRequestFrame(r)); err != nil {
+		return nil, err
+	}
+
+	response := &ChatResponse{}
+	for {
+		var frame responseFrame
+		if err = conn.ReadJSON(&frame); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		if frame.Header.Code != 0 {
+			e := frame.Header.Error
+			return nil, &e
+		}
+		response.Sid = frame.Header.Sid
+		for _, t := range frame.Payload.Choices.Text {
+			if t.FunctionCall != nil {
+				response.FunctionCall = t.FunctionCall
+			}
+			if t.Content == "" {
+				continue
+			}
+			response.Content += t.Content
+			if r.StreamingFunc != nil {
+				if err = r.StreamingFunc(ctx, []byte(t.Content), nil); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if frame.Payload.Usage != nil {
+			response.Usage = frame.Payload.Usage.Text
+		}
+		if frame.Header.Status == StatusLast {
+			break
+		}
+	}
+	if response.Content == "" && response.FunctionCall == nil {
+		return nil, ErrEmptyResponse
+	}
+	return response, nil
+}
+
+func (c *Client) newRequestFrame(r *ChatRequest) *requestFrame {
+	f := &requestFrame{}
+	f.Header.AppID = c.appID
+	f.Header.UID = r.UID
+	f.Parameter.Chat = chatParameter{
+		Domain:      r.Model,
+		Temperature: r.Temperature,
+		TopK:        r.TopK,
+		MaxTokens:   r.MaxTokens,
+	}
+	f.Payload.Message.Text = r.Messages
+	if len(r.Functions) > 0 {
+		f.Payload.Functions = &struct {
+			Text []*Function `json:"text"`
+		}{Text: r.Functions}
+	}
+	return f
+}
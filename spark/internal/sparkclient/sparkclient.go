@@ -0,0 +1,141 @@
+package sparkclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// 讯飞星火客户端，只提供 websocket 接口，文档：https://www.xfyun.cn/doc/spark/Web.html
+
+const (
+	defaultBaseURL = "wss://spark-api.xf-yun.com"
+	defaultModel   = ModelMax
+)
+
+// 常用模型
+const (
+	ModelLite    = "lite"
+	ModelPro     = "generalv3"
+	ModelPro128K = "pro-128k"
+	ModelMax     = "generalv3.5"
+	ModelMax32K  = "max-32k"
+	Model4Ultra  = "4.0Ultra"
+)
+
+// modelPaths 模型（domain）对应的接口路径
+var modelPaths = map[string]string{
+	ModelLite:    "/v1.1/chat",
+	ModelPro:     "/v3.1/chat",
+	ModelPro128K: "/chat/pro-128k",
+	ModelMax:     "/v3.5/chat",
+	ModelMax32K:  "/chat/max-32k",
+	Model4Ultra:  "/v4.0/chat",
+}
+
+var (
+	ErrEmptyResponse = errors.New("empty response")
+	ErrMissingAuth   = errors.New("缺少 app_id、api_key 或 api_secret")
+)
+
+// Error 星火返回的错误，header.code 不为 0
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Sid     string `json:"sid"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("spark error code: %d, errMsg: %s, sid: %s", e.Code, e.Message, e.Sid)
+}
+
+// Client 讯飞星火客户端
+type Client struct {
+	appID     string
+	apiKey    string
+	apiSecret string
+	Model     string
+	baseURL   string
+}
+
+// Option is an option for the Spark client.
+type Option func(*Client) error
+
+// New 创建星火客户端
+func New(opts ...Option) (*Client, error) {
+	c := &Client{}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.appID == "" || c.apiKey == "" || c.apiSecret == "" {
+		return nil, ErrMissingAuth
+	}
+	if c.baseURL == "" {
+		c.baseURL = defaultBaseURL
+	}
+	if c.Model == "" {
+		c.Model = defaultModel
+	}
+	return c, nil
+}
+
+// WithAuth 设置控制台中应用的 APPID、APIKey、APISecret
+func WithAuth(appID, apiKey, apiSecret string) Option {
+	return func(c *Client) error {
+		c.appID, c.apiKey, c.apiSecret = appID, apiKey, apiSecret
+		return nil
+	}
+}
+
+func WithModel(value string) Option {
+	return func(c *Client) error {
+		c.Model = value
+		return nil
+	}
+}
+
+// WithBaseURL 设置接口地址，不含路径，例如 wss://spark-api.xf-yun.com
+func WithBaseURL(value string) Option {
+	return func(c *Client) error {
+		c.baseURL = value
+		return nil
+	}
+}
+
+// chatURL 返回模型对应的接口地址，未收录的模型使用 /chat/{model}
+func (c *Client) chatURL(model string) string {
+	path, ok := modelPaths[model]
+	if !ok {
+		path = "/chat/" + model
+	}
+	return c.baseURL + path
+}
+
+// signURL 对 host、date、request-line 做 hmac-sha256 签名，签名结果放在 url 参数中
+func (c *Client) signURL(rawURL string, now time.Time) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	date := now.UTC().Format(http.TimeFormat)
+	origin := fmt.Sprintf("host: %s\ndate: %s\nGET %s HTTP/1.1", u.Host, date, u.Path)
+	mac := hmac.New(sha256.New, []byte(c.apiSecret))
+	mac.Write([]byte(origin))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	authorization := fmt.Sprintf(`api_key="%s", algorithm="hmac-sha256", headers="host date request-line", signature="%s"`,
+		c.apiKey, signature)
+
+	q := url.Values{}
+	q.Set("authorization", base64.StdEncoding.EncodeToString([]byte(authorization)))
+	q.Set("date", date)
+	q.Set("host", u.Host)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,197 @@
+package spark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+	"github.com/comqositi/kpllms/spark/internal/sparkclient"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmptyResponse = errors.New("no response")
+	ErrMissingToken  = errors.New("缺少 APPID、APIKey 或 APISecret，请设置 SPARK_APP_ID、SPARK_API_KEY、SPARK_API_SECRET 环境变量或使用 WithAuth") //nolint:lll
+)
+
+// Error 星火返回的错误，可通过 errors.As 获取错误码
+type Error = sparkclient.Error
+
+type LLM struct {
+	client *sparkclient.Client
+	uid    string
+}
+
+var _ kpllms.Model = (*LLM)(nil)
+
+// New 创建讯飞星火大模型 model 的实现
+func New(opts ...Option) (*LLM, error) {
+	options := &options{
+		appID:     os.Getenv(appIDEnvVarName),
+		apiKey:    os.Getenv(apiKeyEnvVarName),
+		apiSecret: os.Getenv(apiSecretEnvVarName),
+		model:     os.Getenv(modelEnvVarName),
+		baseURL:   os.Getenv(baseURLEnvVarName),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.appID == "" || options.apiKey == "" || options.apiSecret == "" {
+		return nil, ErrMissingToken
+	}
+	c, err := sparkclient.New(
+		sparkclient.WithAuth(options.appID, options.apiKey, options.apiSecret),
+		sparkclient.WithModel(options.model),
+		sparkclient.WithBaseURL(options.baseURL),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &LLM{client: c, uid: options.uid}, nil
+}
+
+// samplingRanges 星火支持的采样参数范围，不支持 top_p，设置时返回 ErrUnsupportedCallOption
+var samplingRanges = kpllms.SamplingRanges{
+	Temperature: kpllms.Range{Min: 0, Max: 1, ExclusiveMin: true},
+}
+
+// Chat 实现大模型接口
+func (o *LLM) Chat(ctx context.Context, messages []*schema.ChatMessage, options ...kpllms.CallOption) (*schema.ContentResponse, error) {
+	opts := kpllms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if opts.TopP != nil {
+		return nil, fmt.Errorf("%w: spark does not support top_p", kpllms.ErrUnsupportedCallOption)
+	}
+	if err := samplingRanges.Validate(&opts); err != nil {
+		return nil, err
+	}
+	if err := opts.CheckSupported("spark", kpllms.OptionUser); err != nil {
+		return nil, err
+	}
+
+	msgs, err := messagesToClientMessages(messages)
+	if err != nil {
+		return nil, err
+	}
+	req := &sparkclient.ChatRequest{
+		Model:         opts.Model,
+		Messages:      msgs,
+		Temperature:   opts.Temperature,
+		MaxTokens:     kpllms.Deref(opts.MaxTokens),
+		UID:           o.uid,
+		StreamingFunc: opts.StreamingFunc,
+	}
+	if opts.User != "" {
+		req.UID = opts.User
+	}
+
+	// 星火没有 tool_choice 参数，不调用函数时不传函数定义
+	if opts.ToolChoice.Type != schema.ToolChoiceTypeNone {
+		for _, t := range opts.Tools {
+			if t.Type != schema.ToolCallTypeFunction || t.Function == nil {
+				return nil, fmt.Errorf("failed to convert llms tool to spark function: tool type %v not supported", t.Type)
+			}
+			req.Functions = append(req.Functions, &sparkclient.Function{
+				Name:        t.Function.Name,
+				Description: t.Function.Description,
+				Parameters:  t.Function.Parameters,
+			})
+		}
+	}
+
+	result, err := o.client.CreateChat(ctx, req)
+	if err != nil {
+		if errors.Is(err, sparkclient.ErrEmptyResponse) {
+			return nil, ErrEmptyResponse
+		}
+		return nil, err
+	}
+
+	choice := &schema.ContentChoice{
+		Content:    result.Content,
+		StopReason: "stop",
+		GenerationInfo: map[string]any{
+			"sid": result.Sid,
+		},
+		Usage: &schema.Usage{
+			PromptTokens:     result.Usage.PromptTokens,
+			CompletionTokens: result.Usage.CompletionTokens,
+			TotalTokens:      result.Usage.TotalTokens,
+		},
+	}
+	if fc := result.FunctionCall; fc != nil {
+		choice.StopReason = "tool_calls"
+		// 星火的函数调用没有 id，这里生成一个，返回结果时用于找回函数名
+		choice.ToolCalls = []*schema.ToolCall{{
+			Id:   "call_" + uuid.NewString(),
+			Type: schema.ToolCallTypeFunction,
+			Function: schema.FunctionCall{
+				Name:      fc.Name,
+				Arguments: fc.Arguments,
+			},
+		}}
+	}
+	return &schema.ContentResponse{Choices: []*schema.ContentChoice{choice}}, nil
+}
+
+// messagesToClientMessages 函数调用结果转为 tool 消息，星火不支持多模态和并行函数调用
+func messagesToClientMessages(messages []*schema.ChatMessage) ([]*sparkclient.ChatMessage, error) {
+	msgs := make([]*sparkclient.ChatMessage, 0, len(messages))
+	for _, mc := range messages {
+		content, err := textFromContent(mc.Content)
+		if err != nil {
+			return nil, err
+		}
+		switch mc.Role {
+		case schema.RoleSystem:
+			msgs = append(msgs, &sparkclient.ChatMessage{Role: sparkclient.RoleSystem, Content: content})
+		case schema.RoleUser:
+			msgs = append(msgs, &sparkclient.ChatMessage{Role: sparkclient.RoleUser, Content: content})
+		case schema.RoleAssistant:
+			msg := &sparkclient.ChatMessage{Role: sparkclient.RoleAssistant, Content: content}
+			if len(mc.ToolCalls) > 1 {
+				return nil, errors.New("spark does not support parallel function calls")
+			}
+			for _, t := range mc.ToolCalls {
+				msg.FunctionCall = &sparkclient.FunctionCall{Name: t.Function.Name, Arguments: t.Function.Arguments}
+			}
+			msgs = append(msgs, msg)
+		case schema.RoleTool:
+			msgs = append(msgs, &sparkclient.ChatMessage{Role: sparkclient.RoleTool, Content: content})
+		default:
+			return nil, fmt.Errorf("role %v not supported", mc.Role)
+		}
+	}
+	return msgs, nil
+}
+
+// textFromContent 星火只支持文本内容
+func textFromContent(content any) (string, error) {
+	switch c := content.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return c, nil
+	case []any:
+		var sb strings.Builder
+		for _, p := range c {
+			switch t := p.(type) {
+			case schema.TextContent:
+				sb.WriteString(t.Text)
+			case *schema.TextContent:
+				sb.WriteString(t.Text)
+			default:
+				return "", fmt.Errorf("content part type %T not supported", p)
+			}
+		}
+		return sb.String(), nil
+	default:
+		return "", fmt.Errorf("content type %T not supported", content)
+	}
+}
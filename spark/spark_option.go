@@ -0,0 +1,64 @@
+package spark
+
+import "github.com/comqositi/kpllms/spark/internal/sparkclient"
+
+const (
+	appIDEnvVarName     = "SPARK_APP_ID"     //nolint:gosec
+	apiKeyEnvVarName    = "SPARK_API_KEY"    //nolint:gosec
+	apiSecretEnvVarName = "SPARK_API_SECRET" //nolint:gosec
+	modelEnvVarName     = "SPARK_MODEL"      //nolint:gosec
+	baseURLEnvVarName   = "SPARK_BASE_URL"   //nolint:gosec
+)
+
+// 常用模型，即请求参数中的 domain，其他模型使用 /chat/{domain} 接口地址
+const (
+	ModelLite    = sparkclient.ModelLite
+	ModelPro     = sparkclient.ModelPro
+	ModelPro128K = sparkclient.ModelPro128K
+	ModelMax     = sparkclient.ModelMax
+	ModelMax32K  = sparkclient.ModelMax32K
+	Model4Ultra  = sparkclient.Model4Ultra
+)
+
+type options struct {
+	appID     string
+	apiKey    string
+	apiSecret string
+	model     string
+	baseURL   string
+	uid       string
+}
+
+// Option is a functional option for the Spark client.
+type Option func(*options)
+
+// WithAuth 设置控制台中应用的 APPID、APIKey、APISecret，
+// 未设置时读取 SPARK_APP_ID、SPARK_API_KEY、SPARK_API_SECRET 环境变量
+func WithAuth(appID, apiKey, apiSecret string) Option {
+	return func(o *options) {
+		o.appID = appID
+		o.apiKey = apiKey
+		o.apiSecret = apiSecret
+	}
+}
+
+// WithModel 设置模型，未设置时读取 SPARK_MODEL 环境变量，默认 generalv3.5
+func WithModel(model string) Option {
+	return func(o *options) {
+		o.model = model
+	}
+}
+
+// WithBaseURL 设置接口地址，不含路径，未设置时读取 SPARK_BASE_URL 环境变量，默认 wss://spark-api.xf-yun.com
+func WithBaseURL(baseURL string) Option {
+	return func(o *options) {
+		o.baseURL = baseURL
+	}
+}
+
+// WithUID 设置请求头中的用户 id
+func WithUID(uid string) Option {
+	return func(o *options) {
+		o.uid = uid
+	}
+}
@@ -0,0 +1,167 @@
+package spark
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+	"github.com/gorilla/websocket"
+)
+
+// newTestServer 本地 websocket 服务，校验签名后读取请求帧，由 handler 返回响应帧
+func newTestServer(t *testing.T, handler func(path string, req map[string]any) []string) (*LLM, string) {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !validSignature(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message":"HMAC signature does not match"}`))
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var req map[string]any
+		if err = conn.ReadJSON(&req); err != nil {
+			t.Errorf("read request: %v", err)
+			return
+		}
+		for _, frame := range handler(r.URL.Path, req) {
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(frame))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	baseURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+	llm, err := New(WithAuth("app", "key", "secret"), WithBaseURL(baseURL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return llm, baseURL
+}
+
+func validSignature(r *http.Request) bool {
+	q := r.URL.Query()
+	raw, err := base64.StdEncoding.DecodeString(q.Get("authorization"))
+	if err != nil {
+		return false
+	}
+	origin := fmt.Sprintf("host: %s\ndate: %s\nGET %s HTTP/1.1", q.Get("host"), q.Get("date"), r.URL.Path)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(origin))
+	want := fmt.Sprintf(`api_key="key", algorithm="hmac-sha256", headers="host date request-line", signature="%s"`,
+		base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	return string(raw) == want && q.Get("host") == r.Host
+}
+
+func frame(status int, text string, usage string) string {
+	f := fmt.Sprintf(`{"header":{"code":0,"message":"Success","sid":"sid1","status":%d},"payload":{"choices":{"status":%d,"seq":0,"text":[%s]}`,
+		status, status, text)
+	if usage != "" {
+		f += `,"usage":{"text":` + usage + `}`
+	}
+	return f + "}}"
+}
+
+func TestChatStream(t *testing.T) {
+	var got map[string]any
+	var path string
+	llm, _ := newTestServer(t, func(p string, req map[string]any) []string {
+		got, path = req, p
+		return []string{
+			frame(0, `{"role":"assistant","content":"你好","index":0}`, ""),
+			frame(1, `{"role":"assistant","content":"，我是","index":0}`, ""),
+			frame(2, `{"role":"assistant","content":"星火","index":0}`, `{"question_tokens":2,"prompt_tokens":2,"completion_tokens":5,"total_tokens":7}`),
+		}
+	})
+	var streamed []string
+	resp, err := llm.Chat(context.Background(), []*schema.ChatMessage{
+		{Role: schema.RoleSystem, Content: "你是助手"},
+		{Role: schema.RoleUser, Content: "你好"},
+	}, kpllms.WithModel(ModelLite), kpllms.WithStreamingFunc(func(ctx context.Context, chunk []byte, innerErr error) error {
+		streamed = append(streamed, string(chunk))
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/v1.1/chat" {
+		t.Fatalf("path = %s", path)
+	}
+	header := got["header"].(map[string]any)
+	chat := got["parameter"].(map[string]any)["chat"].(map[string]any)
+	text := got["payload"].(map[string]any)["message"].(map[string]any)["text"].([]any)
+	if header["app_id"] != "app" || chat["domain"] != ModelLite || len(text) != 2 {
+		t.Fatalf("request = %v", got)
+	}
+	c := resp.Choices[0]
+	if len(streamed) != 3 || c.Content != "你好，我是星火" || c.Usage.TotalTokens != 7 || c.GenerationInfo["sid"] != "sid1" {
+		t.Fatalf("streamed = %v, choice = %+v", streamed, c)
+	}
+}
+
+func TestChatFunctionCall(t *testing.T) {
+	var got map[string]any
+	llm, _ := newTestServer(t, func(_ string, req map[string]any) []string {
+		got = req
+		return []string{frame(2, `{"role":"assistant","content":"","content_type":"text","index":0,
+			"function_call":{"name":"get_weather","arguments":"{\"city\":\"合肥\"}"}}`,
+			`{"prompt_tokens":20,"completion_tokens":8,"total_tokens":28}`)}
+	})
+	tools := []*kpllms.Tool{{Type: schema.ToolCallTypeFunction, Function: &kpllms.FunctionDefinition{
+		Name: "get_weather", Description: "查询天气", Parameters: map[string]any{"type": "object"},
+	}}}
+	resp, err := llm.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "合肥天气"}},
+		kpllms.WithTools(tools))
+	if err != nil {
+		t.Fatal(err)
+	}
+	functions := got["payload"].(map[string]any)["functions"].(map[string]any)["text"].([]any)
+	if len(functions) != 1 || functions[0].(map[string]any)["name"] != "get_weather" {
+		t.Fatalf("functions = %v", functions)
+	}
+	c := resp.Choices[0]
+	if c.StopReason != "tool_calls" || len(c.ToolCalls) != 1 || c.ToolCalls[0].Id == "" ||
+		c.ToolCalls[0].Function.Arguments != `{"city":"合肥"}` {
+		t.Fatalf("choice = %+v", c)
+	}
+}
+
+func TestChatError(t *testing.T) {
+	llm, baseURL := newTestServer(t, func(_ string, req map[string]any) []string {
+		return []string{`{"header":{"code":10013,"message":"输入内容审核不通过","sid":"sid2","status":2}}`}
+	})
+	_, err := llm.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "x"}})
+	var e *Error
+	if !errors.As(err, &e) || e.Code != 10013 || e.Sid != "sid2" {
+		t.Fatalf("err = %v", err)
+	}
+
+	bad, _ := New(WithAuth("app", "key", "wrong"), WithBaseURL(baseURL))
+	_, err = bad.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "x"}})
+	var httpErr *schema.HttpError
+	if !errors.As(err, &httpErr) || httpErr.Code != http.StatusUnauthorized {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestChatUnsupportedTopP(t *testing.T) {
+	llm, err := New(WithAuth("app", "key", "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = llm.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "x"}}, kpllms.WithTopP(0.5))
+	if !errors.Is(err, kpllms.ErrUnsupportedCallOption) {
+		t.Fatalf("err = %v", err)
+	}
+}
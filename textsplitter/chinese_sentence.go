@@ -0,0 +1,107 @@
+package textsplitter
+
+import "unicode/utf8"
+
+// 句末标点，连续出现时视为同一个句末，例如 "？！"
+var sentenceTerminators = map[rune]bool{ //nolint:gochecknoglobals
+	'。': true, '！': true, '？': true, '；': true, '…': true,
+	'!': true, '?': true, ';': true,
+}
+
+// 全角引号、括号等成对符号，成对符号内的句末标点不切分
+var quotePairs = map[rune]rune{ //nolint:gochecknoglobals
+	'“': '”', '‘': '’', '「': '」', '『': '』', '（': '）',
+}
+
+var quoteClosers = map[rune]bool{ //nolint:gochecknoglobals
+	'”': true, '’': true, '」': true, '』': true, '）': true,
+}
+
+// 单个句子超过切片长度时，依次尝试的次级分隔符
+var clauseSeparators = []string{"，", "、", "：", ",", " ", ""} //nolint:gochecknoglobals
+
+// ChineseSentence 中文分句切分：按 。！？； 等句末标点和换行分句，
+// 引号内的标点不切分，句末标点后紧跟的右引号归入当前句，再将句子合并为切片
+type ChineseSentence struct {
+	opts options
+}
+
+var _ TextSplitter = (*ChineseSentence)(nil)
+
+// NewChineseSentence 创建中文分句切分器
+func NewChineseSentence(opts ...Option) *ChineseSentence {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(o.separators) == 0 {
+		o.separators = clauseSeparators
+	}
+	return &ChineseSentence{opts: o}
+}
+
+// SplitText 实现 TextSplitter 接口
+func (s *ChineseSentence) SplitText(text string) ([]Chunk, error) {
+	if err := s.opts.validate(); err != nil {
+		return nil, err
+	}
+	pieces := make([]piece, 0)
+	split := false
+	for _, sent := range splitSentences(text) {
+		part := text[sent[0]:sent[1]]
+		if l := s.opts.lenFunc(part); l <= s.opts.chunkSize {
+			pieces = append(pieces, piece{start: sent[0], end: sent[1], length: l, boundary: split})
+			split = false
+			continue
+		}
+		// 过长的句子单独切分，不与前后句子合并
+		sub := splitRecursive(part, sent[0], s.opts.separators, s.opts.chunkSize, s.opts.lenFunc)
+		sub[0].boundary = true
+		pieces = append(pieces, sub...)
+		split = true
+	}
+	return mergePieces(text, pieces, s.opts.chunkSize, s.opts.chunkOverlap), nil
+}
+
+// splitSentences 返回每个句子在原文中的 [start, end) 字节偏移量，所有句子首尾相接
+func splitSentences(text string) [][2]int {
+	sentences := make([][2]int, 0)
+	var stack []rune
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		switch {
+		case r == '\n':
+			stack = stack[:0]
+		case quotePairs[r] != 0:
+			stack = append(stack, quotePairs[r])
+			continue
+		case len(stack) > 0:
+			if r != stack[len(stack)-1] {
+				continue
+			}
+			stack = stack[:len(stack)-1]
+			prev, _ := utf8.DecodeLastRuneInString(text[:i-size])
+			if len(stack) > 0 || !sentenceTerminators[prev] {
+				continue
+			}
+		case !sentenceTerminators[r]:
+			continue
+		}
+		// 吸收后续的句末标点、右引号和换行
+		for i < len(text) {
+			next, n := utf8.DecodeRuneInString(text[i:])
+			if !sentenceTerminators[next] && !quoteClosers[next] && next != '\n' {
+				break
+			}
+			i += n
+		}
+		sentences = append(sentences, [2]int{start, i})
+		start = i
+	}
+	if start < len(text) {
+		sentences = append(sentences, [2]int{start, len(text)})
+	}
+	return sentences
+}
@@ -0,0 +1,55 @@
+package textsplitter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// piece 原文中不可再分的片段，所有 piece 首尾相接覆盖原文
+type piece struct {
+	start  int
+	end    int
+	length int
+	// 为 true 时不与前一个片段合并，用于保持递归切分前的段落边界
+	boundary bool
+}
+
+// mergePieces 将相邻片段合并为不超过 chunkSize 的切片，相邻切片重叠不超过 chunkOverlap
+// 单个片段超过 chunkSize 时单独成为一个切片，切片不会跨越 boundary
+func mergePieces(text string, pieces []piece, chunkSize, chunkOverlap int) []Chunk {
+	chunks := make([]Chunk, 0)
+	for i := 0; i < len(pieces); {
+		total := 0
+		j := i
+		for j < len(pieces) && (j == i || !pieces[j].boundary && total+pieces[j].length <= chunkSize) {
+			total += pieces[j].length
+			j++
+		}
+		if c, ok := trimChunk(text, pieces[i].start, pieces[j-1].end); ok {
+			chunks = append(chunks, c)
+		}
+		if j == len(pieces) {
+			break
+		}
+		// 从末尾回退，作为下一个切片的重叠部分
+		back, overlap := j, 0
+		for back > i+1 && !pieces[back].boundary && overlap+pieces[back-1].length <= chunkOverlap {
+			overlap += pieces[back-1].length
+			back--
+		}
+		i = back
+	}
+	return chunks
+}
+
+// trimChunk 去掉切片首尾的空白，同时修正偏移量，全为空白时返回 false
+func trimChunk(text string, start, end int) (Chunk, bool) {
+	s := text[start:end]
+	trimmed := strings.TrimLeftFunc(s, unicode.IsSpace)
+	start += len(s) - len(trimmed)
+	trimmed = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+	if trimmed == "" {
+		return Chunk{}, false
+	}
+	return Chunk{Text: trimmed, Start: start, End: start + len(trimmed)}, true
+}
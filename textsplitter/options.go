@@ -0,0 +1,90 @@
+package textsplitter
+
+import "unicode/utf8"
+
+const (
+	defaultChunkSize    = 512
+	defaultChunkOverlap = 64
+	defaultModelName    = "gpt-3.5-turbo"
+)
+
+type options struct {
+	chunkSize    int
+	chunkOverlap int
+	separators   []string
+	lenFunc      func(string) int
+	modelName    string
+	encodingName string
+	encoder      TokenEncoder
+}
+
+// Option 切分配置
+type Option func(*options)
+
+func defaultOptions() options {
+	return options{
+		chunkSize:    defaultChunkSize,
+		chunkOverlap: defaultChunkOverlap,
+		lenFunc:      utf8.RuneCountInString,
+		modelName:    defaultModelName,
+	}
+}
+
+func (o options) validate() error {
+	if o.chunkSize <= 0 {
+		return ErrInvalidChunkSize
+	}
+	if o.chunkOverlap < 0 || o.chunkOverlap >= o.chunkSize {
+		return ErrInvalidChunkOverlap
+	}
+	return nil
+}
+
+// WithChunkSize 设置切片的最大长度，默认 512，长度单位由切分器决定
+func WithChunkSize(size int) Option {
+	return func(o *options) {
+		o.chunkSize = size
+	}
+}
+
+// WithChunkOverlap 设置相邻切片的最大重叠长度，默认 64
+func WithChunkOverlap(overlap int) Option {
+	return func(o *options) {
+		o.chunkOverlap = overlap
+	}
+}
+
+// WithSeparators 设置递归切分使用的分隔符，按优先级从高到低排列，空字符串表示按字符切分
+func WithSeparators(separators []string) Option {
+	return func(o *options) {
+		o.separators = separators
+	}
+}
+
+// WithLenFunc 设置长度计算方法，默认按字符数（rune）计算
+func WithLenFunc(lenFunc func(string) int) Option {
+	return func(o *options) {
+		o.lenFunc = lenFunc
+	}
+}
+
+// WithModelName 设置 token 切分使用的模型，用于选择 tiktoken 编码，默认 gpt-3.5-turbo
+func WithModelName(model string) Option {
+	return func(o *options) {
+		o.modelName = model
+	}
+}
+
+// WithEncodingName 设置 token 切分使用的 tiktoken 编码名称，例如 cl100k_base，优先于 WithModelName
+func WithEncodingName(name string) Option {
+	return func(o *options) {
+		o.encodingName = name
+	}
+}
+
+// WithTokenEncoder 设置自定义的 token 编码器，优先于 WithEncodingName 和 WithModelName
+func WithTokenEncoder(encoder TokenEncoder) Option {
+	return func(o *options) {
+		o.encoder = encoder
+	}
+}
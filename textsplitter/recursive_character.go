@@ -0,0 +1,89 @@
+package textsplitter
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// 默认分隔符，依次尝试段落、换行、中文句末标点、空格，最后按字符切分
+var defaultSeparators = []string{"\n\n", "\n", "。", " ", ""} //nolint:gochecknoglobals
+
+// RecursiveCharacter 递归字符切分：先用优先级最高的分隔符切分，仍然过长的片段再用下一个分隔符切分
+type RecursiveCharacter struct {
+	opts options
+}
+
+var _ TextSplitter = (*RecursiveCharacter)(nil)
+
+// NewRecursiveCharacter 创建递归字符切分器
+func NewRecursiveCharacter(opts ...Option) *RecursiveCharacter {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(o.separators) == 0 {
+		o.separators = defaultSeparators
+	}
+	return &RecursiveCharacter{opts: o}
+}
+
+// SplitText 实现 TextSplitter 接口
+func (s *RecursiveCharacter) SplitText(text string) ([]Chunk, error) {
+	if err := s.opts.validate(); err != nil {
+		return nil, err
+	}
+	pieces := splitRecursive(text, 0, s.opts.separators, s.opts.chunkSize, s.opts.lenFunc)
+	return mergePieces(text, pieces, s.opts.chunkSize, s.opts.chunkOverlap), nil
+}
+
+// splitRecursive 切分 text，offset 为 text 在原文中的偏移量，分隔符保留在前一个片段的末尾
+func splitRecursive(text string, offset int, separators []string, chunkSize int, lenFunc func(string) int) []piece {
+	if text == "" {
+		return nil
+	}
+	// 选择第一个出现在文本中的分隔符，都未出现且没有配置空字符串分隔符时不再切分
+	sep, rest, found := "", []string(nil), false
+	for i, s := range separators {
+		if s == "" || strings.Contains(text, s) {
+			sep, rest, found = s, separators[i+1:], true
+			break
+		}
+	}
+	if !found {
+		return []piece{{start: offset, end: offset + len(text), length: lenFunc(text)}}
+	}
+
+	pieces := make([]piece, 0)
+	split := false
+	for _, part := range splitKeepSeparator(text, sep) {
+		if part == "" {
+			continue
+		}
+		if l := lenFunc(part); l <= chunkSize || sep == "" {
+			// 上一个片段被继续切分过，不与其合并
+			pieces = append(pieces, piece{start: offset, end: offset + len(part), length: l, boundary: split})
+			split = false
+		} else {
+			sub := splitRecursive(part, offset, rest, chunkSize, lenFunc)
+			sub[0].boundary = true
+			pieces = append(pieces, sub...)
+			split = true
+		}
+		offset += len(part)
+	}
+	return pieces
+}
+
+// splitKeepSeparator 按分隔符切分并保留分隔符，sep 为空时按字符切分
+func splitKeepSeparator(text, sep string) []string {
+	if sep == "" {
+		parts := make([]string, 0, utf8.RuneCountInString(text))
+		for len(text) > 0 {
+			_, size := utf8.DecodeRuneInString(text)
+			parts = append(parts, text[:size])
+			text = text[size:]
+		}
+		return parts
+	}
+	return strings.SplitAfter(text, sep)
+}
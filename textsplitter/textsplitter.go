@@ -0,0 +1,69 @@
+package textsplitter
+
+import (
+	"errors"
+	"maps"
+
+	"github.com/comqositi/kpllms/schema"
+)
+
+// 切分后写入文档元数据的字段
+const (
+	// 切片在原文中的起始字节偏移量，原文[start_index:end_index] 即为切片内容
+	MetadataStartIndex = "start_index"
+	// 切片在原文中的结束字节偏移量（不包含）
+	MetadataEndIndex = "end_index"
+	// 切片在原文中的序号，从 0 开始
+	MetadataChunkIndex = "chunk_index"
+)
+
+var (
+	ErrInvalidChunkSize    = errors.New("textsplitter: chunk size 必须大于 0")
+	ErrInvalidChunkOverlap = errors.New("textsplitter: chunk overlap 必须大于等于 0 且小于 chunk size")
+)
+
+// TextSplitter 文本切分接口，切分结果可直接用于 EmbedDocuments
+type TextSplitter interface {
+	SplitText(text string) ([]Chunk, error)
+}
+
+// Chunk 切片，Text 与原文[Start:End] 完全一致，用于引用溯源
+type Chunk struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// SplitDocuments 切分文档，切片继承原文档的元数据，并写入偏移量和序号
+func SplitDocuments(splitter TextSplitter, docs []schema.Document) ([]schema.Document, error) {
+	out := make([]schema.Document, 0, len(docs))
+	for _, d := range docs {
+		chunks, err := splitter.SplitText(d.PageContent)
+		if err != nil {
+			return nil, err
+		}
+		for i, c := range chunks {
+			metadata := maps.Clone(d.Metadata)
+			if metadata == nil {
+				metadata = make(map[string]any, 3)
+			}
+			metadata[MetadataStartIndex] = c.Start
+			metadata[MetadataEndIndex] = c.End
+			metadata[MetadataChunkIndex] = i
+			out = append(out, schema.Document{
+				PageContent: c.Text,
+				Metadata:    metadata,
+			})
+		}
+	}
+	return out, nil
+}
+
+// Texts 返回切片内容
+func Texts(chunks []Chunk) []string {
+	texts := make([]string, 0, len(chunks))
+	for _, c := range chunks {
+		texts = append(texts, c.Text)
+	}
+	return texts
+}
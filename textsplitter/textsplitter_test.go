@@ -0,0 +1,156 @@
+package textsplitter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/comqositi/kpllms/schema"
+)
+
+// runeEncoder 每个 ASCII 字符一个 token，每个汉字按 UTF-8 字节拆成多个 token，模拟 tiktoken 的行为
+type runeEncoder struct{}
+
+func (runeEncoder) Encode(text string) ([]int, error) {
+	tokens := make([]int, 0, len(text))
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			tokens = append(tokens, int(r))
+			continue
+		}
+		for _, b := range []byte(string(r)) {
+			tokens = append(tokens, 1000+int(b))
+		}
+	}
+	return tokens, nil
+}
+
+func (runeEncoder) Decode(tokens []int) (string, error) {
+	b := make([]byte, 0, len(tokens))
+	for _, t := range tokens {
+		if t >= 1000 {
+			b = append(b, byte(t-1000))
+		} else {
+			b = append(b, byte(t))
+		}
+	}
+	return string(b), nil
+}
+
+// failingEncoder 模拟无法加载词表的编码器
+type failingEncoder struct{}
+
+var errNoVocabulary = errors.New("no vocabulary")
+
+func (failingEncoder) Encode(string) ([]int, error) { return nil, errNoVocabulary }
+func (failingEncoder) Decode([]int) (string, error) { return "", errNoVocabulary }
+
+func checkOffsets(t *testing.T, text string, chunks []Chunk) {
+	t.Helper()
+	for _, c := range chunks {
+		if text[c.Start:c.End] != c.Text {
+			t.Fatalf("chunk %q does not match text[%d:%d] = %q", c.Text, c.Start, c.End, text[c.Start:c.End])
+		}
+	}
+}
+
+func TestRecursiveCharacter(t *testing.T) {
+	text := "第一段第一句。第一段第二句。\n\n第二段很短。\n\n第三段 has some english words here"
+	s := NewRecursiveCharacter(WithChunkSize(12), WithChunkOverlap(0))
+	chunks, err := s.SplitText(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOffsets(t, text, chunks)
+	// 第三段被继续切分，不与较短的第二段合并
+	want := []string{"第一段第一句。", "第一段第二句。", "第二段很短。", "第三段 has", "some", "english", "words here"}
+	if got := Texts(chunks); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for _, c := range chunks {
+		if n := utf8.RuneCountInString(c.Text); n > 12 {
+			t.Fatalf("chunk %q longer than chunk size: %d", c.Text, n)
+		}
+	}
+}
+
+func TestRecursiveCharacterOverlap(t *testing.T) {
+	text := "a b c d e f g h"
+	s := NewRecursiveCharacter(WithChunkSize(6), WithChunkOverlap(2))
+	chunks, err := s.SplitText(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOffsets(t, text, chunks)
+	want := []string{"a b c", "c d e", "e f g", "g h"}
+	if got := Texts(chunks); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	if _, err = NewRecursiveCharacter(WithChunkSize(2), WithChunkOverlap(2)).SplitText(text); err != ErrInvalidChunkOverlap {
+		t.Fatalf("expected ErrInvalidChunkOverlap, got %v", err)
+	}
+}
+
+func TestTokenSplitter(t *testing.T) {
+	text := "ab你好cd世界"
+	s := NewTokenSplitter(WithTokenEncoder(runeEncoder{}), WithChunkSize(5), WithChunkOverlap(0))
+	chunks, err := s.SplitText(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOffsets(t, text, chunks)
+	// 汉字占 3 个 token，切分点不会落在汉字中间
+	want := []string{"ab你", "好cd", "世", "界"}
+	if got := Texts(chunks); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	s = NewTokenSplitter(WithTokenEncoder(failingEncoder{}), WithChunkSize(10), WithChunkOverlap(0))
+	if _, err = s.SplitText(strings.Repeat("无法编码的文本。", 20)); !errors.Is(err, errNoVocabulary) {
+		t.Fatalf("expected encoder error, got %v", err)
+	}
+}
+
+func TestChineseSentence(t *testing.T) {
+	text := "他说：“今天下雨。明天晴。”我不信！真的吗？是的；好吧\n新的一行"
+	got := make([]string, 0)
+	for _, s := range splitSentences(text) {
+		got = append(got, text[s[0]:s[1]])
+	}
+	want := []string{"他说：“今天下雨。明天晴。”", "我不信！", "真的吗？", "是的；", "好吧\n", "新的一行"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	s := NewChineseSentence(WithChunkSize(16), WithChunkOverlap(4))
+	chunks, err := s.SplitText(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOffsets(t, text, chunks)
+	wantChunks := []string{"他说：“今天下雨。明天晴。”", "我不信！真的吗？是的；好吧", "好吧\n新的一行"}
+	if got := Texts(chunks); !reflect.DeepEqual(got, wantChunks) {
+		t.Fatalf("got %q, want %q", got, wantChunks)
+	}
+}
+
+func TestSplitDocuments(t *testing.T) {
+	docs := []schema.Document{{PageContent: strings.Repeat("一句话。", 3), Metadata: map[string]any{"source": "a.txt"}}}
+	out, err := SplitDocuments(NewChineseSentence(WithChunkSize(8), WithChunkOverlap(0)), docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("unexpected chunk count %d", len(out))
+	}
+	m := out[1].Metadata
+	if m["source"] != "a.txt" || m[MetadataChunkIndex] != 1 || m[MetadataStartIndex] != 24 || m[MetadataEndIndex] != 36 {
+		t.Fatalf("unexpected metadata %v", m)
+	}
+	if _, ok := docs[0].Metadata[MetadataChunkIndex]; ok {
+		t.Fatal("source document metadata modified")
+	}
+}
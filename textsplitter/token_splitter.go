@@ -0,0 +1,109 @@
+package textsplitter
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/comqositi/kpllms/internal/tokenutils"
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// TokenEncoder token 编码器，默认使用 tiktoken。无法编码时返回错误，切分器不会把文本当作 0 个 token
+type TokenEncoder interface {
+	Encode(text string) ([]int, error)
+	Decode(tokens []int) (string, error)
+}
+
+// tiktokenEncoder 适配 tiktoken，不允许特殊 token，按普通文本编码
+type tiktokenEncoder struct {
+	modelName    string
+	encodingName string
+}
+
+func (e tiktokenEncoder) Encode(text string) ([]int, error) {
+	enc, err := e.encoding()
+	if err != nil {
+		return nil, err
+	}
+	return enc.Encode(text, nil, nil), nil
+}
+
+func (e tiktokenEncoder) Decode(tokens []int) (string, error) {
+	enc, err := e.encoding()
+	if err != nil {
+		return "", err
+	}
+	return enc.Decode(tokens), nil
+}
+
+func (e tiktokenEncoder) encoding() (*tiktoken.Tiktoken, error) {
+	if e.encodingName != "" {
+		return tokenutils.GetEncoding(e.encodingName)
+	}
+	return tokenutils.EncodingForModel(e.modelName)
+}
+
+// TokenSplitter 按 token 数切分，保证切片不超过 embedding 模型的 token 上限
+// 切分点只会落在字符边界上，一个汉字被编码成多个 token 时不会被拆开
+type TokenSplitter struct {
+	opts    options
+	encoder TokenEncoder
+}
+
+var _ TextSplitter = (*TokenSplitter)(nil)
+
+// NewTokenSplitter 创建 token 切分器，WithChunkSize 和 WithChunkOverlap 的单位为 token
+func NewTokenSplitter(opts ...Option) *TokenSplitter {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	encoder := o.encoder
+	if encoder == nil {
+		encoder = tiktokenEncoder{modelName: o.modelName, encodingName: o.encodingName}
+	}
+	return &TokenSplitter{opts: o, encoder: encoder}
+}
+
+// SplitText 实现 TextSplitter 接口
+func (s *TokenSplitter) SplitText(text string) ([]Chunk, error) {
+	if err := s.opts.validate(); err != nil {
+		return nil, err
+	}
+	tokens, err := s.encoder.Encode(text)
+	if err != nil {
+		return nil, fmt.Errorf("textsplitter: encode text: %w", err)
+	}
+	pieces := make([]piece, 0, len(tokens))
+	offset := 0
+	for _, t := range tokens {
+		decoded, err := s.encoder.Decode([]int{t})
+		if err != nil {
+			return nil, fmt.Errorf("textsplitter: decode token: %w", err)
+		}
+		size := len(decoded)
+		// 切分点不在字符边界上时并入上一个片段
+		if n := len(pieces); n > 0 && offset < len(text) && !utf8.RuneStart(text[offset]) {
+			pieces[n-1].end += size
+			pieces[n-1].length++
+		} else {
+			pieces = append(pieces, piece{start: offset, end: offset + size, length: 1})
+		}
+		offset += size
+	}
+	if offset != len(text) {
+		// 编码器无法还原原文时，退回按字符切分，长度仍按 token 计算
+		var encodeErr error
+		pieces = splitRecursive(text, 0, []string{""}, s.opts.chunkSize, func(text string) int {
+			tokens, err := s.encoder.Encode(text)
+			if err != nil && encodeErr == nil {
+				encodeErr = err
+			}
+			return len(tokens)
+		})
+		if encodeErr != nil {
+			return nil, fmt.Errorf("textsplitter: encode text: %w", encodeErr)
+		}
+	}
+	return mergePieces(text, pieces, s.opts.chunkSize, s.opts.chunkOverlap), nil
+}
@@ -0,0 +1,110 @@
+package kpllms
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidCallOption 调用参数超出厂商支持的范围
+	ErrInvalidCallOption = errors.New("invalid call option")
+	// ErrUnsupportedCallOption 厂商不支持的调用参数
+	ErrUnsupportedCallOption = errors.New("unsupported call option")
+)
+
+// OptionName 可选的调用参数，不是所有厂商都支持
+type OptionName string
+
+const (
+	OptionStop             OptionName = "stop"
+	OptionN                OptionName = "n"
+	OptionFrequencyPenalty OptionName = "frequency_penalty"
+	OptionPresencePenalty  OptionName = "presence_penalty"
+	OptionSeed             OptionName = "seed"
+	OptionUser             OptionName = "user"
+	OptionLogProbs         OptionName = "logprobs"
+	OptionChoiceStreaming  OptionName = "choice_streaming"
+)
+
+// CheckSupported 检查已设置的可选参数是否在 supported 中，N 为 1 时不检查
+func (o *CallOptions) CheckSupported(provider string, supported ...OptionName) error {
+	set := map[OptionName]bool{
+		OptionStop:             len(o.Stop) > 0,
+		OptionN:                o.N > 1,
+		OptionFrequencyPenalty: o.FrequencyPenalty != nil,
+		OptionPresencePenalty:  o.PresencePenalty != nil,
+		OptionSeed:             o.Seed != nil,
+		OptionUser:             o.User != "",
+		OptionLogProbs:         o.LogProbs || o.TopLogProbs > 0,
+		OptionChoiceStreaming:  o.ChoiceStreamingFunc != nil,
+	}
+	for _, name := range supported {
+		delete(set, name)
+	}
+	for _, name := range []OptionName{OptionStop, OptionN, OptionFrequencyPenalty, OptionPresencePenalty, OptionSeed, OptionUser, OptionLogProbs, OptionChoiceStreaming} {
+		if set[name] {
+			return fmt.Errorf("%w: %s does not support %s", ErrUnsupportedCallOption, provider, name)
+		}
+	}
+	if o.N < 0 {
+		return fmt.Errorf("%w: n=%d, must be greater than 0", ErrInvalidCallOption, o.N)
+	}
+	return nil
+}
+
+// Range 参数的取值范围，ExclusiveMin 为 true 时不包含最小值
+type Range struct {
+	Min          float64
+	Max          float64
+	ExclusiveMin bool
+}
+
+// Check 检查已设置的参数，未设置时不检查
+func (r Range) Check(name string, v *float64) error {
+	if v == nil {
+		return nil
+	}
+	if *v > r.Max || *v < r.Min || (r.ExclusiveMin && *v == r.Min) {
+		left := "["
+		if r.ExclusiveMin {
+			left = "("
+		}
+		return fmt.Errorf("%w: %s=%v, must be in %s%v, %v]", ErrInvalidCallOption, name, *v, left, r.Min, r.Max)
+	}
+	return nil
+}
+
+// CheckMaxTokens 检查已设置的最大输出 token 数，必须大于 0
+func CheckMaxTokens(v *int) error {
+	if v != nil && *v <= 0 {
+		return fmt.Errorf("%w: max_tokens=%d, must be greater than 0", ErrInvalidCallOption, *v)
+	}
+	return nil
+}
+
+// SamplingRanges 厂商支持的采样参数范围
+type SamplingRanges struct {
+	Temperature Range
+	TopP        Range
+	// 为零值时不检查惩罚参数
+	Penalty Range
+}
+
+// Validate 检查 temperature、top_p、惩罚参数和最大输出 token 数
+func (r SamplingRanges) Validate(o *CallOptions) error {
+	if err := r.Temperature.Check("temperature", o.Temperature); err != nil {
+		return err
+	}
+	if err := r.TopP.Check("top_p", o.TopP); err != nil {
+		return err
+	}
+	if r.Penalty != (Range{}) {
+		if err := r.Penalty.Check("frequency_penalty", o.FrequencyPenalty); err != nil {
+			return err
+		}
+		if err := r.Penalty.Check("presence_penalty", o.PresencePenalty); err != nil {
+			return err
+		}
+	}
+	return CheckMaxTokens(o.MaxTokens)
+}
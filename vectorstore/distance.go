@@ -0,0 +1,69 @@
+package vectorstore
+
+import (
+	"fmt"
+	"math"
+)
+
+// DistanceStrategy 相似度计算方式
+type DistanceStrategy string
+
+const (
+	// DistanceCosine 余弦相似度，得分范围 [-1, 1]
+	DistanceCosine DistanceStrategy = "cosine"
+	// DistanceDot 内积，适合已归一化的向量
+	DistanceDot DistanceStrategy = "dot"
+	// DistanceL2 欧式距离，得分为 1/(1+距离)，范围 (0, 1]
+	DistanceL2 DistanceStrategy = "l2"
+)
+
+// scoreFunc 返回两个向量的得分，越大越相似
+type scoreFunc func(a, b []float32) float32
+
+func scoreFuncFor(distance DistanceStrategy) (scoreFunc, error) {
+	switch distance {
+	case DistanceCosine, "":
+		return CosineSimilarity, nil
+	case DistanceDot:
+		return DotProduct, nil
+	case DistanceL2:
+		return func(a, b []float32) float32 {
+			return 1 / (1 + L2Distance(a, b))
+		}, nil
+	default:
+		return nil, fmt.Errorf("vectorstore: distance %v not supported", distance)
+	}
+}
+
+// DotProduct 内积
+func DotProduct(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// CosineSimilarity 余弦相似度，任意一个向量为零向量时返回 0
+func CosineSimilarity(a, b []float32) float32 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(na) * math.Sqrt(nb)))
+}
+
+// L2Distance 欧式距离
+func L2Distance(a, b []float32) float32 {
+	var sum float64
+	for i := range a {
+		d := float64(a[i]) - float64(b[i])
+		sum += d * d
+	}
+	return float32(math.Sqrt(sum))
+}
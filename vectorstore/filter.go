@@ -0,0 +1,51 @@
+package vectorstore
+
+import "reflect"
+
+// MatchFilters 判断元数据是否满足过滤条件，供其他检索实现复用同样的过滤语义
+func MatchFilters(metadata map[string]any, opts Options) bool {
+	for key, want := range opts.Filters {
+		got, ok := metadata[key]
+		if !ok || !matchValue(got, want) {
+			return false
+		}
+	}
+	if opts.FilterFunc != nil && !opts.FilterFunc(metadata) {
+		return false
+	}
+	return true
+}
+
+// matchValue 比较元数据值，want 为切片时命中任意一个即可
+// 数字统一按 float64 比较，避免从快照恢复后 int 变为 float64 导致不相等
+func matchValue(got, want any) bool {
+	wv := reflect.ValueOf(want)
+	if wv.Kind() == reflect.Slice && wv.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < wv.Len(); i++ {
+			if matchValue(got, wv.Index(i).Interface()) {
+				return true
+			}
+		}
+		return false
+	}
+	gf, gok := toFloat(got)
+	wf, wok := toFloat(want)
+	if gok && wok {
+		return gf == wf
+	}
+	return reflect.DeepEqual(got, want)
+}
+
+func toFloat(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,209 @@
+package vectorstore
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// hnswIndex HNSW 近似最近邻索引，节点 id 为内存向量库中的记录下标
+// 参考论文：Efficient and robust approximate nearest neighbor search using Hierarchical Navigable Small World graphs
+type hnswIndex struct {
+	config    HNSWConfig
+	score     scoreFunc
+	vector    func(id int) []float32
+	nodes     map[int]*hnswNode
+	entry     int
+	maxLevel  int
+	levelMult float64
+	rng       *rand.Rand
+}
+
+type hnswNode struct {
+	// 每一层的邻居
+	friends [][]int
+}
+
+type hnswCandidate struct {
+	id   int
+	dist float32
+}
+
+func newHNSWIndex(config HNSWConfig, score scoreFunc, vector func(id int) []float32) *hnswIndex {
+	return &hnswIndex{
+		config:    config,
+		score:     score,
+		vector:    vector,
+		nodes:     make(map[int]*hnswNode),
+		entry:     -1,
+		levelMult: 1 / math.Log(float64(config.M)),
+		rng:       rand.New(rand.NewSource(1)), //nolint:gosec
+	}
+}
+
+// distance 距离越小越相似
+func (h *hnswIndex) distance(q []float32, id int) float32 {
+	return -h.score(q, h.vector(id))
+}
+
+func (h *hnswIndex) maxFriends(level int) int {
+	if level == 0 {
+		return h.config.M * 2
+	}
+	return h.config.M
+}
+
+func (h *hnswIndex) randomLevel() int {
+	return int(math.Floor(-math.Log(1-h.rng.Float64()) * h.levelMult))
+}
+
+// insert 加入一个节点
+func (h *hnswIndex) insert(id int) {
+	q := h.vector(id)
+	level := h.randomLevel()
+	node := &hnswNode{friends: make([][]int, level+1)}
+	h.nodes[id] = node
+
+	if h.entry < 0 {
+		h.entry = id
+		h.maxLevel = level
+		return
+	}
+
+	ep := hnswCandidate{id: h.entry, dist: h.distance(q, h.entry)}
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedy(q, ep, l)
+	}
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(q, []hnswCandidate{ep}, h.config.EfConstruction, l)
+		neighbors := candidates
+		if len(neighbors) > h.config.M {
+			neighbors = neighbors[:h.config.M]
+		}
+		for _, n := range neighbors {
+			node.friends[l] = append(node.friends[l], n.id)
+			h.link(n.id, id, l)
+		}
+		ep = candidates[0]
+	}
+	if level > h.maxLevel {
+		h.entry = id
+		h.maxLevel = level
+	}
+}
+
+// link 为 from 节点加入邻居 to，超过上限时只保留最近的邻居
+func (h *hnswIndex) link(from, to, level int) {
+	node := h.nodes[from]
+	node.friends[level] = append(node.friends[level], to)
+	limit := h.maxFriends(level)
+	if len(node.friends[level]) <= limit {
+		return
+	}
+	q := h.vector(from)
+	cs := make([]hnswCandidate, 0, len(node.friends[level]))
+	for _, f := range node.friends[level] {
+		cs = append(cs, hnswCandidate{id: f, dist: h.distance(q, f)})
+	}
+	sortCandidates(cs)
+	node.friends[level] = node.friends[level][:0]
+	for _, c := range cs[:limit] {
+		node.friends[level] = append(node.friends[level], c.id)
+	}
+}
+
+// greedy 在某一层贪心查找最近的节点
+func (h *hnswIndex) greedy(q []float32, ep hnswCandidate, level int) hnswCandidate {
+	for changed := true; changed; {
+		changed = false
+		for _, f := range h.nodes[ep.id].friends[level] {
+			if d := h.distance(q, f); d < ep.dist {
+				ep = hnswCandidate{id: f, dist: d}
+				changed = true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer 在某一层查找最近的 ef 个节点，结果按距离升序
+func (h *hnswIndex) searchLayer(q []float32, eps []hnswCandidate, ef int, level int) []hnswCandidate {
+	visited := make(map[int]struct{}, ef*4)
+	candidates := &candidateHeap{}
+	results := &candidateHeap{max: true}
+	for _, ep := range eps {
+		visited[ep.id] = struct{}{}
+		heap.Push(candidates, ep)
+		heap.Push(results, ep)
+	}
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(hnswCandidate)
+		if results.Len() >= ef && c.dist > results.items[0].dist {
+			break
+		}
+		friends := h.nodes[c.id].friends
+		if level >= len(friends) {
+			continue
+		}
+		for _, f := range friends[level] {
+			if _, ok := visited[f]; ok {
+				continue
+			}
+			visited[f] = struct{}{}
+			d := h.distance(q, f)
+			if results.Len() < ef || d < results.items[0].dist {
+				heap.Push(candidates, hnswCandidate{id: f, dist: d})
+				heap.Push(results, hnswCandidate{id: f, dist: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+	out := make([]hnswCandidate, results.Len())
+	copy(out, results.items)
+	sortCandidates(out)
+	return out
+}
+
+// search 查找最近的 ef 个节点，结果按距离升序
+func (h *hnswIndex) search(q []float32, ef int) []hnswCandidate {
+	if h.entry < 0 {
+		return nil
+	}
+	ep := hnswCandidate{id: h.entry, dist: h.distance(q, h.entry)}
+	for l := h.maxLevel; l > 0; l-- {
+		ep = h.greedy(q, ep, l)
+	}
+	return h.searchLayer(q, []hnswCandidate{ep}, ef, 0)
+}
+
+func sortCandidates(cs []hnswCandidate) {
+	sort.Slice(cs, func(i, j int) bool {
+		return cs[i].dist < cs[j].dist
+	})
+}
+
+// candidateHeap 候选集堆，max 为 true 时为大顶堆
+type candidateHeap struct {
+	items []hnswCandidate
+	max   bool
+}
+
+func (c *candidateHeap) Len() int { return len(c.items) }
+func (c *candidateHeap) Less(i, j int) bool {
+	if c.max {
+		return c.items[i].dist > c.items[j].dist
+	}
+	return c.items[i].dist < c.items[j].dist
+}
+func (c *candidateHeap) Swap(i, j int) { c.items[i], c.items[j] = c.items[j], c.items[i] }
+func (c *candidateHeap) Push(x any)    { c.items = append(c.items, x.(hnswCandidate)) }
+func (c *candidateHeap) Pop() any {
+	old := c.items
+	n := len(old)
+	x := old[n-1]
+	c.items = old[:n-1]
+	return x
+}
@@ -0,0 +1,264 @@
+package vectorstore
+
+import (
+	"context"
+	"sync"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+	"github.com/google/uuid"
+)
+
+// Memory 内存向量库，默认暴力检索，文档量较大时可通过 WithHNSW 开启近似索引
+type Memory struct {
+	embedder kpllms.Embedder
+	score    scoreFunc
+	opts     memoryOptions
+
+	mu      sync.RWMutex
+	records []*record
+	ids     map[string]int
+	dims    int
+	deleted int
+	index   *hnswIndex
+}
+
+type record struct {
+	doc     schema.Document
+	vector  []float32
+	deleted bool
+}
+
+var _ VectorStore = (*Memory)(nil)
+
+// NewMemory 创建内存向量库
+func NewMemory(embedder kpllms.Embedder, opts ...MemoryOption) (*Memory, error) {
+	if embedder == nil {
+		return nil, ErrMissingEmbedder
+	}
+	o := memoryOptions{distance: DistanceCosine}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	score, err := scoreFuncFor(o.distance)
+	if err != nil {
+		return nil, err
+	}
+	m := &Memory{
+		embedder: embedder,
+		score:    score,
+		opts:     o,
+	}
+	m.reset()
+	return m, nil
+}
+
+// reset 清空所有数据，调用方需持有写锁
+func (m *Memory) reset() {
+	m.records = nil
+	m.ids = make(map[string]int)
+	m.dims = 0
+	m.deleted = 0
+	m.index = nil
+	if m.opts.hnsw != nil {
+		m.index = newHNSWIndex(*m.opts.hnsw, m.score, m.vectorAt)
+	}
+}
+
+func (m *Memory) vectorAt(id int) []float32 {
+	return m.records[id].vector
+}
+
+// AddDocuments 写入文档，id 已存在的文档会被覆盖
+func (m *Memory) AddDocuments(ctx context.Context, docs []schema.Document, _ ...Option) ([]string, error) {
+	if len(docs) == 0 {
+		return nil, nil
+	}
+	texts := make([]string, 0, len(docs))
+	for _, d := range docs {
+		texts = append(texts, d.PageContent)
+	}
+	vectors, err := m.embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(docs) {
+		return nil, ErrUnexpectedResponseLength
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.add(docs, vectors)
+}
+
+// add 写入已向量化的文档，调用方需持有写锁。整批校验通过后才确定空库的向量维度
+func (m *Memory) add(docs []schema.Document, vectors [][]float32) ([]string, error) {
+	dims := m.dims
+	for _, v := range vectors {
+		if len(v) == 0 {
+			return nil, ErrEmptyVector
+		}
+		if dims == 0 {
+			dims = len(v)
+		}
+		if len(v) != dims {
+			return nil, ErrDimensionMismatch
+		}
+	}
+	m.dims = dims
+	ids := make([]string, 0, len(docs))
+	for i, d := range docs {
+		if d.Id == "" {
+			d.Id = uuid.NewString()
+		}
+		m.remove(d.Id)
+		d.Score = 0
+		m.records = append(m.records, &record{doc: d, vector: vectors[i]})
+		slot := len(m.records) - 1
+		m.ids[d.Id] = slot
+		if m.index != nil {
+			m.index.insert(slot)
+		}
+		ids = append(ids, d.Id)
+	}
+	return ids, nil
+}
+
+// Delete 根据 id 删除文档，不存在的 id 会被忽略
+func (m *Memory) Delete(_ context.Context, ids []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		m.remove(id)
+	}
+	// 删除只做标记，删除过半后清理记录并重建索引
+	if m.deleted > 0 && m.deleted*2 >= len(m.records) {
+		m.compact()
+	}
+	return nil
+}
+
+// remove 标记删除，调用方需持有写锁
+func (m *Memory) remove(id string) {
+	slot, ok := m.ids[id]
+	if !ok {
+		return
+	}
+	delete(m.ids, id)
+	m.records[slot].deleted = true
+	m.deleted++
+}
+
+// compact 清理已删除的记录并重建索引，调用方需持有写锁
+func (m *Memory) compact() {
+	docs, vectors := m.live()
+	m.reset()
+	if len(docs) > 0 {
+		_, _ = m.add(docs, vectors)
+	}
+}
+
+// live 返回未删除的文档和向量，调用方需持有锁
+func (m *Memory) live() ([]schema.Document, [][]float32) {
+	docs := make([]schema.Document, 0, len(m.records)-m.deleted)
+	vectors := make([][]float32, 0, len(m.records)-m.deleted)
+	for _, r := range m.records {
+		if r.deleted {
+			continue
+		}
+		docs = append(docs, r.doc)
+		vectors = append(vectors, r.vector)
+	}
+	return docs, vectors
+}
+
+// Len 返回文档数量
+func (m *Memory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.ids)
+}
+
+// SimilaritySearch 相似度检索
+func (m *Memory) SimilaritySearch(ctx context.Context, query string, k int, options ...Option) ([]schema.Document, error) {
+	if k <= 0 {
+		return nil, ErrInvalidK
+	}
+	vector, err := m.embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return m.SimilaritySearchByVector(ctx, vector, k, options...)
+}
+
+// SimilaritySearchByVector 使用已向量化的查询检索
+func (m *Memory) SimilaritySearchByVector(_ context.Context, vector []float32, k int, options ...Option) ([]schema.Document, error) {
+	if k <= 0 {
+		return nil, ErrInvalidK
+	}
+	opts := Options{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.ids) == 0 {
+		return nil, nil
+	}
+	if len(vector) != m.dims {
+		return nil, ErrDimensionMismatch
+	}
+
+	filtered := len(opts.Filters) > 0 || opts.FilterFunc != nil
+	if m.index != nil {
+		ef := max(m.index.config.EfSearch, k)
+		if filtered {
+			ef = max(ef, k*4)
+		}
+		// 过滤后结果不足时退回暴力检索，保证过滤条件下的召回
+		docs := m.collect(m.index.search(vector, ef+m.deleted), k, opts)
+		if len(docs) == k || !filtered {
+			return docs, nil
+		}
+	}
+	return m.collect(m.bruteForce(vector), k, opts), nil
+}
+
+// bruteForce 计算全部记录的距离，结果按距离升序
+func (m *Memory) bruteForce(vector []float32) []hnswCandidate {
+	cs := make([]hnswCandidate, 0, len(m.ids))
+	for slot, r := range m.records {
+		if r.deleted {
+			continue
+		}
+		cs = append(cs, hnswCandidate{id: slot, dist: -m.score(vector, r.vector)})
+	}
+	sortCandidates(cs)
+	return cs
+}
+
+// collect 按过滤条件和阈值取前 k 个结果
+func (m *Memory) collect(cs []hnswCandidate, k int, opts Options) []schema.Document {
+	docs := make([]schema.Document, 0, k)
+	for _, c := range cs {
+		r := m.records[c.id]
+		if r.deleted {
+			continue
+		}
+		score := -c.dist
+		if opts.ScoreThreshold != 0 && score < opts.ScoreThreshold {
+			break
+		}
+		if !MatchFilters(r.doc.Metadata, opts) {
+			continue
+		}
+		doc := r.doc
+		doc.Score = score
+		docs = append(docs, doc)
+		if len(docs) == k {
+			break
+		}
+	}
+	return docs
+}
@@ -0,0 +1,231 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/comqositi/kpllms/schema"
+)
+
+// fakeEmbedder 按关键词出现次数生成向量，记录调用的是 db 还是 query
+type fakeEmbedder struct {
+	words   []string
+	calls   []string
+	vectors map[string][]float32
+}
+
+func (f *fakeEmbedder) embed(text string) []float32 {
+	if v, ok := f.vectors[text]; ok {
+		return v
+	}
+	v := make([]float32, len(f.words))
+	for i, w := range f.words {
+		for j := 0; j+len(w) <= len(text); j++ {
+			if text[j:j+len(w)] == w {
+				v[i]++
+			}
+		}
+	}
+	return v
+}
+
+func (f *fakeEmbedder) EmbedDocuments(_ context.Context, texts []string) ([][]float32, error) {
+	f.calls = append(f.calls, "db")
+	out := make([][]float32, 0, len(texts))
+	for _, t := range texts {
+		out = append(out, f.embed(t))
+	}
+	return out, nil
+}
+
+func (f *fakeEmbedder) EmbedQuery(_ context.Context, text string) ([]float32, error) {
+	f.calls = append(f.calls, "query")
+	return f.embed(text), nil
+}
+
+func newTestDocs() []schema.Document {
+	return []schema.Document{
+		{Id: "1", PageContent: "苹果 苹果 香蕉", Metadata: map[string]any{"lang": "zh", "page": 1}},
+		{Id: "2", PageContent: "香蕉 香蕉 橙子", Metadata: map[string]any{"lang": "zh", "page": 2}},
+		{Id: "3", PageContent: "橙子 橙子 橙子", Metadata: map[string]any{"lang": "en", "page": 3}},
+	}
+}
+
+func TestMemorySimilaritySearch(t *testing.T) {
+	ctx := context.Background()
+	for _, distance := range []DistanceStrategy{DistanceCosine, DistanceDot, DistanceL2} {
+		emb := &fakeEmbedder{words: []string{"苹果", "香蕉", "橙子"}}
+		store, err := NewMemory(emb, WithDistance(distance))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = store.AddDocuments(ctx, newTestDocs()); err != nil {
+			t.Fatal(err)
+		}
+		docs, err := store.SimilaritySearch(ctx, "橙子 橙子 橙子", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(docs) != 2 || docs[0].Id != "3" {
+			t.Fatalf("%s: unexpected result %+v", distance, docs)
+		}
+		if emb.calls[0] != "db" || emb.calls[1] != "query" {
+			t.Fatalf("%s: unexpected embed calls %v", distance, emb.calls)
+		}
+	}
+}
+
+func TestMemoryFiltersAndThreshold(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewMemory(&fakeEmbedder{words: []string{"苹果", "香蕉", "橙子"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.AddDocuments(ctx, newTestDocs()); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := store.SimilaritySearch(ctx, "橙子", 3, WithFilters(map[string]any{"lang": "zh"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 || docs[0].Id != "2" {
+		t.Fatalf("unexpected filtered result %+v", docs)
+	}
+
+	docs, err = store.SimilaritySearch(ctx, "橙子", 3, WithFilters(map[string]any{"page": []int{1, 3}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 || docs[0].Id != "3" || docs[1].Id != "1" {
+		t.Fatalf("unexpected in-filter result %+v", docs)
+	}
+
+	docs, err = store.SimilaritySearch(ctx, "橙子", 3, WithScoreThreshold(0.4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("unexpected threshold result %+v", docs)
+	}
+
+	if err = store.Delete(ctx, []string{"3"}); err != nil {
+		t.Fatal(err)
+	}
+	docs, err = store.SimilaritySearch(ctx, "橙子", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 || docs[0].Id != "2" {
+		t.Fatalf("unexpected result after delete %+v", docs)
+	}
+}
+
+func TestMemoryHNSWRecall(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(42))
+	emb := &fakeEmbedder{vectors: map[string][]float32{}}
+	docs := make([]schema.Document, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		text := fmt.Sprintf("doc-%d", i)
+		v := make([]float32, 16)
+		for j := range v {
+			v[j] = rng.Float32()*2 - 1
+		}
+		emb.vectors[text] = v
+		docs = append(docs, schema.Document{Id: text, PageContent: text})
+	}
+
+	flat, err := NewMemory(emb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hnsw, err := NewMemory(emb, WithHNSW(HNSWConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = flat.AddDocuments(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = hnsw.AddDocuments(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	hits, total := 0, 0
+	for i := 0; i < 20; i++ {
+		q := emb.vectors[fmt.Sprintf("doc-%d", i*37)]
+		want, _ := flat.SimilaritySearchByVector(ctx, q, 10)
+		got, _ := hnsw.SimilaritySearchByVector(ctx, q, 10)
+		ids := map[string]bool{}
+		for _, d := range got {
+			ids[d.Id] = true
+		}
+		for _, d := range want {
+			total++
+			if ids[d.Id] {
+				hits++
+			}
+		}
+	}
+	if recall := float64(hits) / float64(total); recall < 0.9 {
+		t.Fatalf("hnsw recall too low: %.2f", recall)
+	}
+}
+
+func TestMemorySaveAndLoadFile(t *testing.T) {
+	ctx := context.Background()
+	emb := &fakeEmbedder{words: []string{"苹果", "香蕉", "橙子"}}
+	store, err := NewMemory(emb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.AddDocuments(ctx, newTestDocs()); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "store.json")
+	if err = store.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := NewMemory(emb, WithHNSW(HNSWConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = restored.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Len() != 3 {
+		t.Fatalf("unexpected restored length %d", restored.Len())
+	}
+	docs, err := restored.SimilaritySearch(ctx, "苹果", 1, WithFilters(map[string]any{"page": 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || docs[0].Id != "1" {
+		t.Fatalf("unexpected restored result %+v", docs)
+	}
+}
+
+func TestMemoryAddInvalidBatch(t *testing.T) {
+	ctx := context.Background()
+	emb := &fakeEmbedder{vectors: map[string][]float32{
+		"a": {1, 0, 0}, "b": {1, 0, 0, 0}, "c": {}, "d": {0, 1, 0, 0},
+	}}
+	store, err := NewMemory(emb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.AddDocuments(ctx, []schema.Document{{PageContent: "a"}, {PageContent: "b"}}); err != ErrDimensionMismatch {
+		t.Fatalf("expected ErrDimensionMismatch, got %v", err)
+	}
+	if _, err = store.AddDocuments(ctx, []schema.Document{{PageContent: "c"}}); err != ErrEmptyVector {
+		t.Fatalf("expected ErrEmptyVector, got %v", err)
+	}
+	// 失败的批次不会确定向量维度
+	if _, err = store.AddDocuments(ctx, []schema.Document{{PageContent: "b"}, {PageContent: "d"}}); err != nil {
+		t.Fatal(err)
+	}
+}
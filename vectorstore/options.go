@@ -0,0 +1,82 @@
+package vectorstore
+
+// Options 写入和检索时的参数
+type Options struct {
+	// 得分阈值，低于该值的结果会被过滤，0 表示不过滤
+	ScoreThreshold float32
+	// 元数据过滤，所有键值都相等才命中
+	Filters map[string]any
+	// 自定义元数据过滤，返回 true 表示命中
+	FilterFunc func(metadata map[string]any) bool
+}
+
+// Option 写入和检索参数
+type Option func(*Options)
+
+// WithScoreThreshold 设置得分阈值
+func WithScoreThreshold(threshold float32) Option {
+	return func(o *Options) {
+		o.ScoreThreshold = threshold
+	}
+}
+
+// WithFilters 设置元数据过滤条件，值为切片时表示命中其中任意一个即可
+func WithFilters(filters map[string]any) Option {
+	return func(o *Options) {
+		o.Filters = filters
+	}
+}
+
+// WithFilterFunc 设置自定义元数据过滤方法
+func WithFilterFunc(filter func(metadata map[string]any) bool) Option {
+	return func(o *Options) {
+		o.FilterFunc = filter
+	}
+}
+
+const (
+	defaultHNSWM              = 16
+	defaultHNSWEfConstruction = 200
+	defaultHNSWEfSearch       = 64
+)
+
+type memoryOptions struct {
+	distance DistanceStrategy
+	hnsw     *HNSWConfig
+}
+
+// MemoryOption 内存向量库的配置
+type MemoryOption func(*memoryOptions)
+
+// HNSWConfig HNSW 索引参数
+type HNSWConfig struct {
+	// 每个节点的最大邻居数
+	M int
+	// 建索引时的候选集大小
+	EfConstruction int
+	// 检索时的候选集大小
+	EfSearch int
+}
+
+// WithDistance 设置相似度计算方式，默认余弦相似度
+func WithDistance(distance DistanceStrategy) MemoryOption {
+	return func(o *memoryOptions) {
+		o.distance = distance
+	}
+}
+
+// WithHNSW 使用 HNSW 近似索引，适合文档量较大的场景，参数为 0 时使用默认值
+func WithHNSW(config HNSWConfig) MemoryOption {
+	return func(o *memoryOptions) {
+		if config.M <= 0 {
+			config.M = defaultHNSWM
+		}
+		if config.EfConstruction <= 0 {
+			config.EfConstruction = defaultHNSWEfConstruction
+		}
+		if config.EfSearch <= 0 {
+			config.EfSearch = defaultHNSWEfSearch
+		}
+		o.hnsw = &config
+	}
+}
@@ -0,0 +1,101 @@
+package vectorstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/comqositi/kpllms/schema"
+)
+
+const snapshotVersion = 1
+
+// snapshot 内存向量库快照，HNSW 索引不落盘，恢复时重建
+type snapshot struct {
+	Version   int                `json:"version"`
+	Documents []snapshotDocument `json:"documents"`
+}
+
+type snapshotDocument struct {
+	Id          string         `json:"id"`
+	PageContent string         `json:"page_content"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	Vector      []float32      `json:"vector"`
+}
+
+// SaveFile 将全部文档和向量保存到本地文件，先写临时文件再重命名，避免写一半的文件覆盖旧快照
+func (m *Memory) SaveFile(path string) error {
+	m.mu.RLock()
+	docs, vectors := m.live()
+	m.mu.RUnlock()
+
+	snap := snapshot{
+		Version:   snapshotVersion,
+		Documents: make([]snapshotDocument, 0, len(docs)),
+	}
+	for i, d := range docs {
+		snap.Documents = append(snap.Documents, snapshotDocument{
+			Id:          d.Id,
+			PageContent: d.PageContent,
+			Metadata:    d.Metadata,
+			Vector:      vectors[i],
+		})
+	}
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile 从本地文件恢复，会清空当前数据
+func (m *Memory) LoadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var snap snapshot
+	if err = json.Unmarshal(b, &snap); err != nil {
+		return err
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("vectorstore: snapshot version %d not supported", snap.Version)
+	}
+
+	docs := make([]schema.Document, 0, len(snap.Documents))
+	vectors := make([][]float32, 0, len(snap.Documents))
+	for _, d := range snap.Documents {
+		docs = append(docs, schema.Document{
+			Id:          d.Id,
+			PageContent: d.PageContent,
+			Metadata:    d.Metadata,
+		})
+		vectors = append(vectors, d.Vector)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reset()
+	if len(docs) == 0 {
+		return nil
+	}
+	if _, err = m.add(docs, vectors); err != nil {
+		m.reset()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/comqositi/kpllms/schema"
+)
+
+var (
+	ErrMissingEmbedder          = errors.New("vectorstore: embedder 不能为空")
+	ErrInvalidK                 = errors.New("vectorstore: k 必须大于 0")
+	ErrUnexpectedResponseLength = errors.New("vectorstore: 向量数量与文档数量不一致")
+	ErrDimensionMismatch        = errors.New("vectorstore: 向量维度不一致")
+	ErrEmptyVector              = errors.New("vectorstore: 向量不能为空")
+)
+
+// VectorStore 向量库接口
+type VectorStore interface {
+	// AddDocuments 写入文档，使用 Embedder.EmbedDocuments 向量化，返回文档 id
+	AddDocuments(ctx context.Context, docs []schema.Document, options ...Option) ([]string, error)
+	// Delete 根据 id 删除文档
+	Delete(ctx context.Context, ids []string) error
+	// SimilaritySearch 相似度检索，使用 Embedder.EmbedQuery 向量化查询，返回最相近的 k 个文档
+	SimilaritySearch(ctx context.Context, query string, k int, options ...Option) ([]schema.Document, error)
+}
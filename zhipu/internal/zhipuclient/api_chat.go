@@ -0,0 +1,215 @@
+package zhipuclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/comqositi/kpllms/internal/httputils"
+)
+
+// 智谱 chat 接口实现，文档：https://open.bigmodel.cn/dev/api/normal-model/glm-4
+
+const (
+	ToolTypeFunction  = "function"
+	ToolTypeWebSearch = "web_search"
+	ToolTypeRetrieval = "retrieval"
+
+	FinishReasonSensitive = "sensitive"
+)
+
+// ChatRequest chat 接口请求
+type ChatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []*ChatMessage  `json:"messages"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	TopP           *float64        `json:"top_p,omitempty"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	Stop           []string        `json:"stop,omitempty"`
+	Stream         bool            `json:"stream,omitempty"`
+	Tools          []*Tool         `json:"tools,omitempty"`
+	ToolChoice     string          `json:"tool_choice,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	RequestID      string          `json:"request_id,omitempty"`
+	UserID         string          `json:"user_id,omitempty"`
+
+	// 流式放回的回调函数
+	StreamingFunc func(ctx context.Context, chunk []byte, innerErr error) error `json:"-"`
+}
+
+type ResponseFormat struct {
+	Type string `json:"type"`
+}
+
+type ChatMessage struct {
+	Role       string     `json:"role"`
+	Content    any        `json:"content,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// Tool type 为 function、web_search 或 retrieval，只设置对应的字段
+type Tool struct {
+	Type      string              `json:"type"`
+	Function  *FunctionDefinition `json:"function,omitempty"`
+	WebSearch *WebSearch          `json:"web_search,omitempty"`
+	Retrieval *Retrieval          `json:"retrieval,omitempty"`
+}
+
+type FunctionDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+// WebSearch 联网搜索配置
+type WebSearch struct {
+	Enable bool `json:"enable"`
+	// 自定义搜索内容，为空时由模型根据对话生成
+	SearchQuery string `json:"search_query,omitempty"`
+	// 是否返回搜索结果
+	SearchResult bool `json:"search_result,omitempty"`
+}
+
+// Retrieval 知识库检索配置
+type Retrieval struct {
+	KnowledgeID    string `json:"knowledge_id"`
+	PromptTemplate string `json:"prompt_template,omitempty"`
+}
+
+type ToolCall struct {
+	Index    int          `json:"index,omitempty"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function FunctionCall `json:"function"`
+}
+
+type FunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+type ResponseMessage struct {
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+}
+
+type Choice struct {
+	Index        int             `json:"index"`
+	FinishReason string          `json:"finish_reason"`
+	Message      ResponseMessage `json:"message"`
+	Delta        ResponseMessage `json:"delta"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// WebSearchResult 联网搜索结果
+type WebSearchResult struct {
+	Title   string `json:"title"`
+	Link    string `json:"link"`
+	Content string `json:"content"`
+	Media   string `json:"media,omitempty"`
+	Refer   string `json:"refer,omitempty"`
+}
+
+// ChatResponse chat 接口返回，流式返回时每个 chunk 也是该结构，内容在 delta 中
+type ChatResponse struct {
+	ID        string            `json:"id"`
+	Created   int64             `json:"created"`
+	Model     string            `json:"model"`
+	Choices   []*Choice         `json:"choices"`
+	Usage     *Usage            `json:"usage,omitempty"`
+	WebSearch []WebSearchResult `json:"web_search,omitempty"`
+}
+
+// CreateChat 调用 chat 接口，流式返回时拼接所有 chunk
+func (c *Client) CreateChat(ctx context.Context, r *ChatRequest) (*ChatResponse, error) {
+	if r.Model == "" {
+		r.Model = c.Model
+	}
+	r.Stream = r.StreamingFunc != nil
+	headers, err := c.setHeaders()
+	if err != nil {
+		return nil, err
+	}
+
+	var response ChatResponse
+	url := c.baseURL + "/chat/completions"
+	if !r.Stream {
+		if err = httputils.HttpPost(ctx, url, r, headers, &response); err != nil {
+			return nil, convertError(err)
+		}
+	} else if err = c.stream(ctx, url, r, headers, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Choices) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	return &response, nil
+}
+
+func (c *Client) stream(ctx context.Context, url string, r *ChatRequest, headers map[string]string, response *ChatResponse) error {
+	choice := &Choice{Message: ResponseMessage{Role: "assistant"}}
+	response.Choices = []*Choice{choice}
+	err := httputils.HttpStream(ctx, url, r, headers, func(ctx context.Context, line string) error {
+		if !strings.HasPrefix(line, "data:") {
+			return nil
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			return nil
+		}
+		var chunk ChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("unexpected line: %v", line)
+		}
+		response.ID, response.Created, response.Model = chunk.ID, chunk.Created, chunk.Model
+		if chunk.Usage != nil {
+			response.Usage = chunk.Usage
+		}
+		if len(chunk.WebSearch) > 0 {
+			response.WebSearch = chunk.WebSearch
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		delta := chunk.Choices[0]
+		if delta.FinishReason != "" {
+			choice.FinishReason = delta.FinishReason
+		}
+		for _, tc := range delta.Delta.ToolCalls {
+			mergeToolCall(&choice.Message, tc)
+		}
+		if delta.Delta.Content == "" {
+			return nil
+		}
+		choice.Message.Content += delta.Delta.Content
+		return r.StreamingFunc(ctx, []byte(delta.Delta.Content), nil)
+	})
+	return convertError(err)
+}
+
+// mergeToolCall 按 index 拼接流式返回的工具调用
+func mergeToolCall(msg *ResponseMessage, delta ToolCall) {
+	for i := range msg.ToolCalls {
+		tc := &msg.ToolCalls[i]
+		if tc.Index != delta.Index {
+			continue
+		}
+		if delta.ID != "" {
+			tc.ID = delta.ID
+		}
+		if delta.Function.Name != "" {
+			tc.Function.Name = delta.Function.Name
+		}
+		tc.Function.Arguments += delta.Function.Arguments
+		return
+	}
+	msg.ToolCalls = append(msg.ToolCalls, delta)
+}
@@ -0,0 +1,48 @@
+package zhipuclient
+
+import (
+	"context"
+	"sort"
+
+	"github.com/comqositi/kpllms/internal/httputils"
+)
+
+// embedding-3 单次最多 64 条
+const maxEmbeddingBatchSize = 64
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+	// embedding-3 支持指定维度 256 512 1024 2048
+	Dimensions int `json:"dimensions,omitempty"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage Usage `json:"usage"`
+}
+
+// CreateEmbedding 文本向量化，超过单次上限时分批请求
+func (c *Client) CreateEmbedding(ctx context.Context, texts []string, dimensions int) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbeddingBatchSize {
+		end := min(start+maxEmbeddingBatchSize, len(texts))
+		headers, err := c.setHeaders()
+		if err != nil {
+			return nil, err
+		}
+		req := &embeddingRequest{Model: c.EmbeddingsModel, Input: texts[start:end], Dimensions: dimensions}
+		var resp embeddingResponse
+		if err = httputils.HttpPost(ctx, c.baseURL+"/embeddings", req, headers, &resp); err != nil {
+			return nil, convertError(err)
+		}
+		sort.Slice(resp.Data, func(i, j int) bool { return resp.Data[i].Index < resp.Data[j].Index })
+		for _, d := range resp.Data {
+			embeddings = append(embeddings, d.Embedding)
+		}
+	}
+	return embeddings, nil
+}
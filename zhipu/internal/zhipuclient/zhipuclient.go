@@ -0,0 +1,165 @@
+package zhipuclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/comqositi/kpllms/schema"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	defaultBaseURL        = "https://open.bigmodel.cn/api/paas/v4"
+	defaultChatModel      = "glm-4"
+	defaultEmbeddingModel = "embedding-3"
+
+	// jwt 有效期，以及到期前多久重新签发
+	tokenTTL           = 30 * time.Minute
+	tokenRefreshMargin = time.Minute
+
+	// 输入或输出内容命中敏感词的错误码
+	errorCodeSensitive = "1301"
+)
+
+// zhipu 客户端
+
+var (
+	ErrEmptyResponse = errors.New("empty response")
+	ErrInvalidAPIKey = errors.New("api key 格式错误，应为 id.secret")
+	// ErrSensitive 输入或输出内容命中敏感词
+	ErrSensitive = errors.New("zhipu: 内容命中敏感词")
+)
+
+// Client 智谱开放平台客户端
+type Client struct {
+	id              string
+	secret          string
+	Model           string
+	EmbeddingsModel string
+	baseURL         string
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// Option is an option for the Zhipu client.
+type Option func(*Client) error
+
+// New 创建智谱客户端
+func New(opts ...Option) (*Client, error) {
+	c := &Client{}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.id == "" || c.secret == "" {
+		return nil, ErrInvalidAPIKey
+	}
+	if c.baseURL == "" {
+		c.baseURL = defaultBaseURL
+	}
+	c.baseURL = strings.TrimSuffix(c.baseURL, "/")
+	if c.Model == "" {
+		c.Model = defaultChatModel
+	}
+	if c.EmbeddingsModel == "" {
+		c.EmbeddingsModel = defaultEmbeddingModel
+	}
+	return c, nil
+}
+
+// WithAPIKey 设置 api key，格式为 id.secret
+func WithAPIKey(value string) Option {
+	return func(c *Client) error {
+		id, secret, ok := strings.Cut(value, ".")
+		if !ok || id == "" || secret == "" {
+			return ErrInvalidAPIKey
+		}
+		c.id, c.secret = id, secret
+		return nil
+	}
+}
+
+func WithModel(value string) Option {
+	return func(c *Client) error {
+		c.Model = value
+		return nil
+	}
+}
+
+func WithEmbeddingsModel(value string) Option {
+	return func(c *Client) error {
+		c.EmbeddingsModel = value
+		return nil
+	}
+}
+
+func WithBaseURL(value string) Option {
+	return func(c *Client) error {
+		c.baseURL = value
+		return nil
+	}
+}
+
+// getToken 返回缓存的 jwt，到期前一分钟重新签发
+func (c *Client) getToken() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.token != "" && now.Before(c.expiresAt.Add(-tokenRefreshMargin)) {
+		return c.token, nil
+	}
+	expiresAt := now.Add(tokenTTL)
+	// 智谱要求 exp 和 timestamp 使用毫秒
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"api_key":   c.id,
+		"exp":       expiresAt.UnixMilli(),
+		"timestamp": now.UnixMilli(),
+	})
+	t.Header["sign_type"] = "SIGN"
+	token, err := t.SignedString([]byte(c.secret))
+	if err != nil {
+		return "", err
+	}
+	c.token, c.expiresAt = token, expiresAt
+	return token, nil
+}
+
+// 设置权限
+func (c *Client) setHeaders() (map[string]string, error) {
+	token, err := c.getToken()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": "Bearer " + token,
+	}, nil
+}
+
+// errorResponse 错误返回 {"error":{"code":"1301","message":"..."}}
+type errorResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// convertError 命中敏感词的错误同时包装 ErrSensitive 和原始的 HttpError
+func convertError(err error) error {
+	var httpErr *schema.HttpError
+	if !errors.As(err, &httpErr) {
+		return err
+	}
+	var e errorResponse
+	if json.Unmarshal([]byte(httpErr.ErrMsg), &e) == nil && e.Error.Code == errorCodeSensitive {
+		return fmt.Errorf("%w: %w", ErrSensitive, err)
+	}
+	return err
+}
@@ -0,0 +1,228 @@
+package zhipu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+	"github.com/comqositi/kpllms/zhipu/internal/zhipuclient"
+)
+
+var (
+	ErrEmptyResponse            = errors.New("no response")
+	ErrMissingToken             = errors.New("missing the Zhipu API key, set it in the ZHIPU_API_KEY environment variable") //nolint:lll
+	ErrUnexpectedResponseLength = errors.New("unexpected length of response")
+	// ErrSensitive 输入或输出内容命中敏感词，接口返回 1301 错误或 finish_reason 为 sensitive
+	ErrSensitive = zhipuclient.ErrSensitive
+)
+
+type LLM struct {
+	client              *zhipuclient.Client
+	embeddingDimensions int
+}
+
+var (
+	_ kpllms.Model    = (*LLM)(nil)
+	_ kpllms.Embedder = (*LLM)(nil)
+)
+
+// New 创建智谱大模型 model 和 embedder 的实现
+func New(opts ...Option) (*LLM, error) {
+	options := &options{
+		apiKey:  os.Getenv(apiKeyEnvVarName),
+		model:   os.Getenv(modelEnvVarName),
+		baseURL: os.Getenv(baseURLEnvVarName),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.apiKey == "" {
+		return nil, ErrMissingToken
+	}
+	c, err := zhipuclient.New(
+		zhipuclient.WithAPIKey(options.apiKey),
+		zhipuclient.WithModel(options.model),
+		zhipuclient.WithEmbeddingsModel(options.embeddingModel),
+		zhipuclient.WithBaseURL(options.baseURL),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &LLM{client: c, embeddingDimensions: options.embeddingDimensions}, nil
+}
+
+// samplingRanges 智谱支持的采样参数范围
+var samplingRanges = kpllms.SamplingRanges{
+	Temperature: kpllms.Range{Min: 0, Max: 1},
+	TopP:        kpllms.Range{Min: 0, Max: 1, ExclusiveMin: true},
+}
+
+// Chat 实现大模型接口
+func (o *LLM) Chat(ctx context.Context, messages []*schema.ChatMessage, options ...kpllms.CallOption) (*schema.ContentResponse, error) {
+	opts := kpllms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if err := samplingRanges.Validate(&opts); err != nil {
+		return nil, err
+	}
+	if err := opts.CheckSupported("zhipu", kpllms.OptionStop, kpllms.OptionUser); err != nil {
+		return nil, err
+	}
+	// 智谱只支持 auto 调用方式，不能指定调用的函数
+	if opts.ToolChoice.Type == schema.ToolChoiceTypeFunction {
+		return nil, fmt.Errorf("%w: zhipu does not support tool_choice %s", kpllms.ErrUnsupportedCallOption, opts.ToolChoice.Type)
+	}
+
+	msgs := make([]*zhipuclient.ChatMessage, 0, len(messages))
+	for _, mc := range messages {
+		msg := &zhipuclient.ChatMessage{Content: mc.Content}
+		switch mc.Role {
+		case schema.RoleSystem, schema.RoleUser:
+			msg.Role = mc.Role
+		case schema.RoleAssistant:
+			msg.Role = mc.Role
+			for _, t := range mc.ToolCalls {
+				msg.ToolCalls = append(msg.ToolCalls, zhipuclient.ToolCall{
+					ID:   t.Id,
+					Type: zhipuclient.ToolTypeFunction,
+					Function: zhipuclient.FunctionCall{
+						Name:      t.Function.Name,
+						Arguments: t.Function.Arguments,
+					},
+				})
+			}
+		case schema.RoleTool:
+			msg.Role = mc.Role
+			msg.ToolCallID = mc.ToolCallId
+		default:
+			return nil, fmt.Errorf("role %v not supported", mc.Role)
+		}
+		msgs = append(msgs, msg)
+	}
+
+	req := &zhipuclient.ChatRequest{
+		Model:         opts.Model,
+		Messages:      msgs,
+		Temperature:   opts.Temperature,
+		TopP:          opts.TopP,
+		MaxTokens:     kpllms.Deref(opts.MaxTokens),
+		Stop:          opts.Stop,
+		UserID:        opts.User,
+		StreamingFunc: opts.StreamingFunc,
+	}
+	// 使用 json 格式返回
+	if opts.JsonMode {
+		req.ResponseFormat = &zhipuclient.ResponseFormat{Type: "json_object"}
+	}
+
+	// 组装工具，智谱只支持 auto 调用方式，不调用函数时不传工具
+	if opts.ToolChoice.Type != schema.ToolChoiceTypeNone {
+		for _, tool := range opts.Tools {
+			t, err := toolFromTool(tool)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert llms tool to zhipu tool: %w", err)
+			}
+			req.Tools = append(req.Tools, t)
+		}
+	}
+
+	result, err := o.client.CreateChat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var usage *schema.Usage
+	if result.Usage != nil {
+		usage = &schema.Usage{
+			PromptTokens:     result.Usage.PromptTokens,
+			CompletionTokens: result.Usage.CompletionTokens,
+			TotalTokens:      result.Usage.TotalTokens,
+		}
+	}
+	choices := make([]*schema.ContentChoice, 0, len(result.Choices))
+	for _, c := range result.Choices {
+		// 输出命中敏感词，已经输出的内容也不应展示
+		if c.FinishReason == zhipuclient.FinishReasonSensitive {
+			return nil, ErrSensitive
+		}
+		choice := &schema.ContentChoice{
+			Content:    c.Message.Content,
+			StopReason: c.FinishReason,
+			Usage:      usage,
+			GenerationInfo: map[string]any{
+				"id": result.ID,
+			},
+		}
+		if len(result.WebSearch) > 0 {
+			choice.GenerationInfo["web_search"] = result.WebSearch
+		}
+		for _, tc := range c.Message.ToolCalls {
+			choice.ToolCalls = append(choice.ToolCalls, &schema.ToolCall{
+				Id:   tc.ID,
+				Type: schema.ToolCallTypeFunction,
+				Function: schema.FunctionCall{
+					Name:      tc.Function.Name,
+					Arguments: tc.Function.Arguments,
+				},
+			})
+		}
+		choices = append(choices, choice)
+	}
+	return &schema.ContentResponse{Choices: choices}, nil
+}
+
+func toolFromTool(t *kpllms.Tool) (*zhipuclient.Tool, error) {
+	switch t.Type {
+	case zhipuclient.ToolTypeFunction:
+		if t.Function == nil {
+			return nil, errors.New("function definition is required")
+		}
+		return &zhipuclient.Tool{Type: t.Type, Function: &zhipuclient.FunctionDefinition{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			Parameters:  t.Function.Parameters,
+		}}, nil
+	case ToolTypeWebSearch:
+		config, ok := t.Config.(WebSearch)
+		if !ok {
+			return nil, fmt.Errorf("web_search tool config must be zhipu.WebSearch, got %T", t.Config)
+		}
+		return &zhipuclient.Tool{Type: t.Type, WebSearch: &config}, nil
+	case ToolTypeRetrieval:
+		config, ok := t.Config.(Retrieval)
+		if !ok {
+			return nil, fmt.Errorf("retrieval tool config must be zhipu.Retrieval, got %T", t.Config)
+		}
+		return &zhipuclient.Tool{Type: t.Type, Retrieval: &config}, nil
+	default:
+		return nil, fmt.Errorf("tool type %v not supported", t.Type)
+	}
+}
+
+// EmbedDocuments  实现 embedder 接口 文档存储向量：存入数据库的向量，被用于检索
+func (o *LLM) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings, err := o.client.CreateEmbedding(ctx, texts, o.embeddingDimensions)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	if len(texts) != len(embeddings) {
+		return embeddings, ErrUnexpectedResponseLength
+	}
+	return embeddings, nil
+}
+
+// EmbedQuery  实现 embedder 接口 查询向量：对需要用于检索的文本进行想量化
+func (o *LLM) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := o.EmbedDocuments(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
@@ -0,0 +1,84 @@
+package zhipu
+
+import (
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/zhipu/internal/zhipuclient"
+)
+
+const (
+	apiKeyEnvVarName  = "ZHIPU_API_KEY"  //nolint:gosec
+	modelEnvVarName   = "ZHIPU_MODEL"    //nolint:gosec
+	baseURLEnvVarName = "ZHIPU_BASE_URL" //nolint:gosec
+)
+
+const (
+	// ToolTypeWebSearch 联网搜索工具
+	ToolTypeWebSearch = zhipuclient.ToolTypeWebSearch
+	// ToolTypeRetrieval 知识库检索工具
+	ToolTypeRetrieval = zhipuclient.ToolTypeRetrieval
+)
+
+// WebSearch 联网搜索配置
+type WebSearch = zhipuclient.WebSearch
+
+// Retrieval 知识库检索配置
+type Retrieval = zhipuclient.Retrieval
+
+// WebSearchResult 联网搜索结果，在 GenerationInfo 的 web_search 中返回
+type WebSearchResult = zhipuclient.WebSearchResult
+
+// NewWebSearchTool 创建联网搜索工具，和函数一起通过 kpllms.WithTools 传入
+func NewWebSearchTool(config WebSearch) *kpllms.Tool {
+	return &kpllms.Tool{Type: ToolTypeWebSearch, Config: config}
+}
+
+// NewRetrievalTool 创建知识库检索工具，和函数一起通过 kpllms.WithTools 传入
+func NewRetrievalTool(config Retrieval) *kpllms.Tool {
+	return &kpllms.Tool{Type: ToolTypeRetrieval, Config: config}
+}
+
+type options struct {
+	apiKey              string
+	model               string
+	embeddingModel      string
+	embeddingDimensions int
+	baseURL             string
+}
+
+// Option is a functional option for the Zhipu client.
+type Option func(*options)
+
+// WithAPIKey 设置 api key，格式为 id.secret，未设置时读取 ZHIPU_API_KEY 环境变量
+func WithAPIKey(apiKey string) Option {
+	return func(o *options) {
+		o.apiKey = apiKey
+	}
+}
+
+// WithModel 设置模型，未设置时读取 ZHIPU_MODEL 环境变量，默认 glm-4
+func WithModel(model string) Option {
+	return func(o *options) {
+		o.model = model
+	}
+}
+
+// WithEmbeddingModel 设置向量模型，默认 embedding-3
+func WithEmbeddingModel(model string) Option {
+	return func(o *options) {
+		o.embeddingModel = model
+	}
+}
+
+// WithEmbeddingDimensions 设置 embedding-3 的向量维度，默认 2048
+func WithEmbeddingDimensions(dimensions int) Option {
+	return func(o *options) {
+		o.embeddingDimensions = dimensions
+	}
+}
+
+// WithBaseURL 设置接口地址，未设置时读取 ZHIPU_BASE_URL 环境变量，默认 https://open.bigmodel.cn/api/paas/v4
+func WithBaseURL(baseURL string) Option {
+	return func(o *options) {
+		o.baseURL = baseURL
+	}
+}
@@ -0,0 +1,183 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/comqositi/kpllms"
+	"github.com/comqositi/kpllms/schema"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newTestServer 校验 jwt 后调用 handler，tokens 记录每次请求使用的 jwt
+func newTestServer(t *testing.T, handler func(path string, body map[string]any, w http.ResponseWriter)) (*LLM, *[]string) {
+	t.Helper()
+	var tokens []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		token, err := jwt.Parse(raw, func(token *jwt.Token) (any, error) {
+			return []byte("secret"), nil
+		}, jwt.WithValidMethods([]string{"HS256"}))
+		if err != nil {
+			t.Errorf("invalid jwt: %v", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		claims := token.Claims.(jwt.MapClaims)
+		exp := int64(claims["exp"].(float64))
+		// exp 和 timestamp 为毫秒
+		if token.Header["sign_type"] != "SIGN" || claims["api_key"] != "id" || exp < time.Now().Add(20*time.Minute).UnixMilli() {
+			t.Errorf("unexpected jwt: %v %v", token.Header, claims)
+		}
+		tokens = append(tokens, raw)
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		handler(strings.TrimPrefix(r.URL.Path, "/api/paas/v4"), body, w)
+	}))
+	t.Cleanup(srv.Close)
+	llm, err := New(WithAPIKey("id.secret"), WithBaseURL(srv.URL+"/api/paas/v4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return llm, &tokens
+}
+
+func TestChat(t *testing.T) {
+	var got map[string]any
+	llm, tokens := newTestServer(t, func(_ string, body map[string]any, w http.ResponseWriter) {
+		got = body
+		_, _ = io.WriteString(w, `{"id":"c1","model":"glm-4","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","content":"",
+			"tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"北京\"}"}}]}}],
+			"usage":{"prompt_tokens":30,"completion_tokens":10,"total_tokens":40},
+			"web_search":[{"title":"北京天气","link":"https://example.com","content":"晴"}]}`)
+	})
+	tools := []*kpllms.Tool{
+		{Type: schema.ToolCallTypeFunction, Function: &kpllms.FunctionDefinition{Name: "get_weather", Parameters: map[string]any{"type": "object"}}},
+		NewWebSearchTool(WebSearch{Enable: true, SearchResult: true}),
+		NewRetrievalTool(Retrieval{KnowledgeID: "k1"}),
+	}
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "北京天气"}}
+	for i := 0; i < 2; i++ {
+		if _, err := llm.Chat(context.Background(), msgs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := llm.Chat(context.Background(), msgs, kpllms.WithTools(tools))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*tokens) != 3 || (*tokens)[0] != (*tokens)[2] {
+		t.Fatal("jwt should be cached between calls")
+	}
+
+	b, _ := json.Marshal(got["tools"])
+	want := `[{"function":{"name":"get_weather","parameters":{"type":"object"}},"type":"function"},` +
+		`{"type":"web_search","web_search":{"enable":true,"search_result":true}},` +
+		`{"retrieval":{"knowledge_id":"k1"},"type":"retrieval"}]`
+	if string(b) != want {
+		t.Fatalf("tools = %s", b)
+	}
+	c := resp.Choices[0]
+	if c.StopReason != "tool_calls" || c.Usage.TotalTokens != 40 || len(c.GenerationInfo["web_search"].([]WebSearchResult)) != 1 {
+		t.Fatalf("unexpected choice: %+v", c)
+	}
+	if len(c.ToolCalls) != 1 || c.ToolCalls[0].Id != "call_1" || c.ToolCalls[0].Function.Arguments != `{"city":"北京"}` {
+		t.Fatalf("unexpected tool calls: %+v", c.ToolCalls)
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	chunks := []string{
+		`{"id":"c2","choices":[{"index":0,"delta":{"role":"assistant","content":"你好"}}]}`,
+		`{"id":"c2","choices":[{"index":0,"delta":{"role":"assistant","content":"，世界"}}]}`,
+		`{"id":"c2","choices":[{"index":0,"finish_reason":"stop","delta":{"role":"assistant","content":""}}],"usage":{"prompt_tokens":3,"completion_tokens":4,"total_tokens":7}}`,
+	}
+	llm, _ := newTestServer(t, func(_ string, body map[string]any, w http.ResponseWriter) {
+		for _, c := range chunks {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	})
+	var streamed strings.Builder
+	resp, err := llm.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "你好"}},
+		kpllms.WithStreamingFunc(func(ctx context.Context, chunk []byte, innerErr error) error {
+			streamed.Write(chunk)
+			return nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := resp.Choices[0]
+	if streamed.String() != "你好，世界" || c.Content != "你好，世界" || c.StopReason != "stop" || c.Usage.TotalTokens != 7 {
+		t.Fatalf("streamed = %q, choice = %+v", streamed.String(), c)
+	}
+}
+
+func TestChatSensitive(t *testing.T) {
+	llm, _ := newTestServer(t, func(_ string, body map[string]any, w http.ResponseWriter) {
+		if body["model"] == "glm-4-flash" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":{"code":"1301","message":"系统检测到输入或生成内容可能包含不安全或敏感内容"}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"choices":[{"index":0,"finish_reason":"sensitive","message":{"role":"assistant","content":"部分"}}]}`)
+	})
+	msgs := []*schema.ChatMessage{{Role: schema.RoleUser, Content: "敏感内容"}}
+	if _, err := llm.Chat(context.Background(), msgs); !errors.Is(err, ErrSensitive) {
+		t.Fatalf("err = %v", err)
+	}
+	_, err := llm.Chat(context.Background(), msgs, kpllms.WithModel("glm-4-flash"))
+	var httpErr *schema.HttpError
+	if !errors.Is(err, ErrSensitive) || !errors.As(err, &httpErr) || httpErr.Code != http.StatusBadRequest {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	llm, _ := newTestServer(t, func(path string, body map[string]any, w http.ResponseWriter) {
+		if path != "/embeddings" || body["model"] != "embedding-3" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		input := body["input"].([]any)
+		data := make([]map[string]any, 0, len(input))
+		for i := range input {
+			data = append(data, map[string]any{"index": i, "embedding": []float32{float32(i)}})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+	})
+	docs, err := llm.EmbedDocuments(context.Background(), make([]string, 70))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 70 || docs[65][0] != 1 {
+		t.Fatalf("docs = %v", docs)
+	}
+}
+
+func TestInvalidAPIKey(t *testing.T) {
+	if _, err := New(WithAPIKey("no-secret")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestChatForcedToolChoice(t *testing.T) {
+	llm, _ := newTestServer(t, func(_ string, body map[string]any, w http.ResponseWriter) {
+		t.Errorf("unexpected request: %v", body)
+	})
+	tools := []*kpllms.Tool{{Type: schema.ToolCallTypeFunction, Function: &kpllms.FunctionDefinition{Name: "get_weather"}}}
+	choice := kpllms.ToolChoice{Type: schema.ToolChoiceTypeFunction, Function: kpllms.ToolChoiceFunction{Name: "get_weather"}}
+	_, err := llm.Chat(context.Background(), []*schema.ChatMessage{{Role: schema.RoleUser, Content: "天气"}},
+		kpllms.WithTools(tools), kpllms.WithToolChoice(choice))
+	if !errors.Is(err, kpllms.ErrUnsupportedCallOption) {
+		t.Fatalf("err = %v", err)
+	}
+}